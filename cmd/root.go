@@ -7,7 +7,6 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -35,6 +34,18 @@ func init() {
 	rootCmd.PersistentFlags().Bool("enable-tls", false, "Enable TLS on admin webserver")
 	rootCmd.PersistentFlags().String("key-file", "", "TLS keyfile")
 	rootCmd.PersistentFlags().String("cert-file", "", "TLS certificate file")
+	rootCmd.PersistentFlags().String("auth-token-file", "", "Static api tokens file for the admin webserver")
+	rootCmd.PersistentFlags().Bool("auth-token-review", false, "Authenticate admin webserver users with kubernetes TokenReviews")
+	rootCmd.PersistentFlags().String("oidc-issuer-url", "", "Authenticate admin webserver users with OIDC id tokens of this issuer")
+	rootCmd.PersistentFlags().String("oidc-client-id", "", "Client id of the OIDC id tokens")
+	rootCmd.PersistentFlags().Bool("authorize", false, "Authorize admin webserver actions with kubernetes SubjectAccessReviews")
+	rootCmd.PersistentFlags().String("proxy-addr", ":8081", "Wake-on-request proxy listen address")
+	rootCmd.PersistentFlags().Bool("enable-proxy", false, "Enable wake-on-request proxy")
+	rootCmd.PersistentFlags().String("prometheus-url", "", "Prometheus endpoint used to evaluate idle conditions")
+	rootCmd.PersistentFlags().String("otlp-endpoint", "", "OTLP/http collector endpoint to export traces to (e.g. http://localhost:4318)")
+	rootCmd.PersistentFlags().String("log-format", "glog", "Log format (glog, text or json)")
+	rootCmd.PersistentFlags().String("log-level", "", "Default log level (trace, debug, info, warn or error), derived from -v if not set")
+	rootCmd.PersistentFlags().String("log-levels", "", "Log level per subsystem (e.g. agent=debug,webui=warn)")
 	rootCmd.PersistentFlags().String("timezone", "Local", "Timezone in which schedules are defined")
 	rootCmd.PersistentFlags().Duration("interval", 15*time.Minute, "Agent resync period")
 	viper.BindPFlag("generic.timezone", rootCmd.PersistentFlags().Lookup("timezone"))
@@ -44,6 +55,18 @@ func init() {
 	viper.BindPFlag("web.enable-tls", rootCmd.PersistentFlags().Lookup("enable-tls"))
 	viper.BindPFlag("web.cert-file", rootCmd.PersistentFlags().Lookup("cert-file"))
 	viper.BindPFlag("web.key-file", rootCmd.PersistentFlags().Lookup("key-file"))
+	viper.BindPFlag("web.auth.token-file", rootCmd.PersistentFlags().Lookup("auth-token-file"))
+	viper.BindPFlag("web.auth.token-review", rootCmd.PersistentFlags().Lookup("auth-token-review"))
+	viper.BindPFlag("web.auth.oidc-issuer-url", rootCmd.PersistentFlags().Lookup("oidc-issuer-url"))
+	viper.BindPFlag("web.auth.oidc-client-id", rootCmd.PersistentFlags().Lookup("oidc-client-id"))
+	viper.BindPFlag("web.auth.authorize", rootCmd.PersistentFlags().Lookup("authorize"))
+	viper.BindPFlag("proxy.listen-addr", rootCmd.PersistentFlags().Lookup("proxy-addr"))
+	viper.BindPFlag("proxy.enable", rootCmd.PersistentFlags().Lookup("enable-proxy"))
+	viper.BindPFlag("prometheus.url", rootCmd.PersistentFlags().Lookup("prometheus-url"))
+	viper.BindPFlag("tracing.otlp-endpoint", rootCmd.PersistentFlags().Lookup("otlp-endpoint"))
+	viper.BindPFlag("logging.format", rootCmd.PersistentFlags().Lookup("log-format"))
+	viper.BindPFlag("logging.default-level", rootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("logging.levels", rootCmd.PersistentFlags().Lookup("log-levels"))
 	viper.BindPFlag("logging.threshold", pflag.CommandLine.Lookup("stderrthreshold"))
 	viper.BindPFlag("logging.level", pflag.CommandLine.Lookup("v"))
 	viper.BindEnv("web.listen-addr", "WEB_LISTEN_ADDR")
@@ -51,6 +74,18 @@ func init() {
 	viper.BindEnv("web.enable-tls", "WEB_ENABLE_TLS")
 	viper.BindEnv("web.cert-file", "WEB_CERT_FILE")
 	viper.BindEnv("web.key-file", "WEB_KEY_FILE")
+	viper.BindEnv("web.auth.token-file", "WEB_AUTH_TOKEN_FILE")
+	viper.BindEnv("web.auth.token-review", "WEB_AUTH_TOKEN_REVIEW")
+	viper.BindEnv("web.auth.oidc-issuer-url", "WEB_AUTH_OIDC_ISSUER_URL")
+	viper.BindEnv("web.auth.oidc-client-id", "WEB_AUTH_OIDC_CLIENT_ID")
+	viper.BindEnv("web.auth.authorize", "WEB_AUTH_AUTHORIZE")
+	viper.BindEnv("proxy.listen-addr", "PROXY_LISTEN_ADDR")
+	viper.BindEnv("proxy.enable", "PROXY_ENABLE")
+	viper.BindEnv("prometheus.url", "PROMETHEUS_URL")
+	viper.BindEnv("tracing.otlp-endpoint", "TRACING_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT")
+	viper.BindEnv("logging.format", "LOGGING_FORMAT")
+	viper.BindEnv("logging.default-level", "LOGGING_DEFAULT_LEVEL")
+	viper.BindEnv("logging.levels", "LOGGING_LEVELS")
 	// kubeconfig
 	if home := homeDir(); home != "" {
 		rootCmd.PersistentFlags().String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
@@ -85,7 +120,6 @@ func initConfig() {
 	} else {
 		setFlag("stderrthreshold", "logging.threshold")
 		setFlag("v", "logging.level")
-		glog.Infof("Using config: %s\n", viper.ConfigFileUsed())
 	}
 }
 
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// activityBuffer is the number of activities that are buffered for each
+// subscriber. If a subscriber doesn't keep up, activities are dropped.
+const activityBuffer = 100
+
+// Activity types that are published by the agent.
+const (
+	ActivityWatch   = "watch"
+	ActivityScale   = "scale"
+	ActivityTrigger = "trigger"
+)
+
+// Activity describes an action of the agent; an object that is added, updated
+// or removed by a watch event, an object that is scaled, or a trigger that is
+// executed.
+type Activity struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event,omitempty"`
+	UID        string    `json:"uid,omitempty"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	ObjectType string    `json:"object_type,omitempty"`
+	ScannerId  string    `json:"scanner_id,omitempty"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Replicas   *int      `json:"replicas,omitempty"`
+	Schedule   string    `json:"schedule,omitempty"`
+	Trigger    string    `json:"trigger,omitempty"`
+	Objects    []string  `json:"objects,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Subscribe will return a channel on which the activities of the agent are
+// published, and a function that should be called to unsubscribe.
+func (a *worker) Subscribe() (<-chan Activity, func()) {
+	ch := make(chan Activity, activityBuffer)
+	a.sm.Lock()
+	defer a.sm.Unlock()
+	if a.subscribers == nil {
+		a.subscribers = map[chan Activity]bool{}
+	}
+	a.subscribers[ch] = true
+	return ch, func() {
+		a.sm.Lock()
+		defer a.sm.Unlock()
+		if a.subscribers[ch] {
+			delete(a.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish will send the given activity to all subscribers. It will not block
+// on subscribers that don't keep up.
+func (a *worker) publish(act Activity) {
+	if act.Time.IsZero() {
+		act.Time = time.Now()
+	}
+	a.sm.Lock()
+	defer a.sm.Unlock()
+	for ch := range a.subscribers {
+		select {
+		case ch <- act:
+		default:
+			logger.Debug("Dropped activity for slow subscriber", "activity", act.Type, "uid", act.UID)
+		}
+	}
+}
+
+// objectActivity will return an activity of given type for given object.
+func objectActivity(typ string, obj *scanner.Object) Activity {
+	return Activity{
+		Type:       typ,
+		UID:        obj.UID,
+		Namespace:  obj.Namespace,
+		Name:       obj.Name,
+		ObjectType: obj.Type,
+		ScannerId:  obj.ScannerId,
+		Cluster:    obj.Cluster,
+	}
+}
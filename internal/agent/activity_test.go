@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+func TestSubscribe(t *testing.T) {
+	agt := &worker{}
+	ch1, unsub1 := agt.Subscribe()
+	ch2, unsub2 := agt.Subscribe()
+
+	agt.publish(Activity{Type: ActivityWatch, UID: "abc"})
+	for i, ch := range []<-chan Activity{ch1, ch2} {
+		act := <-ch
+		if act.Type != ActivityWatch || act.UID != "abc" {
+			t.Errorf("failed test %d - unexpected activity: %v", i, act)
+		}
+		if act.Time.IsZero() {
+			t.Errorf("failed test %d - expected time to be set", i)
+		}
+	}
+
+	unsub1()
+	unsub1()
+	if _, ok := <-ch1; ok {
+		t.Errorf("failed test - expected channel to be closed after unsubscribe")
+	}
+	agt.publish(Activity{Type: ActivityTrigger})
+	if act := <-ch2; act.Type != ActivityTrigger {
+		t.Errorf("failed test - unexpected activity: %v", act)
+	}
+
+	// a subscriber that doesn't keep up should not block the agent
+	for i := 0; i < activityBuffer+10; i++ {
+		agt.publish(Activity{Type: ActivityScale})
+	}
+	if len(ch2) != activityBuffer {
+		t.Errorf("failed test - expected %d buffered activities, got %d", activityBuffer, len(ch2))
+	}
+	unsub2()
+}
+
+func TestPublishScale(t *testing.T) {
+	agt := &worker{}
+	ch, unsub := agt.Subscribe()
+	defer unsub()
+	sched, _ := schedule.New("Mon-Fri 18:00 replicas=0")
+	obj := &scanner.Object{UID: "abc", Namespace: "dev", Name: "app", ScannerId: "dev"}
+
+	agt.publishScale(&event{obj: obj, sched: sched}, 0, nil)
+	act := <-ch
+	if act.Type != ActivityScale || act.UID != "abc" || act.Namespace != "dev" || act.Name != "app" || act.ScannerId != "dev" {
+		t.Errorf("failed test - unexpected activity: %v", act)
+	}
+	if act.Replicas == nil || *act.Replicas != 0 || act.Schedule != sched.Description || act.Error != "" {
+		t.Errorf("failed test - unexpected activity: %v", act)
+	}
+
+	agt.publishScale(&event{obj: obj, sched: sched}, 2, errors.New("some error"))
+	if act := <-ch; act.Error != "some error" || *act.Replicas != 2 {
+		t.Errorf("failed test - unexpected activity: %v", act)
+	}
+}
@@ -1,11 +1,11 @@
 package agent
 
 import (
+	"context"
 	"sync"
 	"time"
 
-	"github.com/golang/glog"
-
+	"github.com/joyrex2001/nightshift/internal/logging"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/trigger"
 )
@@ -18,6 +18,10 @@ type Agent interface {
 	GetObjects() map[string]*scanner.Object
 	GetScanners() []scanner.Scanner
 	GetTriggers() map[string]trigger.Trigger
+	GetTriggerHistory() []trigger.Execution
+	QueueTrigger(string, trigger.Event) error
+	Subscribe() (<-chan Activity, func())
+	GetTimeline(time.Time, time.Time) []Timeline
 	UpdateSchedule()
 	Start()
 	Stop()
@@ -30,12 +34,21 @@ type worker struct {
 	scanners  []scanner.Scanner
 	triggers  map[string]trigger.Trigger
 	trigqueue chan triggr
+	history   []trigger.Execution
+	snoozed   map[string]*event
+	armed     map[string]*event
 	watchers  []watch
 	objects   map[string]*objectspq
 	now       time.Time
 	past      time.Time
+	ctx       context.Context
+
+	sm          sync.Mutex
+	subscribers map[chan Activity]bool
 }
 
+var logger = logging.For(logging.Agent)
+
 var instance *worker
 var once sync.Once
 
@@ -51,6 +64,11 @@ func New() Agent {
 			scanners:  []scanner.Scanner{},
 			triggers:  map[string]trigger.Trigger{},
 			trigqueue: make(chan triggr, 500),
+			history:   []trigger.Execution{},
+			snoozed:   map[string]*event{},
+			armed:     map[string]*event{},
+
+			subscribers: map[chan Activity]bool{},
 		}
 	})
 	return instance
@@ -84,17 +102,28 @@ func (a *worker) AddTrigger(id string, trgr trigger.Trigger) {
 	a.triggers[id] = trgr
 }
 
-// GetScanners will return the configured scanners.
+// GetTriggers will return the configured triggers.
 func (a *worker) GetTriggers() map[string]trigger.Trigger {
-	// disabled; AddTrigger is only done during initialization...
-	// a.m.Lock()
-	// defer a.m.Unlock()
-	return a.triggers
+	a.m.Lock()
+	defer a.m.Unlock()
+	trgrs := make(map[string]trigger.Trigger, len(a.triggers))
+	for id, trgr := range a.triggers {
+		trgrs[id] = trgr
+	}
+	return trgrs
+}
+
+// getTrigger will return the trigger with given id, if it exists.
+func (a *worker) getTrigger(id string) (trigger.Trigger, bool) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	trgr, ok := a.triggers[id]
+	return trgr, ok
 }
 
 // Start will start the agent.
 func (a *worker) Start() {
-	glog.Info("Starting agent...")
+	logger.Info("Starting agent...")
 	a.UpdateSchedule()
 	go a.StartWatch()
 	go a.StartScale()
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"github.com/joyrex2001/nightshift/internal/condition"
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/metrics"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+// arm will keep the given event, of which the schedule has an idle condition,
+// so it can be processed once the condition holds. The event stays armed
+// until another event without idle condition is processed for the object.
+func (a *worker) arm(e *event) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	if a.armed == nil {
+		a.armed = map[string]*event{}
+	}
+	a.armed[e.obj.UID] = e
+}
+
+// disarm will remove the armed idle event for given object.
+func (a *worker) disarm(obj *scanner.Object) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	delete(a.armed, obj.UID)
+}
+
+// idleEvent will evaluate the idle condition of the armed event for the given
+// object, and will return a copy of this event, updated with the current
+// object, if the condition holds. It will return nil if no event is armed,
+// the object is snoozed, the event has already been applied, or if the idle
+// condition or when expression does not hold.
+func (a *worker) idleEvent(obj *scanner.Object) *event {
+	a.m.Lock()
+	armed, ok := a.armed[obj.UID]
+	a.m.Unlock()
+	if !ok || obj.IsSnoozed(a.now) {
+		return nil
+	}
+	e := &event{at: a.now, obj: obj, sched: armed.sched}
+	if isApplied(e) || !isMet(e) {
+		return nil
+	}
+	idle, err := condition.Idle(e.sched.GetIdle(), obj, a.now)
+	if err != nil {
+		logger.Error("Error evaluating idle condition", obj.LogAttr(), logging.Err(err))
+		metrics.Increase("condition_error")
+		return nil
+	}
+	if !idle {
+		return nil
+	}
+	if !e.sched.HasReplicas() || e.sched.IsRelative() {
+		// nothing to compare with on the next tick, so only apply once
+		a.disarm(obj)
+	}
+	return e
+}
+
+// isApplied will return true if the object of given event has already been
+// scaled to the number of replicas as specified by the schedule, including
+// the min and max clamp.
+func isApplied(e *event) bool {
+	if state, _ := e.sched.GetState(); state == schedule.RestoreState && e.obj.State != nil {
+		return e.obj.Replicas == e.obj.State.Replicas
+	}
+	if !e.sched.HasReplicas() || e.sched.IsRelative() {
+		return false
+	}
+	repl, err := e.sched.ResolveReplicas(e.obj.Replicas, getState(e))
+	return err == nil && e.obj.Replicas == repl
+}
+
+// isMet will evaluate the when expression of the schedule of the given event,
+// and will return true if the expression evaluates to true, or if no
+// expression is configured. Errors are considered as not met.
+func isMet(e *event) bool {
+	if !e.sched.HasWhen() {
+		return true
+	}
+	met, err := condition.When(e.sched.GetWhen(), e.obj, e.at)
+	if err != nil {
+		logger.Error("Error evaluating when expression", e.logAttrs(logging.Err(err))...)
+		metrics.Increase("condition_error")
+		return false
+	}
+	if !met {
+		logger.Debug("Skipped scale event, condition not met", e.logAttrs("when", e.sched.GetWhen())...)
+	}
+	return met
+}
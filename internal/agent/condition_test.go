@@ -0,0 +1,205 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/condition"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+func TestIdleEvent(t *testing.T) {
+	var idle, queries int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&queries, 1)
+		if r.FormValue("query") != `sum(rate(http_requests_total{namespace="dev"}[2h])) == 0` {
+			t.Errorf("unexpected query: %s", r.FormValue("query"))
+		}
+		res := `[]`
+		if atomic.LoadInt32(&idle) == 1 {
+			res = `[{"metric":{},"value":[1551726000,"0"]}]`
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":%s}}`, res)
+	}))
+	defer srv.Close()
+	condition.SetPrometheus(condition.Prometheus{URL: srv.URL})
+
+	mock := &mockScanner{scale: -1}
+	scanner.RegisterModule("idlescanner", getScannerFactory("idlescanner", mock))
+	sc1, _ := schedule.New(`Mon-Fri 9:00 replicas=0 idle='sum(rate(http_requests_total{namespace="{{ .namespace }}"}[2h])) == 0'`)
+	sc2, _ := schedule.New("Mon-Fri 18:00 replicas=0")
+	obj := &scanner.Object{UID: "abc", Namespace: "dev", Type: "idlescanner", Replicas: 2, Schedule: []*schedule.Schedule{sc1, sc2}}
+
+	agent := &worker{}
+	agent.past = time.Date(2019, 3, 4, 8, 59, 0, 0, time.UTC) // monday
+	agent.now = time.Date(2019, 3, 4, 9, 0, 0, 0, time.UTC)
+	evts := agent.getEvents(obj)
+	if len(evts) != 1 {
+		t.Fatalf("failed test - expected 1 event, got %v", evts)
+	}
+	if trgrs := agent.processEvent([]*triggr{}, evts[0]); len(trgrs) != 0 || mock.scale != -1 {
+		t.Errorf("failed test - idle event processed before condition holds")
+	}
+
+	// condition does not hold
+	if e := agent.idleEvent(obj); e != nil {
+		t.Errorf("failed test - unexpected idle event: %v", e)
+	}
+
+	// condition holds
+	atomic.StoreInt32(&idle, 1)
+	agent.now = time.Date(2019, 3, 4, 11, 0, 0, 0, time.UTC)
+	e := agent.idleEvent(obj)
+	if e == nil || e.obj != obj || e.sched != sc1 {
+		t.Fatalf("failed test - expected idle event, got %v", e)
+	}
+	agent.handleEvent([]*triggr{}, e)
+	if mock.scale != 0 {
+		t.Errorf("failed test - expected scale to 0, got %d", mock.scale)
+	}
+
+	// already applied, should not query prometheus
+	q := atomic.LoadInt32(&queries)
+	obj.Replicas = 0
+	if e := agent.idleEvent(obj); e != nil {
+		t.Errorf("failed test - unexpected idle event after scaling: %v", e)
+	}
+	if atomic.LoadInt32(&queries) != q {
+		t.Errorf("failed test - unexpected query for applied idle event")
+	}
+
+	// woken up again, condition should be evaluated again
+	obj.Replicas = 1
+	if e := agent.idleEvent(obj); e == nil {
+		t.Errorf("failed test - expected idle event after wake up")
+	}
+
+	// snoozed objects are not scaled
+	until := time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)
+	obj.SnoozeUntil = &until
+	if e := agent.idleEvent(obj); e != nil {
+		t.Errorf("failed test - unexpected idle event for snoozed object: %v", e)
+	}
+	obj.SnoozeUntil = nil
+
+	// a regular event will disarm the idle event
+	agent.processEvent([]*triggr{}, &event{at: agent.now, obj: obj, sched: sc2})
+	if e := agent.idleEvent(obj); e != nil {
+		t.Errorf("failed test - unexpected idle event after disarm: %v", e)
+	}
+}
+
+func TestWhenEvent(t *testing.T) {
+	mock := &mockScanner{scale: -1}
+	scanner.RegisterModule("whenscanner", getScannerFactory("whenscanner", mock))
+	sc, _ := schedule.New(`Mon-Fri 18:00 replicas=0 trigger=notify when="labels.tier != 'critical'"`)
+
+	tests := []struct {
+		obj   *scanner.Object
+		scale int
+		trgrs int
+	}{
+		{
+			obj:   &scanner.Object{UID: "abc", Type: "whenscanner", Replicas: 1, Labels: map[string]string{"tier": "dev"}},
+			scale: 0,
+			trgrs: 1,
+		},
+		{
+			obj:   &scanner.Object{UID: "def", Type: "whenscanner", Replicas: 1, Labels: map[string]string{"tier": "critical"}},
+			scale: -1,
+			trgrs: 0,
+		},
+		{
+			// missing label results in an error, and is not applied
+			obj:   &scanner.Object{UID: "ghi", Type: "whenscanner", Replicas: 1},
+			scale: -1,
+			trgrs: 0,
+		},
+	}
+	for i, tst := range tests {
+		mock.scale = -1
+		agent := &worker{}
+		e := &event{at: time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC), obj: tst.obj, sched: sc}
+		trgrs := agent.processEvent([]*triggr{}, e)
+		if mock.scale != tst.scale {
+			t.Errorf("failed test %d - expected scale %d, got %d", i, tst.scale, mock.scale)
+		}
+		if len(trgrs) != tst.trgrs {
+			t.Errorf("failed test %d - expected %d triggers, got %d", i, tst.trgrs, len(trgrs))
+		}
+	}
+}
+
+func TestIsApplied(t *testing.T) {
+	state := 4
+	tests := []struct {
+		sched    string
+		replicas int
+		state    *scanner.State
+		out      bool
+	}{
+		{sched: "Mon-Fri 9:00 replicas=2", replicas: 2, out: true},
+		{sched: "Mon-Fri 9:00 replicas=2", replicas: 3, out: false},
+		{sched: "Mon-Fri 9:00 replicas=5 max=3", replicas: 3, out: true},
+		{sched: "Mon-Fri 9:00 replicas=5 max=3", replicas: 5, out: false},
+		{sched: "Mon-Fri 9:00 replicas=0 min=1", replicas: 1, out: true},
+		{sched: "Mon-Fri 9:00 replicas=0 min=1", replicas: 0, out: false},
+		{sched: "Mon-Fri 9:00 replicas=-1", replicas: 2, out: false},
+		{sched: "Mon-Fri 9:00 replicas=50%", replicas: 2, state: &scanner.State{Replicas: state}, out: false},
+		{sched: "Mon-Fri 9:00 state=restore", replicas: 4, state: &scanner.State{Replicas: state}, out: true},
+		{sched: "Mon-Fri 9:00 state=restore", replicas: 2, state: &scanner.State{Replicas: state}, out: false},
+		{sched: "Mon-Fri 9:00 trigger=notify", replicas: 2, out: false},
+	}
+	for i, tst := range tests {
+		sc, err := schedule.New(tst.sched)
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected err: %s", i, err)
+		}
+		obj := &scanner.Object{UID: "abc", Replicas: tst.replicas, State: tst.state}
+		if out := isApplied(&event{obj: obj, sched: sc}); out != tst.out {
+			t.Errorf("failed test %d - expected %t, got %t", i, tst.out, out)
+		}
+	}
+}
+
+func TestIdleEventClamped(t *testing.T) {
+	var queries int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&queries, 1)
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1551726000,"0"]}]}}`)
+	}))
+	defer srv.Close()
+	condition.SetPrometheus(condition.Prometheus{URL: srv.URL})
+
+	mock := &mockScanner{scale: -1}
+	scanner.RegisterModule("clampscanner", getScannerFactory("clampscanner", mock))
+	sc, _ := schedule.New(`Mon-Fri 9:00 replicas=0 min=1 idle='up == 0'`)
+	obj := &scanner.Object{UID: "abc", Namespace: "dev", Type: "clampscanner", Replicas: 2, Schedule: []*schedule.Schedule{sc}}
+
+	agent := &worker{}
+	agent.now = time.Date(2019, 3, 4, 9, 0, 0, 0, time.UTC)
+	agent.processEvent([]*triggr{}, &event{at: agent.now, obj: obj, sched: sc})
+	e := agent.idleEvent(obj)
+	if e == nil {
+		t.Fatalf("failed test - expected idle event")
+	}
+	agent.handleEvent([]*triggr{}, e)
+	if mock.scale != 1 {
+		t.Errorf("failed test - expected scale to 1, got %d", mock.scale)
+	}
+
+	// scaled to the clamped number of replicas, should not scale again
+	q := atomic.LoadInt32(&queries)
+	obj.Replicas = 1
+	if e := agent.idleEvent(obj); e != nil {
+		t.Errorf("failed test - unexpected idle event after clamped scaling: %v", e)
+	}
+	if atomic.LoadInt32(&queries) != q {
+		t.Errorf("failed test - unexpected query for applied idle event")
+	}
+}
@@ -109,7 +109,9 @@ func (a *worker) addObject(obj *scanner.Object) {
 	heap.Push(opq, obj)
 }
 
-// removeObject will remove an Object from the priority queue.
+// removeObject will remove an Object from the priority queue. If no scanner
+// manages the object anymore, its snoozed and armed events are removed as
+// well.
 func (a *worker) removeObject(obj *scanner.Object) {
 	a.m.Lock()
 	defer a.m.Unlock()
@@ -120,4 +122,8 @@ func (a *worker) removeObject(obj *scanner.Object) {
 	if idx := opq.Index(obj); idx >= 0 {
 		heap.Remove(opq, idx)
 	}
+	if len(*opq) == 0 {
+		delete(a.snoozed, obj.UID)
+		delete(a.armed, obj.UID)
+	}
 }
@@ -125,3 +125,50 @@ func TestGetAddRemoveObjects(t *testing.T) {
 	}
 
 }
+
+func TestRemoveObjectEvents(t *testing.T) {
+	tests := []struct {
+		add    []*scanner.Object
+		remove []*scanner.Object
+		keep   bool
+	}{
+		{
+			add:    []*scanner.Object{{UID: "abc", Priority: 1, Type: "myscanner"}},
+			remove: []*scanner.Object{{UID: "abc", Priority: 1, Type: "myscanner"}},
+			keep:   false,
+		},
+		{
+			add: []*scanner.Object{
+				{UID: "abc", Priority: 1, Type: "myscanner"},
+				{UID: "abc", Priority: 2, Type: "myscanner2"},
+			},
+			remove: []*scanner.Object{{UID: "abc", Priority: 2, Type: "myscanner2"}},
+			keep:   true,
+		},
+		{
+			add:    []*scanner.Object{{UID: "abc", Priority: 1, Type: "myscanner"}},
+			remove: []*scanner.Object{{UID: "def", Priority: 1, Type: "myscanner"}},
+			keep:   true,
+		},
+	}
+	for i, tst := range tests {
+		agt := &worker{}
+		agt.InitObjects()
+		agt.objects = map[string]*objectspq{}
+		for _, add := range tst.add {
+			agt.addObject(add)
+		}
+		e := &event{obj: tst.add[0]}
+		agt.snooze(e)
+		agt.arm(e)
+		for _, rem := range tst.remove {
+			agt.removeObject(rem)
+		}
+		if _, ok := agt.snoozed["abc"]; ok != tst.keep {
+			t.Errorf("failed test %d - expected snoozed event %t, but got %t", i, tst.keep, ok)
+		}
+		if _, ok := agt.armed["abc"]; ok != tst.keep {
+			t.Errorf("failed test %d - expected armed event %t, but got %t", i, tst.keep, ok)
+		}
+	}
+}
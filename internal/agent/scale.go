@@ -1,14 +1,18 @@
 package agent
 
 import (
+	"context"
 	"sort"
 	"time"
 
-	"github.com/golang/glog"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/joyrex2001/nightshift/internal/logging"
 	"github.com/joyrex2001/nightshift/internal/metrics"
+	"github.com/joyrex2001/nightshift/internal/savings"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/schedule"
+	"github.com/joyrex2001/nightshift/internal/tracing"
 )
 
 const scaleInterval = 30 * time.Second
@@ -21,6 +25,16 @@ type event struct {
 	restore bool
 }
 
+// logAttrs will return the fields that describe the event in log messages,
+// followed by given fields.
+func (e *event) logAttrs(attrs ...any) []any {
+	res := []any{e.obj.LogAttr(), logging.EventTime(e.at)}
+	if e.sched != nil {
+		res = append(res, "schedule", e.sched.Description)
+	}
+	return append(res, attrs...)
+}
+
 // StartScale will call the scale method on a predefined interval.
 func (a *worker) StartScale() {
 	for {
@@ -42,19 +56,95 @@ func (a *worker) StopScale() {
 // Scale will process all scanned objects and scale them accordingly.
 func (a *worker) scaleObjects() {
 	trgrs := []*triggr{}
-	glog.V(4).Info("Scaling resources start...")
+	logger.Debug("Scaling resources start...")
 	a.now = time.Now()
+	ctx, span := tracing.Start(context.Background(), "scaleObjects")
+	defer span.End()
+	a.ctx = ctx
+	objs := []*scanner.Object{}
 	for _, obj := range a.GetObjects() {
+		objs = append(objs, obj)
+		if e := a.resumeSnoozed(obj); e != nil {
+			logger.Debug("Resumed snoozed scale event", e.logAttrs()...)
+			trgrs = a.processEvent(trgrs, e)
+		}
 		for _, e := range a.getEvents(obj) {
-			glog.V(4).Infof("Scale event: %v", e)
-			trgrs = a.appendTrigger(trgrs, obj, e.sched.GetTriggers())
-			a.handleState(e)
-			a.scale(e)
+			if obj.IsSnoozed(e.at) {
+				logger.Debug("Snoozed scale event", e.logAttrs()...)
+				a.snooze(e)
+				continue
+			}
+			logger.Debug("Scale event", e.logAttrs()...)
+			trgrs = a.processEvent(trgrs, e)
+		}
+		if e := a.idleEvent(obj); e != nil {
+			logger.Debug("Idle scale event", e.logAttrs()...)
+			trgrs = a.handleEvent(trgrs, e)
 		}
 	}
 	a.queueTriggers(trgrs)
+	savings.Observe(objs, a.now)
+	setObjectMetrics(objs, a.now)
+	span.SetAttributes(
+		attribute.Int("nightshift.objects", len(objs)),
+		attribute.Int("nightshift.triggers", len(trgrs)),
+	)
 	a.past = a.now
-	glog.V(4).Info("Scaling resources finished...")
+	logger.Debug("Scaling resources finished...")
+}
+
+// processEvent will process the given scale event, unless its when expression
+// does not hold, or the schedule has an idle condition, in which case the
+// event is armed, and will be processed once the condition holds. It will
+// return the given list of triggers, appended with the triggers of this event.
+func (a *worker) processEvent(trgrs []*triggr, e *event) []*triggr {
+	if !isMet(e) {
+		return trgrs
+	}
+	if e.sched.HasIdle() {
+		a.arm(e)
+		return trgrs
+	}
+	a.disarm(e.obj)
+	return a.handleEvent(trgrs, e)
+}
+
+// handleEvent will process the given scale event, and will return the given
+// list of triggers, appended with the triggers of this event.
+func (a *worker) handleEvent(trgrs []*triggr, e *event) []*triggr {
+	trgrs = a.appendTrigger(trgrs, e)
+	a.handleState(e)
+	a.scale(e)
+	return trgrs
+}
+
+// snooze will keep given event, which is skipped because the schedule of the
+// object is snoozed, so it can be applied when the snooze ends. Only the most
+// recent event is kept for each object. The events are kept in memory only,
+// and will be lost when nightshift is restarted.
+func (a *worker) snooze(e *event) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	if a.snoozed == nil {
+		a.snoozed = map[string]*event{}
+	}
+	a.snoozed[e.obj.UID] = e
+}
+
+// resumeSnoozed will return the most recent event that was skipped for the
+// given object while its schedule was snoozed, if the object is not snoozed
+// anymore. The event will be updated with the current object. It will return
+// nil if no such event exists.
+func (a *worker) resumeSnoozed(obj *scanner.Object) *event {
+	a.m.Lock()
+	defer a.m.Unlock()
+	e, ok := a.snoozed[obj.UID]
+	if !ok || obj.IsSnoozed(a.now) {
+		return nil
+	}
+	delete(a.snoozed, obj.UID)
+	e.obj = obj
+	return e
 }
 
 // getEvents will return the events in chronological order that have to be
@@ -66,7 +156,7 @@ func (a *worker) getEvents(obj *scanner.Object) []*event {
 		for next := a.past; !next.After(a.now); next = next.AddDate(0, 0, 1) {
 			next, err = s.GetNextTrigger(next)
 			if err != nil {
-				glog.Errorf("Error processing trigger: %s", err)
+				logger.Error("Error processing trigger", obj.LogAttr(), "schedule", s.Description, logging.Err(err))
 				continue
 			}
 			if a.now.After(next) || a.now == next {
@@ -83,14 +173,14 @@ func (a *worker) getEvents(obj *scanner.Object) []*event {
 func (a *worker) handleState(e *event) {
 	state, err := e.sched.GetState()
 	if err != nil {
-		glog.Errorf("Error scaling deployment: %s", err)
+		logger.Error("Error scaling deployment", e.logAttrs(logging.Err(err))...)
 		return
 	}
 	// Save the current number of pods
 	if state == schedule.SaveState {
 		pods, err := e.obj.GetState()
 		if err != nil {
-			glog.Errorf("Error retrieving state: %s", err)
+			logger.Error("Error retrieving state", e.logAttrs(logging.Err(err))...)
 			return
 		}
 		e.state = pods
@@ -99,7 +189,7 @@ func (a *worker) handleState(e *event) {
 	// State that should be applied.
 	if state == schedule.RestoreState {
 		if e.obj.State == nil {
-			glog.Errorf("No state available on %s/%s", e.obj.Namespace, e.obj.Name)
+			logger.Error("No state available", e.logAttrs()...)
 			return
 		}
 		e.restore = true
@@ -111,28 +201,150 @@ func (a *worker) scale(e *event) {
 	// restore state
 	if e.restore {
 		repl := e.obj.State.Replicas
-		if err := e.obj.Scale(e.state, repl); err != nil {
-			glog.Errorf("Error scaling deployment: %s", err)
+		err := a.scaleObject(e, repl, restoreProfile(e.obj))
+		if err != nil {
+			logger.Error("Error scaling deployment", e.logAttrs(logging.Err(err))...)
 			metrics.Increase("scale_error")
 		}
 		metrics.Increase("scale")
-		metrics.SetReplicas(e.obj.Namespace, e.obj.ScannerId, repl)
+		metrics.SetReplicas(e.obj.Namespace, e.obj.ScannerId, e.obj.Cluster, repl)
+		observeDelay(e, err)
+		a.publishScale(e, repl, err)
 		return
 	}
 	// regular scaling
-	if !e.sched.HasReplicas() {
+	if !e.sched.HasReplicas() && !e.sched.HasResources() {
 		// ignore scalling if no replicas are present, this is probably a
 		// schedule just containing triggers.
 		return
 	}
-	repl, err := e.sched.GetReplicas()
+	repl := e.obj.Replicas
+	profile, err := getProfile(e)
+	if err == nil && e.sched.HasReplicas() {
+		repl, err = e.sched.ResolveReplicas(e.obj.Replicas, getState(e))
+	}
 	if err == nil {
-		err = e.obj.Scale(e.state, repl)
+		err = a.scaleObject(e, repl, profile)
 		metrics.Increase("scale")
-		metrics.SetReplicas(e.obj.Namespace, e.obj.ScannerId, repl)
+		metrics.SetReplicas(e.obj.Namespace, e.obj.ScannerId, e.obj.Cluster, repl)
 	}
 	if err != nil {
 		metrics.Increase("scale_error")
-		glog.Errorf("Error scaling deployment: %s", err)
+		logger.Error("Error scaling deployment", e.logAttrs(logging.Err(err))...)
+	}
+	observeDelay(e, err)
+	a.publishScale(e, repl, err)
+}
+
+// scaleObject will scale the object of given event to given replicas, and
+// given resources profile, within a span of the current scale cycle.
+func (a *worker) scaleObject(e *event, repl int, profile *scanner.Profile) error {
+	attrs := []attribute.KeyValue{
+		attribute.String("nightshift.namespace", e.obj.Namespace),
+		attribute.String("nightshift.object", e.obj.Name),
+		attribute.String("nightshift.type", e.obj.Type),
+		attribute.String("nightshift.scanner", e.obj.ScannerId),
+		attribute.String("nightshift.cluster", e.obj.Cluster),
+		attribute.Int("nightshift.replicas", repl),
+		attribute.String("nightshift.event_time", e.at.Format(time.RFC3339)),
+	}
+	if e.sched != nil {
+		attrs = append(attrs, attribute.String("nightshift.schedule", e.sched.Description))
+	}
+	_, span := tracing.Start(a.ctx, "scale", attrs...)
+	from := e.obj.Replicas
+	err := e.obj.ScaleWithProfile(e.state, repl, profile)
+	tracing.End(span, err)
+	if err == nil {
+		logger.Info("Scaled", e.logAttrs("replicas", repl)...)
+		savings.Scaled(e.obj, from)
+	}
+	return err
+}
+
+// observeDelay will record the delay between the scheduled time of given
+// event and the actual scaling, if the scaling succeeded.
+func observeDelay(e *event, err error) {
+	if err == nil {
+		metrics.ObserveScaleDelay(e.obj.Type, e.obj.Cluster, time.Since(e.at))
+	}
+}
+
+// setObjectMetrics will update the per object metrics with the current number
+// of replicas and the next scale event of given objects, and will remove the
+// metrics of objects that are no longer scheduled.
+func setObjectMetrics(objs []*scanner.Object, now time.Time) {
+	keep := map[string]bool{}
+	for _, obj := range objs {
+		keep[obj.UID] = true
+		metrics.SetObject(obj.MetricsObject(), obj.Replicas, nextScale(obj, now))
+	}
+	metrics.PruneObjects(keep)
+}
+
+// nextScale will return the time of the next scale event of given object, or
+// nil if it has none. If the object is snoozed, and an event is skipped, the
+// next scale event is at the end of the snooze.
+func nextScale(obj *scanner.Object, now time.Time) *time.Time {
+	var next *time.Time
+	for _, s := range obj.Schedule {
+		if st, _ := s.GetState(); !s.HasReplicas() && !s.HasResources() && st != schedule.RestoreState {
+			continue
+		}
+		at, err := s.GetNextTrigger(now)
+		if err != nil {
+			continue
+		}
+		if obj.IsSnoozed(at) {
+			at = *obj.SnoozeUntil
+		}
+		if next == nil || at.Before(*next) {
+			next = &at
+		}
+	}
+	return next
+}
+
+// publishScale will publish the scale activity of given event.
+func (a *worker) publishScale(e *event, repl int, err error) {
+	act := objectActivity(ActivityScale, e.obj)
+	act.Time = e.at
+	act.Replicas = &repl
+	act.Schedule = e.sched.Description
+	if err != nil {
+		act.Error = err.Error()
+	}
+	a.publish(act)
+}
+
+// getProfile will return the resources profile that should be applied by the
+// given event. It will return nil if the schedule has no resources setting.
+func getProfile(e *event) (*scanner.Profile, error) {
+	if !e.sched.HasResources() {
+		return nil, nil
+	}
+	return scanner.GetProfile(e.sched.GetResources())
+}
+
+// restoreProfile will return the profile that restores the saved container
+// resources of given object, or nil if no resources were saved.
+func restoreProfile(obj *scanner.Object) *scanner.Profile {
+	if obj.State == nil || len(obj.State.Resources) == 0 {
+		return nil
+	}
+	return scanner.RestoreProfile
+}
+
+// getState will return the state against which relative replicas of the
+// given event are resolved; the state that is saved by this event, or the
+// previously saved state of the object. It will return nil if no state is
+// available.
+func getState(e *event) *int {
+	if e.state != nil {
+		return e.state
+	}
+	if e.obj.State != nil {
+		return &e.obj.State.Replicas
 	}
+	return nil
 }
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/schedule"
 )
@@ -100,6 +102,7 @@ func TestGetEvents(t *testing.T) {
 func TestHandleStateScale(t *testing.T) {
 	mock := &mockScanner{}
 	scanner.RegisterModule("scanner", getScannerFactory("scanner", mock))
+	scanner.AddProfile(&scanner.Profile{Name: "small"})
 
 	tests := []struct {
 		sched   string
@@ -107,6 +110,7 @@ func TestHandleStateScale(t *testing.T) {
 		restore bool
 		save    bool
 		scale   int
+		profile string
 	}{
 		{
 			sched:   "Mon-Fri 8:00 replicas=3 state=restore",
@@ -129,6 +133,66 @@ func TestHandleStateScale(t *testing.T) {
 			save:    true,
 			scale:   2,
 		},
+		{
+			sched:   "Mon-Fri 20:00 replicas=50%",
+			obj:     &scanner.Object{Replicas: 4, State: &scanner.State{Replicas: 6}},
+			restore: false,
+			save:    false,
+			scale:   3,
+		},
+		{
+			sched:   "Mon-Fri 20:00 replicas=-2 min=1",
+			obj:     &scanner.Object{Replicas: 2},
+			restore: false,
+			save:    false,
+			scale:   1,
+		},
+		{
+			sched:   "Mon-Fri 20:00 replicas=max(1,state/2) state=save",
+			obj:     &scanner.Object{Replicas: 5, State: &scanner.State{Replicas: 8}},
+			restore: false,
+			save:    true,
+			scale:   1,
+		},
+		{
+			sched:   "Mon-Fri 20:00 resources=small",
+			obj:     &scanner.Object{Replicas: 2},
+			restore: false,
+			save:    false,
+			scale:   2,
+			profile: "small",
+		},
+		{
+			sched:   "Mon-Fri 20:00 replicas=1 resources=small state=save",
+			obj:     &scanner.Object{Replicas: 2},
+			restore: false,
+			save:    true,
+			scale:   1,
+			profile: "small",
+		},
+		{
+			sched:   "Mon-Fri 20:00 replicas=1 resources=large",
+			obj:     &scanner.Object{Replicas: 2},
+			restore: false,
+			save:    false,
+			scale:   -1,
+		},
+		{
+			sched:   "Mon-Fri 8:00 state=restore",
+			obj:     &scanner.Object{Replicas: 1, State: &scanner.State{Replicas: 3, Resources: map[string]corev1.ResourceRequirements{"app": {}}}},
+			restore: true,
+			save:    false,
+			scale:   3,
+			profile: "restore",
+		},
+		{
+			sched:   "Mon-Fri 8:00 resources=restore",
+			obj:     &scanner.Object{Replicas: 1, State: &scanner.State{Replicas: 3}},
+			restore: false,
+			save:    false,
+			scale:   1,
+			profile: "restore",
+		},
 	}
 
 	for i, tst := range tests {
@@ -137,6 +201,8 @@ func TestHandleStateScale(t *testing.T) {
 		sc, _ := schedule.New(tst.sched)
 		tst.obj.Schedule = []*schedule.Schedule{sc}
 		mock.save = false
+		mock.scale = -1
+		mock.profile = nil
 
 		evt := &event{
 			obj:     tst.obj,
@@ -156,6 +222,101 @@ func TestHandleStateScale(t *testing.T) {
 		if mock.scale != tst.scale {
 			t.Errorf("failed test %d - invalid scaling, expected: %d replicas, got %d replicas", i, tst.scale, mock.scale)
 		}
+		if profile := getProfileName(mock.profile); profile != tst.profile {
+			t.Errorf("failed test %d - invalid profile, expected: '%s', got '%s'", i, tst.profile, profile)
+		}
+	}
+
+}
+
+func getProfileName(p *scanner.Profile) string {
+	if p == nil {
+		return ""
+	}
+	return p.Name
+}
+
+func TestSnoozeEvents(t *testing.T) {
+	agent := &worker{}
+	sc, _ := schedule.New("Mon-Fri 18:00 replicas=0")
+	until := time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)
+	obj := &scanner.Object{UID: "abc", Schedule: []*schedule.Schedule{sc}, SnoozeUntil: &until}
+
+	agent.past = time.Date(2019, 3, 4, 17, 0, 0, 0, time.UTC) // monday
+	agent.now = time.Date(2019, 3, 4, 19, 0, 0, 0, time.UTC)
+	evts := agent.getEvents(obj)
+	if len(evts) != 1 || !obj.IsSnoozed(evts[0].at) {
+		t.Fatalf("failed test - expected 1 snoozed event, got %v", evts)
 	}
+	agent.snooze(evts[0])
+	if e := agent.resumeSnoozed(obj); e != nil {
+		t.Errorf("failed test - event resumed while still snoozed: %v", e)
+	}
+
+	agent.now = time.Date(2019, 3, 4, 23, 1, 0, 0, time.UTC)
+	cur := obj.Copy()
+	e := agent.resumeSnoozed(cur)
+	if e == nil {
+		t.Fatalf("failed test - expected snoozed event to be resumed")
+	}
+	if e.obj != cur || e.sched != sc {
+		t.Errorf("failed test - invalid resumed event: %v", e)
+	}
+	if e := agent.resumeSnoozed(cur); e != nil {
+		t.Errorf("failed test - event resumed twice: %v", e)
+	}
+
+	// removing the snooze will resume the skipped event as well
+	agent.now = time.Date(2019, 3, 4, 19, 0, 0, 0, time.UTC)
+	agent.snooze(evts[0])
+	cur.SnoozeUntil = nil
+	if e := agent.resumeSnoozed(cur); e == nil {
+		t.Errorf("failed test - expected event to be resumed after snooze was removed")
+	}
+}
+
+func TestNextScale(t *testing.T) {
+	now := time.Date(2019, 3, 4, 12, 0, 0, 0, time.UTC) // monday
+	snooze := time.Date(2019, 3, 4, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		sched  []string
+		snooze *time.Time
+		next   *time.Time
+	}{
+		{
+			sched: []string{"Mon-Fri 8:00 replicas=1", "Mon-Fri 18:00 replicas=0"},
+			next:  timePtr(time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)),
+		},
+		{
+			sched: []string{"Mon-Fri 8:00 state=restore", "Mon-Fri 18:00 trigger=webhook"},
+			next:  timePtr(time.Date(2019, 3, 5, 8, 0, 0, 0, time.UTC)),
+		},
+		{
+			sched:  []string{"Mon-Fri 8:00 replicas=1", "Mon-Fri 18:00 replicas=0"},
+			snooze: &snooze,
+			next:   &snooze,
+		},
+		{
+			sched: []string{"Mon-Fri 18:00 trigger=webhook"},
+			next:  nil,
+		},
+	}
+	for i, tst := range tests {
+		obj := &scanner.Object{SnoozeUntil: tst.snooze}
+		for _, s := range tst.sched {
+			sched, err := schedule.New(s)
+			if err != nil {
+				t.Fatalf("failed test %d - unexpected err: %s", i, err)
+			}
+			obj.Schedule = append(obj.Schedule, sched)
+		}
+		next := nextScale(obj, now)
+		if (next == nil) != (tst.next == nil) || (next != nil && !next.Equal(*tst.next)) {
+			t.Errorf("failed test %d - expected %v, got %v", i, tst.next, next)
+		}
+	}
+}
 
+func timePtr(t time.Time) *time.Time {
+	return &t
 }
@@ -7,12 +7,13 @@ import (
 
 // mockScanner is a generic mock for scanners
 type mockScanner struct {
-	id    int
-	scale int
-	save  bool
-	stop  bool
-	out   chan scanner.Event
-	objs  []*scanner.Object
+	id      int
+	scale   int
+	profile *scanner.Profile
+	save    bool
+	stop    bool
+	out     chan scanner.Event
+	objs    []*scanner.Object
 }
 
 func (m *mockScanner) SetConfig(c scanner.Config) {
@@ -31,8 +32,13 @@ func (m *mockScanner) GetState(obj *scanner.Object) (int, error) {
 	return 0, nil
 }
 
-func (m *mockScanner) Scale(obj *scanner.Object, state *int, r int) error {
+func (m *mockScanner) Scale(obj *scanner.Object, state *int, r int, p *scanner.Profile) error {
 	m.scale = r
+	m.profile = p
+	return nil
+}
+
+func (m *mockScanner) Annotate(obj *scanner.Object, annotations map[string]string) error {
 	return nil
 }
 
@@ -54,6 +60,7 @@ type mockTrigger struct {
 	err  error
 	exc  int
 	objs []*scanner.Object
+	evt  trigger.Event
 	cfg  trigger.Config
 }
 
@@ -66,10 +73,11 @@ func (m *mockTrigger) GetConfig() trigger.Config {
 	return m.cfg
 }
 
-func (m *mockTrigger) Execute(objs []*scanner.Object) error {
+func (m *mockTrigger) Execute(evt trigger.Event) (trigger.Result, error) {
 	m.exc++
-	m.objs = append(m.objs, objs...)
-	return m.err
+	m.evt = evt
+	m.objs = append(m.objs, evt.Objects...)
+	return nil, m.err
 }
 
 func getTriggerFactory(typ string, m *mockTrigger) trigger.Factory {
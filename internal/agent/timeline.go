@@ -0,0 +1,241 @@
+package agent
+
+import (
+	"sort"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+// timelineLookBack is the period before the start of a timeline that is
+// simulated to determine the number of replicas at the start of the timeline.
+const timelineLookBack = 7 * 24 * time.Hour
+
+// Timeline types.
+const (
+	TimelineObject  = "object"
+	TimelineScanner = "scanner"
+)
+
+// Timeline describes the scale events that are expected within a period for
+// an object or a scanner, and for objects the expected number of replicas
+// over time. For objects, the schedule of the scanner with the highest
+// priority (or the schedule annotation) is used.
+type Timeline struct {
+	Kind      string          `json:"kind"`
+	Id        string          `json:"id"`
+	Namespace string          `json:"namespace"`
+	Name      string          `json:"name,omitempty"`
+	Type      string          `json:"type"`
+	ScannerId string          `json:"scanner_id,omitempty"`
+	Cluster   string          `json:"cluster,omitempty"`
+	Events    []TimelineEvent `json:"events"`
+	Replicas  []ReplicaPoint  `json:"replicas,omitempty"`
+}
+
+// TimelineEvent describes an expected scale event. Replicas is the number of
+// replicas after the event, if the event scales the object. Conditional events
+// depend on a when expression or idle condition, and are expected to be
+// applied. Snoozed events are skipped, and Resumed events are skipped events
+// that are applied when the snooze ends.
+type TimelineEvent struct {
+	Time        time.Time `json:"time"`
+	Schedule    string    `json:"schedule"`
+	Replicas    *int      `json:"replicas,omitempty"`
+	Conditional bool      `json:"conditional,omitempty"`
+	Snoozed     bool      `json:"snoozed,omitempty"`
+	Resumed     bool      `json:"resumed,omitempty"`
+}
+
+// ReplicaPoint is the expected number of replicas from given time on.
+type ReplicaPoint struct {
+	Time     time.Time `json:"time"`
+	Replicas int       `json:"replicas"`
+}
+
+// GetTimeline will return the timelines of all objects that have a schedule,
+// and of all scanners, for the given period.
+func (a *worker) GetTimeline(from, to time.Time) []Timeline {
+	res := []Timeline{}
+	for _, obj := range a.GetObjects() {
+		if len(obj.Schedule) > 0 {
+			res = append(res, objectTimeline(obj, from, to))
+		}
+	}
+	for _, scnr := range a.GetScanners() {
+		cfg := scnr.GetConfig()
+		res = append(res, Timeline{
+			Kind:      TimelineScanner,
+			Id:        cfg.Id,
+			Namespace: cfg.Namespace,
+			Type:      cfg.Type,
+			Cluster:   cfg.Cluster,
+			Events:    scheduleEvents(cfg.Schedule, from, to),
+		})
+	}
+	return res
+}
+
+// objectTimeline will return the timeline of given object for given period.
+// The number of replicas at the start of the period is determined by
+// simulating the events of the preceding week, starting with the current
+// number of replicas and state. This is an approximation; the actual number
+// of replicas in the past is not known, so until the first event that scales
+// the object, the current number of replicas is assumed.
+func objectTimeline(obj *scanner.Object, from, to time.Time) Timeline {
+	tl := Timeline{
+		Kind:      TimelineObject,
+		Id:        obj.UID,
+		Namespace: obj.Namespace,
+		Name:      obj.Name,
+		Type:      obj.Type,
+		ScannerId: obj.ScannerId,
+		Cluster:   obj.Cluster,
+		Events:    []TimelineEvent{},
+	}
+	sim := &simulation{obj: obj, replicas: obj.Replicas}
+	if obj.State != nil {
+		state := obj.State.Replicas
+		sim.state = &state
+	}
+	for _, evt := range sim.run(scheduleEvents(obj.Schedule, from.Add(-timelineLookBack), to), to) {
+		if evt.Time.Before(from) {
+			continue
+		}
+		tl.Events = append(tl.Events, evt)
+	}
+	tl.Replicas = []ReplicaPoint{{Time: from, Replicas: sim.at(from)}}
+	for _, pnt := range sim.curve {
+		if pnt.Time.After(from) && pnt.Replicas != tl.Replicas[len(tl.Replicas)-1].Replicas {
+			tl.Replicas = append(tl.Replicas, pnt)
+		}
+	}
+	return tl
+}
+
+// scheduleEvents will return the events of given schedules within given
+// period, in chronological order.
+func scheduleEvents(sched []*schedule.Schedule, from, to time.Time) []TimelineEvent {
+	res := []TimelineEvent{}
+	for _, s := range sched {
+		for next := from; ; {
+			at, err := s.GetNextTrigger(next)
+			if err != nil {
+				logger.Error("Error processing trigger", "schedule", s.Description, logging.Err(err))
+				break
+			}
+			if at.After(to) {
+				break
+			}
+			res = append(res, TimelineEvent{
+				Time:        at,
+				Schedule:    s.Description,
+				Conditional: s.HasWhen() || s.HasIdle(),
+			})
+			next = at.Add(time.Minute)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Time.Before(res[j].Time) })
+	return res
+}
+
+// simulation will determine the expected number of replicas of an object by
+// applying its scale events in the same way the agent does.
+type simulation struct {
+	obj      *scanner.Object
+	replicas int
+	state    *int
+	curve    []ReplicaPoint
+}
+
+// run will apply the given events, up to given time, and will return the
+// events annotated with the resulting number of replicas. Events while the
+// object is snoozed are skipped, and the last skipped event is applied when
+// the snooze ends.
+func (sim *simulation) run(events []TimelineEvent, to time.Time) []TimelineEvent {
+	res := []TimelineEvent{}
+	var pending *TimelineEvent
+	resume := func() {
+		if pending != nil {
+			res = append(res, sim.apply(TimelineEvent{
+				Time:     *sim.obj.SnoozeUntil,
+				Schedule: pending.Schedule,
+				Resumed:  true,
+			}))
+			pending = nil
+		}
+	}
+	for _, evt := range events {
+		if sim.obj.IsSnoozed(evt.Time) {
+			evt.Snoozed = true
+			pending = &evt
+			res = append(res, evt)
+			continue
+		}
+		resume()
+		res = append(res, sim.apply(evt))
+	}
+	if pending != nil && !sim.obj.SnoozeUntil.After(to) {
+		resume()
+	}
+	return res
+}
+
+// apply will apply the given event, and will return the event annotated with
+// the resulting number of replicas.
+func (sim *simulation) apply(evt TimelineEvent) TimelineEvent {
+	sched := sim.schedule(evt.Schedule)
+	if sched == nil {
+		return evt
+	}
+	st, err := sched.GetState()
+	if err != nil {
+		return evt
+	}
+	if st == schedule.SaveState {
+		state := sim.replicas
+		sim.state = &state
+	}
+	repl := sim.replicas
+	switch {
+	case st == schedule.RestoreState:
+		if sim.state == nil {
+			return evt
+		}
+		repl = *sim.state
+	case sched.HasReplicas():
+		if repl, err = sched.ResolveReplicas(sim.replicas, sim.state); err != nil {
+			return evt
+		}
+	default:
+		return evt
+	}
+	sim.replicas = repl
+	evt.Replicas = &repl
+	sim.curve = append(sim.curve, ReplicaPoint{Time: evt.Time, Replicas: repl})
+	return evt
+}
+
+// schedule will return the schedule of the object with given description.
+func (sim *simulation) schedule(desc string) *schedule.Schedule {
+	for _, s := range sim.obj.Schedule {
+		if s.Description == desc {
+			return s
+		}
+	}
+	return nil
+}
+
+// at will return the expected number of replicas at given time.
+func (sim *simulation) at(t time.Time) int {
+	repl := sim.obj.Replicas
+	for _, pnt := range sim.curve {
+		if pnt.Time.After(t) {
+			break
+		}
+		repl = pnt.Replicas
+	}
+	return repl
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+func TestObjectTimeline(t *testing.T) {
+	from := time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC) // monday
+	snooze := time.Date(2019, 3, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		sched    []string
+		replicas int
+		state    *scanner.State
+		snooze   *time.Time
+		events   []int // expected replicas per event; -1 for no scaling
+		curve    []ReplicaPoint
+	}{
+		{
+			sched:    []string{"Mon-Fri 8:00 replicas=2", "Mon-Fri 18:00 replicas=0"},
+			replicas: 5,
+			events:   []int{2, 0},
+			curve: []ReplicaPoint{
+				{from, 0},
+				{time.Date(2019, 3, 4, 8, 0, 0, 0, time.UTC), 2},
+				{time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC), 0},
+			},
+		},
+		{
+			sched:    []string{"Mon 8:00 state=restore", "Mon 18:00 state=save replicas=0"},
+			replicas: 3,
+			events:   []int{3, 0},
+			curve: []ReplicaPoint{
+				{from, 0},
+				{time.Date(2019, 3, 4, 8, 0, 0, 0, time.UTC), 3},
+				{time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC), 0},
+			},
+		},
+		{
+			sched:    []string{"Mon 8:00 state=restore", "Sun 18:00 state=save replicas=0"},
+			replicas: 3,
+			events:   []int{3},
+			curve: []ReplicaPoint{
+				{from, 0},
+				{time.Date(2019, 3, 4, 8, 0, 0, 0, time.UTC), 3},
+			},
+		},
+		{
+			sched:    []string{"Mon 8:00 replicas=+1", "Mon 10:00 replicas=state", "Mon 12:00 trigger=webhook"},
+			replicas: 1,
+			state:    &scanner.State{Replicas: 4},
+			events:   []int{5, 4, -1},
+			curve: []ReplicaPoint{
+				{from, 4},
+				{time.Date(2019, 3, 4, 8, 0, 0, 0, time.UTC), 5},
+				{time.Date(2019, 3, 4, 10, 0, 0, 0, time.UTC), 4},
+			},
+		},
+		{
+			sched:    []string{"Mon-Fri 8:00 replicas=2", "Mon-Fri 18:00 replicas=0"},
+			replicas: 0,
+			snooze:   &snooze,
+			events:   []int{-1, 2, 0},
+			curve: []ReplicaPoint{
+				{from, 0},
+				{snooze, 2},
+				{time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC), 0},
+			},
+		},
+	}
+
+	for i, tst := range tests {
+		obj := &scanner.Object{UID: "abc", Replicas: tst.replicas, State: tst.state, SnoozeUntil: tst.snooze}
+		for _, s := range tst.sched {
+			sc, err := schedule.New(s)
+			if err != nil {
+				t.Fatalf("failed test %d - invalid schedule: %s", i, err)
+			}
+			obj.Schedule = append(obj.Schedule, sc)
+		}
+		tl := objectTimeline(obj, from, from.Add(24*time.Hour))
+		if tl.Kind != TimelineObject || tl.Id != "abc" {
+			t.Errorf("failed test %d - unexpected timeline: %v", i, tl)
+		}
+		if len(tl.Events) != len(tst.events) {
+			t.Errorf("failed test %d - expected %d events, got %d: %v", i, len(tst.events), len(tl.Events), tl.Events)
+			continue
+		}
+		for j, evt := range tl.Events {
+			repl := -1
+			if evt.Replicas != nil {
+				repl = *evt.Replicas
+			}
+			if repl != tst.events[j] {
+				t.Errorf("failed test %d - event %d expected %d replicas, got %d", i, j, tst.events[j], repl)
+			}
+		}
+		if len(tl.Replicas) != len(tst.curve) {
+			t.Errorf("failed test %d - expected curve %v, got %v", i, tst.curve, tl.Replicas)
+			continue
+		}
+		for j, pnt := range tl.Replicas {
+			if !pnt.Time.Equal(tst.curve[j].Time) || pnt.Replicas != tst.curve[j].Replicas {
+				t.Errorf("failed test %d - expected curve %v, got %v", i, tst.curve, tl.Replicas)
+				break
+			}
+		}
+	}
+}
+
+func TestScheduleEvents(t *testing.T) {
+	from := time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC) // monday
+	s1, _ := schedule.New("Mon-Fri 8:00 replicas=1")
+	s2, _ := schedule.New("Mon-Fri 18:00 replicas=0 when=\"true\"")
+	evts := scheduleEvents([]*schedule.Schedule{s1, s2}, from, from.Add(7*24*time.Hour))
+	if len(evts) != 10 {
+		t.Fatalf("failed test - expected 10 events, got %d", len(evts))
+	}
+	for i, evt := range evts {
+		if i > 0 && evt.Time.Before(evts[i-1].Time) {
+			t.Errorf("failed test - events not in chronological order: %v", evts)
+		}
+		if evt.Conditional != (evt.Schedule == s2.Description) {
+			t.Errorf("failed test - unexpected conditional for %v", evt)
+		}
+	}
+}
@@ -1,63 +1,230 @@
 package agent
 
 import (
-	"github.com/golang/glog"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/metrics"
 	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/tracing"
+	"github.com/joyrex2001/nightshift/internal/trigger"
 )
 
+// ErrQueueFull is returned when a trigger can't be queued, because the
+// trigger queue is full.
+var ErrQueueFull = errors.New("trigger queue is full")
+
+// maxTriggerQueue is the maximum number of executions that can be queued for
+// a single trigger.
+const maxTriggerQueue = 100
+
+// maxHistory is the maximum number of trigger executions kept in the trigger
+// history.
+const maxHistory = 100
+
 type triggr struct {
-	id      string
-	objects []*scanner.Object
+	id        string
+	objects   []*scanner.Object
+	at        time.Time
+	schedules []string
+	ctx       context.Context
 }
 
-// StartTrigger will consume the triggerqueue channel and execute each
-// triggers sequentially. It will block until the channel is closed.
+// StartTrigger will consume the triggerqueue channel and execute the triggers.
+// Each trigger is executed by its own worker, so the executions of a trigger
+// are sequential, while a trigger that takes long (e.g. a job or command that
+// is waited for) doesn't delay the other triggers. If the queue of a trigger
+// is full, the execution is dropped. It will block until the channel is
+// closed, and the queued executions are finished.
 func (a *worker) StartTrigger() {
+	queues := map[string]chan triggr{}
+	wg := sync.WaitGroup{}
 	for tr := range a.trigqueue {
-		trgr, ok := a.triggers[tr.id]
-		if !ok {
-			glog.Errorf("Non existing trigger called: %s", tr.id)
+		if _, ok := a.getTrigger(tr.id); !ok {
+			logger.Error("Non existing trigger called", logging.TriggerId(tr.id))
 			continue
 		}
-		if err := trgr.Execute(tr.objects); err != nil {
-			glog.Errorf("Error execute trigger: %s", err)
+		queue, ok := queues[tr.id]
+		if !ok {
+			queue = make(chan triggr, maxTriggerQueue)
+			queues[tr.id] = queue
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				a.executeQueue(queue)
+			}()
+		}
+		select {
+		case queue <- tr:
+		default:
+			logger.Error("Trigger queue is full, dropped execution", logging.TriggerId(tr.id), logging.EventTime(tr.at))
+			metrics.Increase("trigger_queue_full")
+		}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+}
+
+// executeQueue will execute the triggers in given queue sequentially, until
+// the queue is closed.
+func (a *worker) executeQueue(queue chan triggr) {
+	for tr := range queue {
+		if trgr, ok := a.getTrigger(tr.id); ok {
+			a.execute(tr.id, trgr, tr.event())
 		}
 	}
 }
 
+// event will return the trigger event details for this triggr.
+func (tr *triggr) event() trigger.Event {
+	return trigger.Event{
+		Objects:  tr.objects,
+		Time:     tr.at,
+		Schedule: strings.Join(tr.schedules, "; "),
+		Context:  tr.ctx,
+	}
+}
+
+// execute will execute the given trigger for given event, and will add the
+// outcome of the execution to the trigger history.
+func (a *worker) execute(id string, trgr trigger.Trigger, evt trigger.Event) {
+	exec := trigger.Execution{
+		Id:      id,
+		Type:    trgr.GetConfig().Type,
+		Start:   time.Now(),
+		Objects: []string{},
+	}
+	for _, obj := range evt.Objects {
+		exec.Objects = append(exec.Objects, fmt.Sprintf("%s/%s", obj.Namespace, obj.Name))
+	}
+	ctx, span := tracing.Start(evt.Context, "trigger",
+		attribute.String("nightshift.trigger.id", id),
+		attribute.String("nightshift.trigger.type", exec.Type),
+		attribute.StringSlice("nightshift.objects", exec.Objects),
+	)
+	evt.Context = ctx
+	res, err := trgr.Execute(evt)
+	tracing.End(span, err)
+	exec.Duration = time.Since(exec.Start)
+	exec.Result = res
+	metrics.ObserveTrigger(id, exec.Type, exec.Duration, err)
+	metrics.Increase("trigger")
+	if err != nil {
+		logger.Error("Error execute trigger", logging.TriggerId(id), logging.EventTime(evt.Time), logging.Err(err))
+		metrics.Increase("trigger_error")
+		exec.Error = err.Error()
+	} else {
+		logger.Info("Executed trigger", logging.TriggerId(id), "trigger_type", exec.Type, logging.EventTime(evt.Time), "objects", len(exec.Objects), "duration", exec.Duration)
+	}
+	a.addHistory(exec)
+	a.publish(Activity{
+		Type:     ActivityTrigger,
+		Time:     exec.Start,
+		Trigger:  id,
+		Schedule: evt.Schedule,
+		Objects:  exec.Objects,
+		Error:    exec.Error,
+	})
+}
+
+// addHistory will add the given trigger execution to the trigger history. If
+// the history exceeds maxHistory entries, the oldest entries are removed.
+func (a *worker) addHistory(exec trigger.Execution) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.history = append(a.history, exec)
+	if len(a.history) > maxHistory {
+		a.history = a.history[len(a.history)-maxHistory:]
+	}
+}
+
+// GetTriggerHistory will return the most recent trigger executions, oldest
+// first.
+func (a *worker) GetTriggerHistory() []trigger.Execution {
+	a.m.Lock()
+	defer a.m.Unlock()
+	hist := make([]trigger.Execution, len(a.history))
+	copy(hist, a.history)
+	return hist
+}
+
 // StopTrigger will stop the scaling loop.
 func (a *worker) StopTrigger() {
 	close(a.trigqueue)
 }
 
-// appendTrigger will append given object with given trigger ids to the given
-// list of triggr objects, and will return the appended result. The result is
-// a normalized list trigger id's with the corresponding objects that were
-// scaled.
-func (a *worker) appendTrigger(list []*triggr, obj *scanner.Object, ids []string) []*triggr {
-	for _, id := range ids {
-		newid := true
+// appendTrigger will append the object of given event to the given list of
+// triggr objects, for each trigger id in the schedule of the event, and will
+// return the appended result. The result is a normalized list trigger id's
+// with the corresponding objects that were scaled, the time of the first event
+// and the unique schedules that caused the trigger.
+func (a *worker) appendTrigger(list []*triggr, e *event) []*triggr {
+	for _, id := range e.sched.GetTriggers() {
+		var trgr *triggr
 		for _, tr := range list {
 			if tr.id == id {
-				tr.objects = append(tr.objects, obj)
-				newid = false
+				trgr = tr
 				break
 			}
 		}
-		if newid {
-			list = append(list, &triggr{id, []*scanner.Object{obj}})
+		if trgr == nil {
+			trgr = &triggr{id: id, at: e.at, ctx: a.ctx}
+			list = append(list, trgr)
+		}
+		trgr.objects = append(trgr.objects, e.obj)
+		if !hasString(trgr.schedules, e.sched.Description) {
+			trgr.schedules = append(trgr.schedules, e.sched.Description)
 		}
 	}
 	return list
 }
 
+// hasString will return true if given list contains given string.
+func hasString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// QueueTrigger will enqueue the trigger with given id for given event, in
+// order to execute a trigger on demand. It will return an error if the trigger
+// does not exist, or ErrQueueFull if the trigger queue is full; it will never
+// block the caller.
+func (a *worker) QueueTrigger(id string, evt trigger.Event) error {
+	if _, ok := a.getTrigger(id); !ok {
+		return fmt.Errorf("non existing trigger: %s", id)
+	}
+	tr := triggr{id: id, objects: evt.Objects, at: evt.Time, ctx: evt.Context}
+	if evt.Schedule != "" {
+		tr.schedules = []string{evt.Schedule}
+	}
+	select {
+	case a.trigqueue <- tr:
+		return nil
+	default:
+		metrics.Increase("trigger_queue_full")
+		return ErrQueueFull
+	}
+}
+
 // queueTriggers will enqueue the collected triggers as specified in the
 // prodived list of trigger id's. Each trigger will be enqueued just once.
 func (a *worker) queueTriggers(list []*triggr) {
-	glog.V(6).Infof("trigger to be queueed: %#v", list)
+	logger.Log(context.Background(), logging.LevelTrace, "Triggers to be queued", "triggers", len(list))
 	for _, tr := range list {
-		glog.V(6).Infof("trigger added to queue: %#v", tr)
+		logger.Log(context.Background(), logging.LevelTrace, "Trigger added to queue", logging.TriggerId(tr.id), logging.EventTime(tr.at), "objects", len(tr.objects))
 		a.trigqueue <- *tr
 	}
 }
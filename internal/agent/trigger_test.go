@@ -7,9 +7,17 @@ import (
 	"time"
 
 	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
 	"github.com/joyrex2001/nightshift/internal/trigger"
 )
 
+// newTriggerEvent will return a scale event for given object with a schedule
+// that has given triggers.
+func newTriggerEvent(at time.Time, obj *scanner.Object, triggers string) *event {
+	sched, _ := schedule.New("mon 10:00 trigger=" + triggers)
+	return &event{at: at, obj: obj, sched: sched}
+}
+
 func TestHandleTriggers(t *testing.T) {
 	agent := &worker{}
 	agent.triggers = map[string]trigger.Trigger{}
@@ -30,11 +38,12 @@ func TestHandleTriggers(t *testing.T) {
 	obj3 := &scanner.Object{}
 	obj4 := &scanner.Object{}
 
-	agent.queueTriggers(agent.appendTrigger([]*triggr{}, obj1, []string{"trigger1"}))
-	agent.queueTriggers(agent.appendTrigger([]*triggr{}, obj2, []string{"trigger2"}))
-	agent.queueTriggers(agent.appendTrigger([]*triggr{}, obj3, []string{"trigger1"}))
-	agent.queueTriggers(agent.appendTrigger([]*triggr{}, obj4, []string{"trigger1"}))
-	agent.queueTriggers(agent.appendTrigger([]*triggr{}, obj4, []string{"trigger4"}))
+	now := time.Now()
+	agent.queueTriggers(agent.appendTrigger([]*triggr{}, newTriggerEvent(now, obj1, "trigger1")))
+	agent.queueTriggers(agent.appendTrigger([]*triggr{}, newTriggerEvent(now, obj2, "trigger2")))
+	agent.queueTriggers(agent.appendTrigger([]*triggr{}, newTriggerEvent(now, obj3, "trigger1")))
+	agent.queueTriggers(agent.appendTrigger([]*triggr{}, newTriggerEvent(now, obj4, "trigger1")))
+	agent.queueTriggers(agent.appendTrigger([]*triggr{}, newTriggerEvent(now, obj4, "trigger4")))
 
 	stopped := false
 	go func() {
@@ -50,6 +59,9 @@ func TestHandleTriggers(t *testing.T) {
 	if !reflect.DeepEqual(mock1.objs, []*scanner.Object{obj1, obj3, obj4}) {
 		t.Errorf("invalid number object for trigger 1; got %v", mock1.objs)
 	}
+	if !mock1.evt.Time.Equal(now) || mock1.evt.Schedule != "mon 10:00 trigger=trigger1" {
+		t.Errorf("invalid event details for trigger 1; got %#v", mock1.evt)
+	}
 	if mock2.exc != 1 {
 		t.Errorf("invalid number of calls to trigger 2; expected 1, got %d", mock2.exc)
 	}
@@ -70,6 +82,69 @@ func TestHandleTriggers(t *testing.T) {
 	}
 }
 
+// blockingTrigger is a trigger that will block until it is released.
+type blockingTrigger struct {
+	mockTrigger
+	release chan bool
+}
+
+func (m *blockingTrigger) Execute(evt trigger.Event) (trigger.Result, error) {
+	<-m.release
+	return m.mockTrigger.Execute(evt)
+}
+
+func TestTriggerHeadOfLine(t *testing.T) {
+	agent := &worker{}
+	agent.triggers = map[string]trigger.Trigger{}
+	agent.trigqueue = make(chan triggr, 500)
+
+	slow := &blockingTrigger{release: make(chan bool)}
+	fast := &mockTrigger{}
+	agent.AddTrigger("slow", slow)
+	agent.AddTrigger("fast", fast)
+
+	now := time.Now()
+	// more executions of the slow trigger than fit in its queue
+	for i := 0; i < maxTriggerQueue+10; i++ {
+		agent.queueTriggers(agent.appendTrigger([]*triggr{}, newTriggerEvent(now.Add(time.Duration(i)*time.Second), &scanner.Object{}, "slow")))
+	}
+	agent.queueTriggers(agent.appendTrigger([]*triggr{}, newTriggerEvent(now, &scanner.Object{}, "fast")))
+
+	stopped := make(chan bool)
+	go func() {
+		agent.StartTrigger()
+		close(stopped)
+	}()
+
+	for i := 0; i < 100 && len(agent.GetTriggerHistory()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	hist := agent.GetTriggerHistory()
+	if len(hist) != 1 || hist[0].Id != "fast" {
+		t.Errorf("expected fast trigger to be executed while slow trigger is blocked, got %v", hist)
+	}
+
+	agent.StopTrigger()
+	select {
+	case <-stopped:
+		t.Errorf("StopTrigger should wait for the queued executions")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(slow.release)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Errorf("StopTrigger did not stop the trigger")
+	}
+	// the executions that didn't fit in the queue are dropped
+	if slow.exc < maxTriggerQueue || slow.exc > maxTriggerQueue+1 {
+		t.Errorf("expected %d slow trigger executions, got %d", maxTriggerQueue, slow.exc)
+	}
+	if fast.exc != 1 {
+		t.Errorf("expected 1 fast trigger execution, got %d", fast.exc)
+	}
+}
+
 func TestAppendTriggers(t *testing.T) {
 	agent := &worker{}
 	agent.trigqueue = make(chan triggr, 500)
@@ -84,17 +159,20 @@ func TestAppendTriggers(t *testing.T) {
 	obj4 := &scanner.Object{}
 	obj5 := &scanner.Object{}
 
+	t1 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+
 	trgrs := []*triggr{}
-	trgrs = agent.appendTrigger(trgrs, obj1, []string{"trigger1"})
-	trgrs = agent.appendTrigger(trgrs, obj2, []string{"trigger2", "trigger3"})
-	trgrs = agent.appendTrigger(trgrs, obj3, []string{"trigger1"})
-	trgrs = agent.appendTrigger(trgrs, obj4, []string{"trigger2"})
-	trgrs = agent.appendTrigger(trgrs, obj5, []string{"trigger1"})
+	trgrs = agent.appendTrigger(trgrs, newTriggerEvent(t1, obj1, "trigger1"))
+	trgrs = agent.appendTrigger(trgrs, newTriggerEvent(t1, obj2, "trigger2,trigger3"))
+	trgrs = agent.appendTrigger(trgrs, newTriggerEvent(t2, obj3, "trigger1"))
+	trgrs = agent.appendTrigger(trgrs, newTriggerEvent(t2, obj4, "trigger2"))
+	trgrs = agent.appendTrigger(trgrs, newTriggerEvent(t2, obj5, "trigger1"))
 
 	exp := []*triggr{
-		{id: "trigger1", objects: []*scanner.Object{obj1, obj3, obj5}},
-		{id: "trigger2", objects: []*scanner.Object{obj2, obj4}},
-		{id: "trigger3", objects: []*scanner.Object{obj2}},
+		{id: "trigger1", objects: []*scanner.Object{obj1, obj3, obj5}, at: t1, schedules: []string{"mon 10:00 trigger=trigger1"}},
+		{id: "trigger2", objects: []*scanner.Object{obj2, obj4}, at: t1, schedules: []string{"mon 10:00 trigger=trigger2,trigger3", "mon 10:00 trigger=trigger2"}},
+		{id: "trigger3", objects: []*scanner.Object{obj2}, at: t1, schedules: []string{"mon 10:00 trigger=trigger2,trigger3"}},
 	}
 
 	for i, res := range exp {
@@ -103,3 +181,72 @@ func TestAppendTriggers(t *testing.T) {
 		}
 	}
 }
+
+func TestTriggerHistory(t *testing.T) {
+	agent := &worker{}
+	mock1 := &mockTrigger{cfg: trigger.Config{Type: "mock"}}
+	mock2 := &mockTrigger{err: fmt.Errorf("oops")}
+	obj := &scanner.Object{Namespace: "development", Name: "app1"}
+
+	agent.execute("trigger1", mock1, trigger.Event{Objects: []*scanner.Object{obj}})
+	agent.execute("trigger2", mock2, trigger.Event{Objects: []*scanner.Object{}})
+
+	hist := agent.GetTriggerHistory()
+	if len(hist) != 2 {
+		t.Fatalf("invalid number of history entries; expected 2, got %d", len(hist))
+	}
+	if hist[0].Id != "trigger1" || hist[0].Type != "mock" || hist[0].Error != "" {
+		t.Errorf("invalid history entry for trigger 1; got %#v", hist[0])
+	}
+	if !reflect.DeepEqual(hist[0].Objects, []string{"development/app1"}) {
+		t.Errorf("invalid objects in history entry for trigger 1; got %v", hist[0].Objects)
+	}
+	if hist[1].Id != "trigger2" || hist[1].Error != "oops" {
+		t.Errorf("invalid history entry for trigger 2; got %#v", hist[1])
+	}
+
+	for i := 0; i < maxHistory; i++ {
+		agent.execute("trigger1", mock1, trigger.Event{Objects: []*scanner.Object{}})
+	}
+	hist = agent.GetTriggerHistory()
+	if len(hist) != maxHistory {
+		t.Errorf("invalid number of history entries; expected %d, got %d", maxHistory, len(hist))
+	}
+	if hist[0].Id != "trigger1" {
+		t.Errorf("oldest history entries not removed; got %#v", hist[0])
+	}
+}
+
+func TestQueueTrigger(t *testing.T) {
+	agent := &worker{}
+	agent.trigqueue = make(chan triggr, 500)
+	agent.triggers = map[string]trigger.Trigger{
+		"trigger1": &mockTrigger{},
+	}
+	obj := &scanner.Object{}
+	now := time.Now()
+
+	if err := agent.QueueTrigger("trigger1", trigger.Event{Objects: []*scanner.Object{obj}, Time: now, Schedule: "hook wake"}); err != nil {
+		t.Errorf("unexpected err: %s", err)
+	}
+	if err := agent.QueueTrigger("trigger2", trigger.Event{}); err == nil {
+		t.Errorf("expected err for non existing trigger, but got none")
+	}
+	if len(agent.trigqueue) != 1 {
+		t.Fatalf("invalid number of queued triggers; expected 1, got %d", len(agent.trigqueue))
+	}
+	tr := <-agent.trigqueue
+	exp := triggr{id: "trigger1", objects: []*scanner.Object{obj}, at: now, schedules: []string{"hook wake"}}
+	if !reflect.DeepEqual(tr, exp) {
+		t.Errorf("failed QueueTrigger - expected %v, got %v", exp, tr)
+	}
+
+	// a full queue should not block
+	agent.trigqueue = make(chan triggr, 1)
+	if err := agent.QueueTrigger("trigger1", trigger.Event{}); err != nil {
+		t.Errorf("unexpected err: %s", err)
+	}
+	if err := agent.QueueTrigger("trigger1", trigger.Event{}); err != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+}
@@ -1,11 +1,11 @@
 package agent
 
 import (
+	"context"
 	"sync"
 	"time"
 
-	"github.com/golang/glog"
-
+	"github.com/joyrex2001/nightshift/internal/logging"
 	"github.com/joyrex2001/nightshift/internal/metrics"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 )
@@ -42,10 +42,10 @@ func (a *worker) UpdateSchedule() {
 	for _, scnr := range a.GetScanners() {
 		objs, err := scnr.GetObjects()
 		if err != nil {
-			glog.Errorf("Error scanning objects with %s: %s", scnr.GetConfig().Id, err)
+			logger.Error("Error scanning objects", scnr.GetConfig().LogAttr(), logging.Err(err))
 			metrics.Increase("resync_error")
 		}
-		glog.V(5).Infof("Scan result: %#v", objs)
+		logger.Log(context.Background(), logging.LevelTrace, "Scan result", scnr.GetConfig().LogAttr(), "objects", len(objs))
 		for _, obj := range objs {
 			a.addObject(obj)
 		}
@@ -59,7 +59,7 @@ func (a *worker) initWatchers() {
 		_quit := make(chan bool)
 		wtc, err := scnr.Watch(_quit)
 		if err != nil {
-			glog.Errorf("Error initialising watcher for scanner: %v", scnr.GetConfig())
+			logger.Error("Error initialising watcher for scanner", scnr.GetConfig().LogAttr(), logging.Err(err))
 		} else {
 			a.watchers = append(a.watchers, watch{wtc, make(chan bool), _quit})
 		}
@@ -92,12 +92,15 @@ func (a *worker) watchScanner(wtc watch) {
 			wtc._quit <- true
 			return
 		case event := <-wtc.event:
-			glog.V(4).Infof("Watch event: %v", event)
+			logger.Debug("Watch event", event.Object.LogAttr(), "event", event.Type)
 			if event.Type == scanner.EventRemove {
 				a.removeObject(event.Object)
 			} else {
 				a.addObject(event.Object)
 			}
+			act := objectActivity(ActivityWatch, event.Object)
+			act.Event = event.Type
+			a.publish(act)
 		}
 	}
 }
@@ -112,9 +115,9 @@ func (a *worker) resyncScanner(quit chan bool) {
 		case <-quit:
 			return
 		case <-tmr.C:
-			glog.V(4).Infof("Resync start...")
+			logger.Debug("Resync start...")
 			a.UpdateSchedule()
-			glog.V(4).Infof("Resync finisheded...")
+			logger.Debug("Resync finished...")
 		}
 	}
 }
@@ -0,0 +1,114 @@
+package condition
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+var (
+	env      *cel.Env
+	programs = map[string]cel.Program{}
+	pm       sync.Mutex
+)
+
+func init() {
+	var err error
+	env, err = cel.NewEnv(
+		cel.Variable("object", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("labels", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("annotations", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("replicas", cel.IntType),
+		cel.Variable("state", cel.IntType),
+		cel.Variable("time", cel.TimestampType),
+		cel.Variable("weekday", cel.IntType),
+		cel.Variable("hour", cel.IntType),
+		cel.Variable("minute", cel.IntType),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// CompileWhen will compile the given expression, and will return an error if
+// the expression is invalid, or does not return a boolean.
+func CompileWhen(expr string) error {
+	_, err := getProgram(expr)
+	return err
+}
+
+// When will evaluate the given CEL expression for the given object at given
+// time. The labels, annotations, current replicas and saved state (or -1 if
+// no state is saved) of the object are available, as well as its namespace,
+// name and type in object, and the time, and its weekday (0 is Sunday), hour
+// and minute in the configured timezone.
+func When(expr string, obj *scanner.Object, at time.Time) (bool, error) {
+	prg, err := getProgram(expr)
+	if err != nil {
+		return false, err
+	}
+	state := -1
+	if obj.State != nil {
+		state = obj.State.Replicas
+	}
+	local := at.In(schedule.GetTimeZone())
+	out, _, err := prg.Eval(map[string]interface{}{
+		"object": map[string]string{
+			"namespace": obj.Namespace,
+			"name":      obj.Name,
+			"type":      obj.Type,
+		},
+		"labels":      stringMap(obj.Labels),
+		"annotations": stringMap(obj.Annotations),
+		"replicas":    obj.Replicas,
+		"state":       state,
+		"time":        at,
+		"weekday":     int(local.Weekday()),
+		"hour":        local.Hour(),
+		"minute":      local.Minute(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("error evaluating '%s'; %s", expr, err)
+	}
+	res, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression '%s' did not return a boolean", expr)
+	}
+	return res, nil
+}
+
+// getProgram will return the compiled program for the given expression.
+// Compiled programs are cached.
+func getProgram(expr string) (cel.Program, error) {
+	pm.Lock()
+	defer pm.Unlock()
+	if prg, ok := programs[expr]; ok {
+		return prg, nil
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("invalid expression '%s'; %s", expr, iss.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression '%s' should return a bool, not %s", expr, ast.OutputType())
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	programs[expr] = prg
+	return prg, nil
+}
+
+// stringMap will return the given map, or an empty map if nil.
+func stringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
@@ -0,0 +1,77 @@
+package condition
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+func TestWhen(t *testing.T) {
+	schedule.SetTimeZone("Europe/Amsterdam")
+	defer schedule.SetTimeZone("UTC")
+	now := time.Date(2019, 3, 4, 17, 0, 0, 0, time.UTC) // monday, 18:00 in Amsterdam
+	obj := &scanner.Object{
+		Namespace:   "dev",
+		Name:        "frontend",
+		Type:        "deployment",
+		Labels:      map[string]string{"tier": "critical"},
+		Annotations: map[string]string{"owner": "team-a"},
+		Replicas:    2,
+		State:       &scanner.State{Replicas: 3},
+	}
+	tests := []struct {
+		expr string
+		obj  *scanner.Object
+		res  bool
+		err  bool
+	}{
+		{expr: "labels.tier != 'critical'", obj: obj, res: false},
+		{expr: "labels.tier == 'critical'", obj: obj, res: true},
+		{expr: "!('tier' in labels) || labels.tier != 'critical'", obj: &scanner.Object{}, res: true},
+		{expr: "annotations.owner == 'team-a' && object.namespace == 'dev' && object.name.startsWith('front')", obj: obj, res: true},
+		{expr: "object.type == 'deployment'", obj: obj, res: true},
+		{expr: "replicas > 1 && state == 3", obj: obj, res: true},
+		{expr: "state == -1", obj: &scanner.Object{}, res: true},
+		{expr: "weekday == 1 && hour == 18 && minute == 0", obj: obj, res: true},
+		{expr: "time > timestamp('2019-03-04T16:00:00Z')", obj: obj, res: true},
+		{expr: "labels.tier != 'critical'", obj: &scanner.Object{}, err: true},
+		{expr: "labels.tier", obj: obj, err: true},
+		{expr: "labels.tier !=", obj: obj, err: true},
+		{expr: "unknown == 1", obj: obj, err: true},
+	}
+	for i, tst := range tests {
+		res, err := When(tst.expr, tst.obj, now)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if res != tst.res {
+			t.Errorf("failed test %d - expected %t, got %t", i, tst.res, res)
+		}
+	}
+}
+
+func TestCompileWhen(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  bool
+	}{
+		{expr: "labels.tier != 'critical'", err: false},
+		{expr: "hour >= 18 || weekday == 0", err: false},
+		{expr: "replicas + 1", err: true},
+		{expr: "labels.tier != ", err: true},
+	}
+	for i, tst := range tests {
+		err := CompileWhen(tst.expr)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+	}
+}
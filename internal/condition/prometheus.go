@@ -0,0 +1,186 @@
+package condition
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// Prometheus describes the Prometheus endpoint against which idle conditions
+// are evaluated.
+type Prometheus struct {
+	URL       string
+	TokenFile string
+	CAFile    string
+	Timeout   time.Duration
+}
+
+// queryResponse is the response of the Prometheus instant query api.
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+var (
+	m      sync.Mutex
+	prom   *Prometheus
+	client *http.Client
+)
+
+var logger = logging.For(logging.Agent)
+
+// SetPrometheus will configure the Prometheus endpoint against which idle
+// conditions are evaluated.
+func SetPrometheus(cfg Prometheus) error {
+	m.Lock()
+	defer m.Unlock()
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return err
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	tlscfg := &tls.Config{}
+	if cfg.CAFile != "" {
+		ca, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlscfg.RootCAs = pool
+	}
+	prom = &cfg
+	client = &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: &http.Transport{TLSClientConfig: tlscfg},
+	}
+	return nil
+}
+
+// Idle will evaluate the given PromQL query for the given object at given
+// time. The idle condition holds if the query returns a non-empty result (as
+// with Prometheus alerting rules), or a non-zero scalar. The query is rendered
+// as a template, with the namespace, name and labels of the object available
+// as .namespace, .name and .labels.
+func Idle(query string, obj *scanner.Object, at time.Time) (bool, error) {
+	m.Lock()
+	cfg, cli := prom, client
+	m.Unlock()
+	if cfg == nil || cfg.URL == "" {
+		return false, fmt.Errorf("no prometheus endpoint configured")
+	}
+	query, err := renderQuery(query, obj)
+	if err != nil {
+		return false, err
+	}
+	res, err := instantQuery(cli, cfg, query, at)
+	if err != nil {
+		return false, err
+	}
+	logger.Log(context.Background(), logging.LevelTrace, "Idle condition", obj.LogAttr(), "query", query, "result", string(res.Data.Result))
+	return holds(res)
+}
+
+// renderQuery will render the given query template for the given object.
+func renderQuery(query string, obj *scanner.Object) (string, error) {
+	tmpl, err := template.New("query").Option("missingkey=zero").Parse(query)
+	if err != nil {
+		return "", fmt.Errorf("invalid idle query; %s", err)
+	}
+	labels := map[string]string{}
+	for k, v := range obj.Labels {
+		labels[k] = v
+	}
+	vars := map[string]interface{}{
+		"namespace": obj.Namespace,
+		"name":      obj.Name,
+		"labels":    labels,
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("invalid idle query; %s", err)
+	}
+	return out.String(), nil
+}
+
+// instantQuery will execute the given query on the Prometheus instant query
+// api for the given time.
+func instantQuery(cli *http.Client, cfg *Prometheus, query string, at time.Time) (*queryResponse, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatInt(at.Unix(), 10))
+	req, err := http.NewRequest("POST", strings.TrimSuffix(cfg.URL, "/")+"/api/v1/query", strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cfg.TokenFile != "" {
+		token, err := ioutil.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	res := &queryResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, fmt.Errorf("invalid prometheus response; status=%s(%d); %s", resp.Status, resp.StatusCode, err)
+	}
+	if res.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed; %s: %s", res.ErrorType, res.Error)
+	}
+	return res, nil
+}
+
+// holds will determine if the condition holds for given query result.
+func holds(res *queryResponse) (bool, error) {
+	switch res.Data.ResultType {
+	case "vector", "matrix":
+		series := []json.RawMessage{}
+		if err := json.Unmarshal(res.Data.Result, &series); err != nil {
+			return false, err
+		}
+		return len(series) > 0, nil
+	case "scalar":
+		sample := []interface{}{}
+		if err := json.Unmarshal(res.Data.Result, &sample); err != nil {
+			return false, err
+		}
+		if len(sample) != 2 {
+			return false, fmt.Errorf("invalid scalar result: %s", res.Data.Result)
+		}
+		val, err := strconv.ParseFloat(fmt.Sprintf("%v", sample[1]), 64)
+		if err != nil {
+			return false, err
+		}
+		return val != 0 && !math.IsNaN(val), nil
+	}
+	return false, fmt.Errorf("unsupported result type '%s'", res.Data.ResultType)
+}
@@ -0,0 +1,113 @@
+package condition
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// fakePrometheus will return a fake Prometheus instant query api, which will
+// return the response configured for the received query.
+func fakePrometheus(t *testing.T, token string, responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"status":"error","errorType":"unauthorized","error":"invalid token"}`)
+			return
+		}
+		if r.FormValue("time") != "1551726000" {
+			t.Errorf("unexpected query time: %s", r.FormValue("time"))
+		}
+		res, ok := responses[r.FormValue("query")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, res)
+	}))
+}
+
+func TestIdle(t *testing.T) {
+	responses := map[string]string{
+		`sum(rate(http_requests_total{namespace="dev",app="frontend"}[2h])) == 0`: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1551726000,"0"]}]}}`,
+		`sum(rate(http_requests_total{namespace="dev",app="backend"}[2h])) == 0`:  `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		`scalar(up) == bool 0`:      `{"status":"success","data":{"resultType":"scalar","result":[1551726000,"1"]}}`,
+		`scalar(down) == bool 0`:    `{"status":"success","data":{"resultType":"scalar","result":[1551726000,"0"]}}`,
+		`"idle"`:                    `{"status":"success","data":{"resultType":"string","result":[1551726000,"idle"]}}`,
+		`http_requests_total[2h]`:   `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1551726000,"1"]]}]}}`,
+		`http_requests_total[2h:1]`: `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+	}
+	srv := fakePrometheus(t, "s3cr3t", responses)
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "nightshift")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	token := filepath.Join(dir, "token")
+	ioutil.WriteFile(token, []byte("s3cr3t\n"), 0600)
+
+	now := time.Unix(1551726000, 0)
+	obj := &scanner.Object{Namespace: "dev", Name: "frontend", Labels: map[string]string{"app": "frontend"}}
+	tests := []struct {
+		query string
+		obj   *scanner.Object
+		idle  bool
+		err   bool
+	}{
+		{
+			query: `sum(rate(http_requests_total{namespace="{{ .namespace }}",app="{{ .labels.app }}"}[2h])) == 0`,
+			obj:   obj,
+			idle:  true,
+		},
+		{
+			query: `sum(rate(http_requests_total{namespace="{{ .namespace }}",app="{{ .labels.app }}"}[2h])) == 0`,
+			obj:   &scanner.Object{Namespace: "dev", Name: "backend", Labels: map[string]string{"app": "backend"}},
+			idle:  false,
+		},
+		{query: `scalar(up) == bool 0`, obj: obj, idle: true},
+		{query: `scalar(down) == bool 0`, obj: obj, idle: false},
+		{query: `http_requests_total[2h]`, obj: obj, idle: true},
+		{query: `http_requests_total[2h:1]`, obj: obj, idle: false},
+		{query: `"idle"`, obj: obj, err: true},
+		{query: `sum(rate(`, obj: obj, err: true},
+		{query: `{{ .namespace`, obj: obj, err: true},
+	}
+
+	if _, err := Idle(tests[0].query, obj, now); err == nil {
+		t.Errorf("expected error when prometheus is not configured")
+	}
+	if err := SetPrometheus(Prometheus{URL: srv.URL, TokenFile: token}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, tst := range tests {
+		idle, err := Idle(tst.query, tst.obj, now)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if idle != tst.idle {
+			t.Errorf("failed test %d - expected idle %t, got %t", i, tst.idle, idle)
+		}
+	}
+
+	SetPrometheus(Prometheus{URL: srv.URL})
+	if _, err := Idle(`scalar(up) == bool 0`, obj, now); err == nil {
+		t.Errorf("expected error without token")
+	}
+}
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net/url"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/resource"
 
+	"github.com/joyrex2001/nightshift/internal/condition"
 	"github.com/joyrex2001/nightshift/internal/schedule"
 )
 
@@ -24,7 +29,22 @@ func New(file string) (*Config, error) {
 		return nil, err
 	}
 	m.processDefaults()
+	if err = m.processClusters(); err != nil {
+		return nil, err
+	}
 	m.processTriggers()
+	if err = m.processHooks(); err != nil {
+		return nil, err
+	}
+	if err = m.processProxies(); err != nil {
+		return nil, err
+	}
+	if err = m.processResources(); err != nil {
+		return nil, err
+	}
+	if err = m.processSavings(); err != nil {
+		return nil, err
+	}
 	return m, nil
 }
 
@@ -60,6 +80,159 @@ func (c *Config) processTriggers() {
 	}
 }
 
+// processHooks will normalize the hook configuration, and will return an
+// error if a hook is invalid.
+func (c *Config) processHooks() error {
+	for _, hook := range c.Hook {
+		hook.Id = strings.ToLower(hook.Id)
+		hook.Action = strings.ToLower(hook.Action)
+		for i := range hook.Trigger {
+			hook.Trigger[i] = strings.ToLower(hook.Trigger[i])
+		}
+		if hook.Id == "" {
+			return fmt.Errorf("hook without id specified")
+		}
+		if hook.Token == "" && hook.TokenFile == "" {
+			return fmt.Errorf("no token or tokenFile specified for hook %s", hook.Id)
+		}
+		switch hook.Action {
+		case "scale", "save", "restore":
+			if len(hook.Scanner) == 0 && len(hook.Namespace) == 0 {
+				return fmt.Errorf("no scanner or namespace specified for hook %s", hook.Id)
+			}
+			if hook.Replicas < 0 {
+				return fmt.Errorf("invalid number of replicas for hook %s: %d", hook.Id, hook.Replicas)
+			}
+		case "trigger":
+			if len(hook.Trigger) == 0 {
+				return fmt.Errorf("no trigger specified for hook %s", hook.Id)
+			}
+		default:
+			return fmt.Errorf("invalid action '%s' for hook %s", hook.Action, hook.Id)
+		}
+	}
+	return nil
+}
+
+// processProxies will normalize the proxy configuration, set the default
+// values, and will return an error if a proxy is invalid.
+func (c *Config) processProxies() error {
+	if c.Proxy == nil {
+		return nil
+	}
+	hosts := map[string]bool{}
+	for _, prxy := range c.Proxy.Route {
+		prxy.Id = strings.ToLower(prxy.Id)
+		if prxy.Id == "" {
+			return fmt.Errorf("proxy route without id specified")
+		}
+		if len(prxy.Host) == 0 {
+			return fmt.Errorf("no host specified for proxy route %s", prxy.Id)
+		}
+		for i := range prxy.Host {
+			prxy.Host[i] = strings.ToLower(prxy.Host[i])
+			if hosts[prxy.Host[i]] {
+				return fmt.Errorf("duplicate host %s for proxy route %s", prxy.Host[i], prxy.Id)
+			}
+			hosts[prxy.Host[i]] = true
+		}
+		if prxy.Namespace == "" {
+			return fmt.Errorf("no namespace specified for proxy route %s", prxy.Id)
+		}
+		u, err := url.Parse(prxy.Target)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid target '%s' for proxy route %s", prxy.Target, prxy.Id)
+		}
+		if prxy.Replicas < 0 {
+			return fmt.Errorf("invalid number of replicas for proxy route %s: %d", prxy.Id, prxy.Replicas)
+		}
+		if prxy.Replicas == 0 {
+			prxy.Replicas = 1
+		}
+		if prxy.Health == "" {
+			prxy.Health = "/"
+		}
+		if prxy.Idle <= 0 {
+			prxy.Idle = 30 * time.Minute
+		}
+		if prxy.Timeout <= 0 {
+			prxy.Timeout = 2 * time.Minute
+		}
+	}
+	return nil
+}
+
+// processClusters will normalize the cluster configuration, and will return
+// an error if a cluster is invalid, or if a scanner refers to an unknown
+// cluster.
+func (c *Config) processClusters() error {
+	names := map[string]bool{}
+	for _, cls := range c.Clusters {
+		cls.Name = strings.ToLower(cls.Name)
+		if cls.Name == "" {
+			return fmt.Errorf("cluster without name specified")
+		}
+		if names[cls.Name] {
+			return fmt.Errorf("duplicate cluster %s", cls.Name)
+		}
+		names[cls.Name] = true
+		if cls.Secret != "" && cls.Kubeconfig != "" {
+			return fmt.Errorf("both secret and kubeconfig specified for cluster %s", cls.Name)
+		}
+		if cls.Secret != "" && len(strings.Split(cls.Secret, "/")) != 2 {
+			return fmt.Errorf("invalid secret '%s' for cluster %s, expected namespace/name", cls.Secret, cls.Name)
+		}
+	}
+	for _, scan := range c.Scanner {
+		scan.Cluster = strings.ToLower(scan.Cluster)
+		if scan.Cluster != "" && !names[scan.Cluster] {
+			return fmt.Errorf("unknown cluster %s specified for scanner", scan.Cluster)
+		}
+	}
+	return nil
+}
+
+// processResources will normalize the resource profiles, and will return an
+// error if a profile is invalid.
+func (c *Config) processResources() error {
+	names := map[string]bool{}
+	for _, res := range c.Resources {
+		res.Name = strings.ToLower(res.Name)
+		if res.Name == "" {
+			return fmt.Errorf("resources profile without name specified")
+		}
+		if res.Name == "restore" {
+			return fmt.Errorf("invalid resources profile name %s", res.Name)
+		}
+		if names[res.Name] {
+			return fmt.Errorf("duplicate resources profile %s", res.Name)
+		}
+		names[res.Name] = true
+		if len(res.Requests) == 0 && len(res.Limits) == 0 {
+			return fmt.Errorf("no requests or limits specified for resources profile %s", res.Name)
+		}
+		for _, qs := range []map[string]string{res.Requests, res.Limits} {
+			for k, v := range qs {
+				if _, err := resource.ParseQuantity(v); err != nil {
+					return fmt.Errorf("invalid quantity '%s' for %s in resources profile %s", v, k, res.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// processSavings will return an error if the configured prices are invalid.
+func (c *Config) processSavings() error {
+	if c.Savings == nil {
+		return nil
+	}
+	if c.Savings.CPU < 0 || c.Savings.Memory < 0 {
+		return fmt.Errorf("invalid savings prices, prices should not be negative")
+	}
+	return nil
+}
+
 // processSchedule will itterate through the config and process all schedule
 // strings and cache these. It will return an error if one or more schedules
 // are invalid.
@@ -126,6 +299,11 @@ func parseSchedule(raw []string) ([]*schedule.Schedule, error) {
 		if err != nil {
 			return nil, err
 		}
+		if s.HasWhen() {
+			if err := condition.CompileWhen(s.GetWhen()); err != nil {
+				return nil, err
+			}
+		}
 		obj = append(obj, s)
 	}
 	return obj, nil
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/kr/pretty"
 )
@@ -29,6 +30,14 @@ func TestNew(t *testing.T) {
 			file: "testdata/invalidschedule2.yaml",
 			err:  true,
 		},
+		{
+			file: "testdata/invalidschedule3.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/invalidschedule4.yaml",
+			err:  true,
+		},
 		{
 			file: "testdata/invalidyaml.yaml",
 			err:  true,
@@ -45,6 +54,46 @@ func TestNew(t *testing.T) {
 			file: "testdata/triggers.yaml",
 			err:  false,
 		},
+		{
+			file: "testdata/hooks.yaml",
+			err:  false,
+		},
+		{
+			file: "testdata/invalidhook.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/proxy.yaml",
+			err:  false,
+		},
+		{
+			file: "testdata/invalidproxy.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/resources.yaml",
+			err:  false,
+		},
+		{
+			file: "testdata/invalidresources.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/clusters.yaml",
+			err:  false,
+		},
+		{
+			file: "testdata/invalidcluster.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/savings.yaml",
+			err:  false,
+		},
+		{
+			file: "testdata/invalidsavings.yaml",
+			err:  true,
+		},
 	}
 	for i, tst := range tests {
 		_, err := New(tst.file)
@@ -285,3 +334,243 @@ func TestLazyGetSchedule(t *testing.T) {
 		t.Errorf("failed lazy processing of schedule; sequential calls produced different lists")
 	}
 }
+
+func TestProcessHooks(t *testing.T) {
+	tests := []struct {
+		in  *Hook
+		out *Hook
+		err bool
+	}{
+		{
+			in:  &Hook{Id: "Wake", Token: "abc", Action: "Restore", Scanner: []string{"dev"}, Trigger: []string{"RefreshDB"}},
+			out: &Hook{Id: "wake", Token: "abc", Action: "restore", Scanner: []string{"dev"}, Trigger: []string{"refreshdb"}},
+			err: false,
+		},
+		{
+			in:  &Hook{Id: "refresh", TokenFile: "/tmp/token", Action: "trigger", Trigger: []string{"refreshdb"}},
+			out: &Hook{Id: "refresh", TokenFile: "/tmp/token", Action: "trigger", Trigger: []string{"refreshdb"}},
+			err: false,
+		},
+		{
+			in:  &Hook{Token: "abc", Action: "save", Namespace: []string{"dev"}},
+			err: true,
+		},
+		{
+			in:  &Hook{Id: "wake", Action: "save", Namespace: []string{"dev"}},
+			err: true,
+		},
+		{
+			in:  &Hook{Id: "wake", Token: "abc", Action: "scale"},
+			err: true,
+		},
+		{
+			in:  &Hook{Id: "wake", Token: "abc", Action: "scale", Namespace: []string{"dev"}, Replicas: -1},
+			err: true,
+		},
+		{
+			in:  &Hook{Id: "wake", Token: "abc", Action: "trigger"},
+			err: true,
+		},
+		{
+			in:  &Hook{Id: "wake", Token: "abc", Action: "wakeup", Namespace: []string{"dev"}},
+			err: true,
+		},
+	}
+	for i, tst := range tests {
+		cfg := &Config{Hook: []*Hook{tst.in}}
+		err := cfg.processHooks()
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if !tst.err && !reflect.DeepEqual(tst.out, tst.in) {
+			t.Errorf("failed test %d - expected: %# v, got %# v", i, pretty.Formatter(tst.out), pretty.Formatter(tst.in))
+		}
+	}
+}
+
+func TestProcessProxies(t *testing.T) {
+	tests := []struct {
+		in  []*Route
+		out []*Route
+		err bool
+	}{
+		{
+			in:  []*Route{{Id: "Dev", Host: []string{"Dev.Example.com"}, Namespace: "dev", Target: "http://app.dev.svc:8080"}},
+			out: []*Route{{Id: "dev", Host: []string{"dev.example.com"}, Namespace: "dev", Target: "http://app.dev.svc:8080", Health: "/", Replicas: 1, Idle: 30 * time.Minute, Timeout: 2 * time.Minute}},
+			err: false,
+		},
+		{
+			in:  []*Route{{Id: "dev", Host: []string{"dev.example.com"}, Namespace: "dev", Selector: "app=dev", Target: "https://app.dev.svc", Health: "/healthz", Replicas: 3, Idle: time.Hour, Timeout: time.Minute, Wait: true}},
+			out: []*Route{{Id: "dev", Host: []string{"dev.example.com"}, Namespace: "dev", Selector: "app=dev", Target: "https://app.dev.svc", Health: "/healthz", Replicas: 3, Idle: time.Hour, Timeout: time.Minute, Wait: true}},
+			err: false,
+		},
+		{
+			in:  []*Route{{Host: []string{"dev.example.com"}, Namespace: "dev", Target: "http://app.dev.svc"}},
+			err: true,
+		},
+		{
+			in:  []*Route{{Id: "dev", Namespace: "dev", Target: "http://app.dev.svc"}},
+			err: true,
+		},
+		{
+			in:  []*Route{{Id: "dev", Host: []string{"dev.example.com"}, Target: "http://app.dev.svc"}},
+			err: true,
+		},
+		{
+			in:  []*Route{{Id: "dev", Host: []string{"dev.example.com"}, Namespace: "dev", Target: "app.dev.svc"}},
+			err: true,
+		},
+		{
+			in:  []*Route{{Id: "dev", Host: []string{"dev.example.com"}, Namespace: "dev", Target: "http://app.dev.svc", Replicas: -1}},
+			err: true,
+		},
+		{
+			in: []*Route{
+				{Id: "dev", Host: []string{"dev.example.com"}, Namespace: "dev", Target: "http://app.dev.svc"},
+				{Id: "test", Host: []string{"Dev.example.com"}, Namespace: "test", Target: "http://app.test.svc"},
+			},
+			err: true,
+		},
+	}
+	for i, tst := range tests {
+		cfg := &Config{Proxy: &Proxy{Route: tst.in}}
+		err := cfg.processProxies()
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if !tst.err && !reflect.DeepEqual(tst.out, tst.in) {
+			t.Errorf("failed test %d - expected: %# v, got %# v", i, pretty.Formatter(tst.out), pretty.Formatter(tst.in))
+		}
+	}
+}
+
+func TestProcessResources(t *testing.T) {
+	tests := []struct {
+		in  []*Resources
+		out []*Resources
+		err bool
+	}{
+		{
+			in:  []*Resources{{Name: "Small", Requests: map[string]string{"cpu": "50m"}, Limits: map[string]string{"memory": "256Mi"}}},
+			out: []*Resources{{Name: "small", Requests: map[string]string{"cpu": "50m"}, Limits: map[string]string{"memory": "256Mi"}}},
+			err: false,
+		},
+		{
+			in:  []*Resources{{Requests: map[string]string{"cpu": "50m"}}},
+			err: true,
+		},
+		{
+			in:  []*Resources{{Name: "restore", Requests: map[string]string{"cpu": "50m"}}},
+			err: true,
+		},
+		{
+			in:  []*Resources{{Name: "small"}},
+			err: true,
+		},
+		{
+			in:  []*Resources{{Name: "small", Limits: map[string]string{"cpu": "a lot"}}},
+			err: true,
+		},
+		{
+			in: []*Resources{
+				{Name: "small", Requests: map[string]string{"cpu": "50m"}},
+				{Name: "Small", Requests: map[string]string{"cpu": "100m"}},
+			},
+			err: true,
+		},
+	}
+	for i, tst := range tests {
+		cfg := &Config{Resources: tst.in}
+		err := cfg.processResources()
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if !tst.err && !reflect.DeepEqual(tst.out, tst.in) {
+			t.Errorf("failed test %d - expected: %# v, got %# v", i, pretty.Formatter(tst.out), pretty.Formatter(tst.in))
+		}
+	}
+}
+
+func TestProcessClusters(t *testing.T) {
+	tests := []struct {
+		in      []*Cluster
+		out     []*Cluster
+		scanner []*Scanner
+		err     bool
+	}{
+		{
+			in:      []*Cluster{{Name: "Dev", Context: "dev"}, {Name: "test", Secret: "nightshift/test"}},
+			out:     []*Cluster{{Name: "dev", Context: "dev"}, {Name: "test", Secret: "nightshift/test"}},
+			scanner: []*Scanner{{Cluster: "DEV"}, {}},
+			err:     false,
+		},
+		{
+			in:  []*Cluster{{Context: "dev"}},
+			err: true,
+		},
+		{
+			in:  []*Cluster{{Name: "dev"}, {Name: "Dev"}},
+			err: true,
+		},
+		{
+			in:  []*Cluster{{Name: "dev", Secret: "nightshift/dev", Kubeconfig: "/kubeconfig"}},
+			err: true,
+		},
+		{
+			in:  []*Cluster{{Name: "dev", Secret: "dev"}},
+			err: true,
+		},
+		{
+			in:      []*Cluster{{Name: "dev"}},
+			scanner: []*Scanner{{Cluster: "test"}},
+			err:     true,
+		},
+	}
+	for i, tst := range tests {
+		cfg := &Config{Clusters: tst.in, Scanner: tst.scanner}
+		err := cfg.processClusters()
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if !tst.err && !reflect.DeepEqual(tst.out, tst.in) {
+			t.Errorf("failed test %d - expected: %# v, got %# v", i, pretty.Formatter(tst.out), pretty.Formatter(tst.in))
+		}
+		if !tst.err && len(tst.scanner) > 0 && tst.scanner[0].Cluster != "dev" {
+			t.Errorf("failed test %d - expected cluster dev, got %s", i, tst.scanner[0].Cluster)
+		}
+	}
+}
+
+func TestProcessSavings(t *testing.T) {
+	tests := []struct {
+		in  *Savings
+		err bool
+	}{
+		{in: nil, err: false},
+		{in: &Savings{CPU: 0.03, Memory: 0.004, Currency: "EUR"}, err: false},
+		{in: &Savings{CPU: -1}, err: true},
+		{in: &Savings{Memory: -0.1}, err: true},
+	}
+	for i, tst := range tests {
+		cfg := &Config{Savings: tst.in}
+		err := cfg.processSavings()
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+	}
+}
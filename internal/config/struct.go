@@ -1,13 +1,20 @@
 package config
 
 import (
+	"time"
+
 	"github.com/joyrex2001/nightshift/internal/schedule"
 )
 
 // Config is reflection of the yaml root configuration entrypoint.
 type Config struct {
-	Trigger []*Trigger `yaml:"trigger"`
-	Scanner []*Scanner `yaml:"scanner"`
+	Trigger   []*Trigger   `yaml:"trigger"`
+	Scanner   []*Scanner   `yaml:"scanner"`
+	Hook      []*Hook      `yaml:"hook"`
+	Proxy     *Proxy       `yaml:"proxy"`
+	Resources []*Resources `yaml:"resources"`
+	Clusters  []*Cluster   `yaml:"clusters"`
+	Savings   *Savings     `yaml:"savings"`
 }
 
 // Scanner is reflection of the yaml configuration file's section "scanner".
@@ -16,6 +23,7 @@ type Scanner struct {
 	Default    *Default      `yaml:"default"`
 	Deployment []*Deployment `yaml:"deployment"`
 	Type       string        `yaml:"type"`
+	Cluster    string        `yaml:"cluster"`
 }
 
 // Trigger is reflection of the yaml configuration file's section "trigger".
@@ -25,6 +33,55 @@ type Trigger struct {
 	Config map[string]string `yaml:"config"`
 }
 
+// Hook is reflection of the yaml configuration file's section "hook".
+type Hook struct {
+	Id        string   `yaml:"id"`
+	Token     string   `yaml:"token"`
+	TokenFile string   `yaml:"tokenFile"`
+	Action    string   `yaml:"action"`
+	Scanner   []string `yaml:"scanner"`
+	Namespace []string `yaml:"namespace"`
+	Replicas  int      `yaml:"replicas"`
+	Trigger   []string `yaml:"trigger"`
+}
+
+// Proxy is reflection of the yaml configuration file's section "proxy".
+type Proxy struct {
+	Route []*Route `yaml:"route"`
+}
+
+// Route is reflection of the yaml configuration file's section "route" in
+// section "proxy".
+type Route struct {
+	Id        string        `yaml:"id"`
+	Host      []string      `yaml:"host"`
+	Namespace string        `yaml:"namespace"`
+	Selector  string        `yaml:"selector"`
+	Target    string        `yaml:"target"`
+	Health    string        `yaml:"health"`
+	Replicas  int           `yaml:"replicas"`
+	Idle      time.Duration `yaml:"idle"`
+	Timeout   time.Duration `yaml:"timeout"`
+	Wait      bool          `yaml:"wait"`
+}
+
+// Cluster is reflection of the yaml configuration file's section "clusters".
+type Cluster struct {
+	Name       string `yaml:"name"`
+	Kubeconfig string `yaml:"kubeconfig"`
+	Context    string `yaml:"context"`
+	Secret     string `yaml:"secret"`
+}
+
+// Resources is reflection of the yaml configuration file's section
+// "resources".
+type Resources struct {
+	Name      string            `yaml:"name"`
+	Container []string          `yaml:"container"`
+	Requests  map[string]string `yaml:"requests"`
+	Limits    map[string]string `yaml:"limits"`
+}
+
 // Default is reflection of the yaml configuration file's section "default".
 type Default struct {
 	Id       string   `yaml:"id"`
@@ -42,3 +99,11 @@ type Deployment struct {
 	schedule []*schedule.Schedule
 	parsed   bool
 }
+
+// Savings is reflection of the yaml configuration file's section "savings".
+// The prices are per cpu core hour, and per memory GB hour.
+type Savings struct {
+	CPU      float64 `yaml:"cpu"`
+	Memory   float64 `yaml:"memory"`
+	Currency string  `yaml:"currency"`
+}
@@ -0,0 +1,304 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Subsystems of which the level can be configured.
+const (
+	Main    = "main"
+	Agent   = "agent"
+	Scanner = "scanner"
+	Trigger = "trigger"
+	WebUI   = "webui"
+	Proxy   = "proxy"
+)
+
+// Output formats.
+const (
+	FormatGlog = "glog"
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// LevelTrace is the level of detailed debug messages.
+const LevelTrace = slog.LevelDebug - 4
+
+// Keys of the fields that are added to structured log messages.
+const (
+	KeySubsystem = "subsystem"
+	KeyNamespace = "namespace"
+	KeyObject    = "object"
+	KeyType      = "type"
+	KeyScanner   = "scanner_id"
+	KeyCluster   = "cluster"
+	KeyTrigger   = "trigger_id"
+	KeyEventTime = "event_time"
+	KeyError     = "error"
+)
+
+// Config describes the output format, the default level, and the level of
+// each subsystem. If the default level is nil, it is derived from the glog
+// verbosity.
+type Config struct {
+	Format string
+	Level  *slog.Level
+	Levels map[string]slog.Level
+}
+
+var (
+	m      sync.RWMutex
+	config = Config{Format: FormatGlog}
+	output slog.Handler
+	writer io.Writer = os.Stderr
+)
+
+// Configure will apply the given logging configuration.
+func Configure(cfg Config) error {
+	var out slog.Handler
+	opts := &slog.HandlerOptions{Level: slog.Level(-100), ReplaceAttr: replaceLevel}
+	switch strings.ToLower(cfg.Format) {
+	case "", FormatGlog:
+		cfg.Format = FormatGlog
+	case FormatText:
+		out = slog.NewTextHandler(writer, opts)
+	case FormatJSON:
+		out = slog.NewJSONHandler(writer, opts)
+	default:
+		return fmt.Errorf("invalid log format '%s', expected glog, text or json", cfg.Format)
+	}
+	m.Lock()
+	defer m.Unlock()
+	config = cfg
+	output = out
+	return nil
+}
+
+// ParseLevel will parse the given level name (trace, debug, info, warn or
+// error).
+func ParseLevel(name string) (slog.Level, error) {
+	if strings.EqualFold(strings.TrimSpace(name), "trace") {
+		return LevelTrace, nil
+	}
+	var lvl slog.Level
+	err := lvl.UnmarshalText([]byte(strings.TrimSpace(name)))
+	return lvl, err
+}
+
+// ParseLevels will parse the given comma separated list of subsystem=level
+// pairs (e.g. agent=debug,webui=warn).
+func ParseLevels(levels string) (map[string]slog.Level, error) {
+	res := map[string]slog.Level{}
+	for _, pair := range strings.Split(levels, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid log level '%s', expected subsystem=level", pair)
+		}
+		lvl, err := ParseLevel(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		res[strings.ToLower(strings.TrimSpace(kv[0]))] = lvl
+	}
+	return res, nil
+}
+
+// For will return the logger of given subsystem.
+func For(subsystem string) *slog.Logger {
+	return slog.New(&handler{subsystem: subsystem})
+}
+
+// Object will return the fields that identify an object.
+func Object(namespace, name, typ, scanner, cluster string) slog.Attr {
+	attrs := []any{KeyNamespace, namespace, KeyObject, name, KeyType, typ}
+	if scanner != "" {
+		attrs = append(attrs, KeyScanner, scanner)
+	}
+	if cluster != "" {
+		attrs = append(attrs, KeyCluster, cluster)
+	}
+	return slog.Group("", attrs...)
+}
+
+// ScannerConfig will return the fields that identify a scanner.
+func ScannerConfig(namespace, typ, id, cluster string) slog.Attr {
+	attrs := []any{KeyNamespace, namespace, KeyType, typ}
+	if id != "" {
+		attrs = append(attrs, KeyScanner, id)
+	}
+	if cluster != "" {
+		attrs = append(attrs, KeyCluster, cluster)
+	}
+	return slog.Group("", attrs...)
+}
+
+// TriggerId will return the field that identifies a trigger.
+func TriggerId(id string) slog.Attr {
+	return slog.String(KeyTrigger, id)
+}
+
+// EventTime will return the field with the time of a scale event.
+func EventTime(t time.Time) slog.Attr {
+	return slog.Time(KeyEventTime, t)
+}
+
+// Err will return the field with given error.
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// level will return the level of given subsystem.
+func level(subsystem string) slog.Level {
+	m.RLock()
+	defer m.RUnlock()
+	if lvl, ok := config.Levels[subsystem]; ok {
+		return lvl
+	}
+	if config.Level != nil {
+		return *config.Level
+	}
+	switch {
+	case bool(glog.V(5)):
+		return LevelTrace
+	case bool(glog.V(4)):
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// replaceLevel will name the trace level in the output.
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if lvl, ok := a.Value.Any().(slog.Level); ok && lvl <= LevelTrace {
+			return slog.String(slog.LevelKey, "TRACE")
+		}
+	}
+	return a
+}
+
+// handler is the slog handler of a subsystem. It will filter the messages on
+// the level of the subsystem, and will write them to glog, or to the
+// configured structured output.
+type handler struct {
+	subsystem string
+	attrs     []slog.Attr
+	prefix    string
+}
+
+// Enabled will check if messages of given level are logged for the subsystem.
+func (h *handler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return lvl >= level(h.subsystem)
+}
+
+// WithAttrs will return a handler that adds given fields to each message.
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	new := *h
+	new.attrs = append(append([]slog.Attr{}, h.attrs...), h.prefixed(attrs)...)
+	return &new
+}
+
+// WithGroup will return a handler that prefixes the keys of the fields of
+// each message with given name.
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	new := *h
+	new.prefix = h.prefix + name + "."
+	return &new
+}
+
+// prefixed will return given fields, with the keys prefixed with the group
+// names of this handler.
+func (h *handler) prefixed(attrs []slog.Attr) []slog.Attr {
+	if h.prefix == "" {
+		return attrs
+	}
+	res := []slog.Attr{}
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Key == "" && a.Value.Kind() == slog.KindGroup {
+			res = append(res, h.prefixed(a.Value.Group())...)
+			continue
+		}
+		res = append(res, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return res
+}
+
+// Handle will write the given message.
+func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
+	attrs := append([]slog.Attr{slog.String(KeySubsystem, h.subsystem)}, h.attrs...)
+	rattrs := []slog.Attr{}
+	rec.Attrs(func(a slog.Attr) bool {
+		rattrs = append(rattrs, a)
+		return true
+	})
+	attrs = append(attrs, h.prefixed(rattrs)...)
+	m.RLock()
+	out := output
+	m.RUnlock()
+	if out == nil {
+		// the depth skips Handle, and the slog.Logger methods
+		logGlog(3, rec.Level, rec.Message, attrs)
+		return nil
+	}
+	new := slog.NewRecord(rec.Time, rec.Level, rec.Message, rec.PC)
+	new.AddAttrs(attrs...)
+	return out.Handle(ctx, new)
+}
+
+// logGlog will write the given message with glog, with the fields appended
+// to the message as key=value pairs.
+func logGlog(depth int, lvl slog.Level, msg string, attrs []slog.Attr) {
+	var b strings.Builder
+	b.WriteString(msg)
+	writeAttrs(&b, "", attrs)
+	switch {
+	case lvl >= slog.LevelError:
+		glog.ErrorDepth(depth+1, b.String())
+	case lvl >= slog.LevelWarn:
+		glog.WarningDepth(depth+1, b.String())
+	default:
+		glog.InfoDepth(depth+1, b.String())
+	}
+}
+
+// writeAttrs will write the given fields as key=value pairs. The subsystem is
+// omitted, to keep the glog output as it was.
+func writeAttrs(b *strings.Builder, prefix string, attrs []slog.Attr) {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Key == KeySubsystem && prefix == "" {
+			continue
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			p := prefix
+			if a.Key != "" {
+				p = prefix + a.Key + "."
+			}
+			writeAttrs(b, p, a.Value.Group())
+			continue
+		}
+		val := a.Value.String()
+		if a.Value.Kind() == slog.KindTime {
+			val = a.Value.Time().Format(time.RFC3339)
+		}
+		if strings.ContainsAny(val, " \"=") {
+			val = fmt.Sprintf("%q", val)
+		}
+		fmt.Fprintf(b, " %s%s=%s", prefix, a.Key, val)
+	}
+}
@@ -0,0 +1,142 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		in  string
+		out map[string]slog.Level
+		err bool
+	}{
+		{in: "", out: map[string]slog.Level{}},
+		{in: "agent=debug", out: map[string]slog.Level{"agent": slog.LevelDebug}},
+		{in: "Agent=trace, webui=WARN", out: map[string]slog.Level{"agent": LevelTrace, "webui": slog.LevelWarn}},
+		{in: "agent", err: true},
+		{in: "agent=verbose", err: true},
+	}
+	for i, tst := range tests {
+		out, err := ParseLevels(tst.in)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err != nil {
+			continue
+		}
+		if len(out) != len(tst.out) {
+			t.Errorf("failed test %d - expected %v, got %v", i, tst.out, out)
+		}
+		for k, v := range tst.out {
+			if out[k] != v {
+				t.Errorf("failed test %d - expected %v, got %v", i, tst.out, out)
+			}
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(Config{})
+	for i, tst := range []struct {
+		format string
+		err    bool
+	}{
+		{format: "", err: false},
+		{format: "glog", err: false},
+		{format: "JSON", err: false},
+		{format: "text", err: false},
+		{format: "xml", err: true},
+	} {
+		err := Configure(Config{Format: tst.format})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer = buf
+	info := slog.LevelInfo
+	if err := Configure(Config{
+		Format: FormatJSON,
+		Level:  &info,
+		Levels: map[string]slog.Level{Agent: slog.LevelDebug, WebUI: slog.LevelWarn},
+	}); err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	defer Configure(Config{})
+
+	at := time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)
+	For(Agent).Debug("Scale event",
+		Object("test", "app", "deployment", "test-default", ""),
+		EventTime(at),
+		TriggerId("webhook"),
+		Err(errors.New("oops")),
+	)
+	For(Agent).Log(context.Background(), LevelTrace, "Details")
+	For(WebUI).Info("Request")
+	For(Scanner).With("name", "value").WithGroup("grp").Info("Grouped", "key", 1)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("failed test - expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	msg := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[0]), &msg); err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	for k, v := range map[string]interface{}{
+		"level":      "DEBUG",
+		"msg":        "Scale event",
+		"subsystem":  "agent",
+		"namespace":  "test",
+		"object":     "app",
+		"type":       "deployment",
+		"scanner_id": "test-default",
+		"trigger_id": "webhook",
+		"event_time": "2019-03-04T18:00:00Z",
+		"error":      "oops",
+	} {
+		if msg[k] != v {
+			t.Errorf("failed test - expected %s=%v, got %v", k, v, msg[k])
+		}
+	}
+	if _, ok := msg["cluster"]; ok {
+		t.Errorf("failed test - expected no cluster field, got %v", msg["cluster"])
+	}
+	msg = map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[1]), &msg); err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	if msg["subsystem"] != "scanner" || msg["name"] != "value" || msg["grp.key"] != float64(1) {
+		t.Errorf("failed test - unexpected message: %s", lines[1])
+	}
+}
+
+func TestWriteAttrs(t *testing.T) {
+	b := &strings.Builder{}
+	writeAttrs(b, "", []slog.Attr{
+		slog.String(KeySubsystem, Agent),
+		Object("test", "app", "deployment", "", ""),
+		slog.String("schedule", "Mon-Fri 18:00 replicas=0"),
+		EventTime(time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)),
+	})
+	out := ` namespace=test object=app type=deployment schedule="Mon-Fri 18:00 replicas=0" event_time=2019-03-04T18:00:00Z`
+	if b.String() != out {
+		t.Errorf("failed test - expected: %s, got %s", out, b.String())
+	}
+}
@@ -1,41 +1,136 @@
 package internal
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/joyrex2001/nightshift/internal/agent"
+	"github.com/joyrex2001/nightshift/internal/condition"
 	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/proxy"
+	"github.com/joyrex2001/nightshift/internal/savings"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/schedule"
+	"github.com/joyrex2001/nightshift/internal/tracing"
 	"github.com/joyrex2001/nightshift/internal/trigger"
 	"github.com/joyrex2001/nightshift/internal/webui"
+	"github.com/joyrex2001/nightshift/internal/webui/backend"
 )
 
+var logger = logging.For(logging.Main)
+
+// tracingShutdownTimeout is the maximum duration to export the pending traces
+// when shutting down.
+const tracingShutdownTimeout = 5 * time.Second
+
 // Main is the main entry point of this service and will start the party and
 // rock the boat.
 func Main(cmd *cobra.Command, args []string) {
 	// generic initialization
+	setLogging()
+	if viper.ConfigFileUsed() != "" {
+		logger.Info("Using config", "file", viper.ConfigFileUsed())
+	}
 	tz := viper.GetString("generic.timezone")
 	if err := schedule.SetTimeZone(tz); err != nil {
-		glog.Errorf("Invalid timezone specified: %s", err)
+		logger.Error("Invalid timezone specified", logging.Err(err))
 	} else {
-		glog.Infof("Using timezone: %s", tz)
+		logger.Info("Using timezone", "timezone", tz)
 	}
+	setPrometheus()
+	setTracing()
+	defer shutdownTracing()
 	// start subsystems
-	startAgent()
-	startWebUI()
+	cfg := loadConfig()
+	startAgent(cfg)
+	startWebUI(cfg)
+	startProxy(cfg)
 	forever()
 }
 
+// setLogging will configure the format of the log messages, and the level of
+// each subsystem. If no level is configured, the level is derived from the
+// glog verbosity.
+func setLogging() {
+	cfg := logging.Config{Format: viper.GetString("logging.format")}
+	if lvl := viper.GetString("logging.default-level"); lvl != "" {
+		level, err := logging.ParseLevel(lvl)
+		if err != nil {
+			logger.Error("Invalid log level specified", logging.Err(err))
+		} else {
+			cfg.Level = &level
+		}
+	}
+	levels, err := logging.ParseLevels(viper.GetString("logging.levels"))
+	if err != nil {
+		logger.Error("Invalid log levels specified", logging.Err(err))
+	}
+	cfg.Levels = levels
+	if err := logging.Configure(cfg); err != nil {
+		logger.Error("Invalid logging configuration", logging.Err(err))
+	}
+}
+
+// setPrometheus will configure the prometheus endpoint that is used to
+// evaluate idle conditions in schedules.
+func setPrometheus() {
+	url := viper.GetString("prometheus.url")
+	if url == "" {
+		return
+	}
+	err := condition.SetPrometheus(condition.Prometheus{
+		URL:       url,
+		TokenFile: viper.GetString("prometheus.token-file"),
+		CAFile:    viper.GetString("prometheus.ca-file"),
+		Timeout:   viper.GetDuration("prometheus.timeout"),
+	})
+	if err != nil {
+		logger.Error("Invalid prometheus configuration", logging.Err(err))
+	} else {
+		logger.Info("Using prometheus", "url", url)
+	}
+}
+
+// setTracing will configure the OTLP collector to which the traces of the
+// scale cycles and trigger executions are exported.
+func setTracing() {
+	endpoint := viper.GetString("tracing.otlp-endpoint")
+	if endpoint == "" {
+		return
+	}
+	if err := tracing.SetExporter(endpoint, config.Version); err != nil {
+		logger.Error("Invalid tracing configuration", logging.Err(err))
+	} else {
+		logger.Info("Exporting traces", "endpoint", endpoint)
+	}
+}
+
+// shutdownTracing will export the pending traces, and will stop exporting.
+func shutdownTracing() {
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	if err := tracing.Shutdown(ctx); err != nil {
+		logger.Error("Error exporting traces", logging.Err(err))
+	}
+}
+
 // startAgent will start the agent that will monitor and scale the openshift
 // resources according to the schedules.
-func startAgent() {
+func startAgent(cfg *config.Config) {
 	agt := agent.New()
-	if cfg := loadConfig(); cfg != nil {
+	if cfg != nil {
+		addClusters(cfg)
+		addProfiles(cfg)
+		setPrices(cfg)
 		addScanners(agt, cfg)
 		addTriggers(agt, cfg)
 	}
@@ -49,7 +144,7 @@ func loadConfig() *config.Config {
 	if viper.ConfigFileUsed() != "" {
 		cfg, err := config.New(viper.ConfigFileUsed())
 		if err != nil {
-			glog.Errorf("Error parsing config: %s", err)
+			logger.Error("Error parsing config", "file", viper.ConfigFileUsed(), logging.Err(err))
 			return nil
 		}
 		return cfg
@@ -63,7 +158,7 @@ func addScanners(agent agent.Agent, cfg *config.Config) {
 	// go through configured scanners
 	prio := 0
 	for _, scan := range cfg.Scanner {
-		glog.V(5).Infof("Adding scanner: %v", scan)
+		logger.Log(context.Background(), logging.LevelTrace, "Adding scanner", "scanner", scan)
 		id := scan.Default.GetId()
 		def, _ := scan.Default.GetSchedule()
 		// add namespace scanner
@@ -74,6 +169,7 @@ func addScanners(agent agent.Agent, cfg *config.Config) {
 				Namespace: ns,
 				Schedule:  def,
 				Priority:  prio,
+				Cluster:   scan.Cluster,
 			})
 			prio++
 		}
@@ -89,6 +185,7 @@ func addScanners(agent agent.Agent, cfg *config.Config) {
 						Schedule:  sched,
 						Label:     sel,
 						Priority:  prio,
+						Cluster:   scan.Cluster,
 					})
 					prio++
 				}
@@ -102,18 +199,66 @@ func addScanners(agent agent.Agent, cfg *config.Config) {
 func addScanner(agent agent.Agent, cfg scanner.Config) {
 	scanr, err := scanner.NewForConfig(cfg)
 	if err != nil {
-		glog.Errorf("Error adding scanners: %s", err)
+		logger.Error("Error adding scanner", cfg.LogAttr(), logging.Err(err))
 		return
 	}
 	agent.AddScanner(scanr)
 }
 
+// addClusters will add the configured clusters, which can be referred to by
+// the scanners.
+func addClusters(cfg *config.Config) {
+	for _, def := range cfg.Clusters {
+		scanner.AddCluster(scanner.Cluster{
+			Name:       def.Name,
+			Kubeconfig: def.Kubeconfig,
+			Context:    def.Context,
+			Secret:     def.Secret,
+		})
+	}
+}
+
+// addProfiles will add the configured resource profiles, which can be applied
+// by the resources setting in schedules.
+func addProfiles(cfg *config.Config) {
+	for _, def := range cfg.Resources {
+		profile := &scanner.Profile{
+			Name:       def.Name,
+			Containers: def.Container,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{},
+				Limits:   corev1.ResourceList{},
+			},
+		}
+		for k, v := range def.Requests {
+			profile.Resources.Requests[corev1.ResourceName(k)] = resource.MustParse(v)
+		}
+		for k, v := range def.Limits {
+			profile.Resources.Limits[corev1.ResourceName(k)] = resource.MustParse(v)
+		}
+		scanner.AddProfile(profile)
+	}
+}
+
+// setPrices will configure the prices that are used to report the cost that
+// has been saved.
+func setPrices(cfg *config.Config) {
+	if cfg.Savings == nil {
+		return
+	}
+	savings.SetPrices(savings.Prices{
+		CPU:      cfg.Savings.CPU,
+		Memory:   cfg.Savings.Memory,
+		Currency: cfg.Savings.Currency,
+	})
+}
+
 // addTriggers will add configured triggers to the provided agent.
 func addTriggers(agent agent.Agent, cfg *config.Config) {
 	for _, def := range cfg.Trigger {
 		trgr, err := trigger.New(def.Type)
 		if err != nil {
-			glog.Errorf("Error adding trigger: %s", err)
+			logger.Error("Error adding trigger", logging.TriggerId(def.Id), logging.Err(err))
 		} else {
 			trgr.SetConfig(trigger.Config{Id: def.Id, Type: def.Type, Settings: def.Config})
 			agent.AddTrigger(def.Id, trgr)
@@ -122,7 +267,7 @@ func addTriggers(agent agent.Agent, cfg *config.Config) {
 }
 
 // startWebUI will start the management webserver.
-func startWebUI() {
+func startWebUI(cfg *config.Config) {
 	enabled := viper.GetBool("web.enable")
 	if enabled {
 		webui := webui.New()
@@ -130,13 +275,40 @@ func startWebUI() {
 		webui.Cert = viper.GetString("web.cert-file")
 		webui.Key = viper.GetString("web.key-file")
 		webui.TLS = viper.GetBool("web.enable-tls")
+		webui.Auth = backend.AuthConfig{
+			TokenFile:         viper.GetString("web.auth.token-file"),
+			TokenReview:       viper.GetBool("web.auth.token-review"),
+			OIDCIssuerURL:     viper.GetString("web.auth.oidc-issuer-url"),
+			OIDCClientID:      viper.GetString("web.auth.oidc-client-id"),
+			OIDCUsernameClaim: viper.GetString("web.auth.oidc-username-claim"),
+			OIDCGroupsClaim:   viper.GetString("web.auth.oidc-groups-claim"),
+			Authorize:         viper.GetBool("web.auth.authorize"),
+		}
+		if cfg != nil {
+			webui.Hooks = cfg.Hook
+		}
 		webui.Start()
 	}
 }
 
-// forever will wait foreva, foreva eva...
-func forever() {
-	for {
-		time.Sleep(time.Second)
+// startProxy will start the wake-on-request proxy.
+func startProxy(cfg *config.Config) {
+	enabled := viper.GetBool("proxy.enable")
+	if enabled {
+		prxy := proxy.New()
+		prxy.Addr = viper.GetString("proxy.listen-addr")
+		if cfg != nil && cfg.Proxy != nil {
+			prxy.Routes = cfg.Proxy.Route
+		}
+		prxy.Start()
 	}
 }
+
+// forever will wait foreva, foreva eva... or until the process is
+// interrupted or terminated.
+func forever() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	logger.Info("Shutting down...")
+}
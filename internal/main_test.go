@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/joyrex2001/nightshift/internal/agent"
 	"github.com/joyrex2001/nightshift/internal/config"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/trigger"
@@ -56,14 +57,30 @@ func (a *mockAgent) GetTriggers() map[string]trigger.Trigger {
 	return res
 }
 
+func (a *mockAgent) GetTriggerHistory() []trigger.Execution {
+	return []trigger.Execution{}
+}
+
+func (a *mockAgent) QueueTrigger(id string, evt trigger.Event) error {
+	return nil
+}
+
+func (a *mockAgent) GetTimeline(from, to time.Time) []agent.Timeline {
+	return []agent.Timeline{}
+}
+
+func (a *mockAgent) Subscribe() (<-chan agent.Activity, func()) {
+	return make(chan agent.Activity), func() {}
+}
+
 type mockTrigger struct {
 	id  string
 	cfg trigger.Config
 }
 
-func (m *mockTrigger) SetConfig(c trigger.Config)      { m.cfg = c }
-func (m *mockTrigger) GetConfig() trigger.Config       { return m.cfg }
-func (m *mockTrigger) Execute([]*scanner.Object) error { return nil }
+func (m *mockTrigger) SetConfig(c trigger.Config)                    { m.cfg = c }
+func (m *mockTrigger) GetConfig() trigger.Config                     { return m.cfg }
+func (m *mockTrigger) Execute(trigger.Event) (trigger.Result, error) { return nil, nil }
 
 func getTriggerFactory(typ string, m *mockTrigger) trigger.Factory {
 	return func() (trigger.Trigger, error) {
@@ -76,12 +93,13 @@ type mockScanner struct {
 	cfg scanner.Config
 }
 
-func (m *mockScanner) SetConfig(c scanner.Config)                        { m.cfg = c }
-func (m *mockScanner) GetConfig() scanner.Config                         { return m.cfg }
-func (m *mockScanner) GetObjects() ([]*scanner.Object, error)            { return []*scanner.Object{}, nil }
-func (m *mockScanner) GetState(obj *scanner.Object) (int, error)         { return 0, nil }
-func (m *mockScanner) Scale(obj *scanner.Object, s *int, r int) error    { return nil }
-func (m *mockScanner) Watch(_stop chan bool) (chan scanner.Event, error) { return nil, nil }
+func (m *mockScanner) SetConfig(c scanner.Config)                               { m.cfg = c }
+func (m *mockScanner) GetConfig() scanner.Config                                { return m.cfg }
+func (m *mockScanner) GetObjects() ([]*scanner.Object, error)                   { return []*scanner.Object{}, nil }
+func (m *mockScanner) GetState(obj *scanner.Object) (int, error)                { return 0, nil }
+func (m *mockScanner) Scale(*scanner.Object, *int, int, *scanner.Profile) error { return nil }
+func (m *mockScanner) Watch(_stop chan bool) (chan scanner.Event, error)        { return nil, nil }
+func (m *mockScanner) Annotate(*scanner.Object, map[string]string) error        { return nil }
 
 func getScannerFactory(typ string, m *mockScanner) scanner.Factory {
 	return func() (scanner.Scanner, error) {
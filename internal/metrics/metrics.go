@@ -1,11 +1,33 @@
 package metrics
 
 import (
+	"sync"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 const metricsPrefix = "nightshift_"
 
+// Results that are used as result label.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// Object identifies the object a per object metric applies to.
+type Object struct {
+	UID       string
+	Namespace string
+	Name      string
+	Type      string
+	Scanner   string
+	Cluster   string
+}
+
+// objectLabels are the labels of the per object metrics.
+var objectLabels = []string{"namespace", "object", "type", "scanner", "cluster"}
+
 var (
 	counters = map[string]*struct {
 		Name string
@@ -30,6 +52,54 @@ var (
 		"manual_restore_error": {
 			Help: "The total number of errors while manual restoring",
 		},
+		"manual_save": {
+			Help: "The total number of processed manual save events",
+		},
+		"manual_save_error": {
+			Help: "The total number of errors while manual saving",
+		},
+		"manual_snooze": {
+			Help: "The total number of processed manual snooze events",
+		},
+		"manual_snooze_error": {
+			Help: "The total number of errors while manual snoozing",
+		},
+		"manual_schedule": {
+			Help: "The total number of processed manual schedule changes",
+		},
+		"manual_schedule_error": {
+			Help: "The total number of errors while manual changing schedules",
+		},
+		"hook": {
+			Help: "The total number of processed inbound hooks",
+		},
+		"hook_error": {
+			Help: "The total number of errors while processing inbound hooks",
+		},
+		"proxy_wake": {
+			Help: "The total number of objects woken up by the proxy",
+		},
+		"proxy_wake_error": {
+			Help: "The total number of errors while waking up objects by the proxy",
+		},
+		"proxy_sleep": {
+			Help: "The total number of idle objects put back to sleep by the proxy",
+		},
+		"proxy_sleep_error": {
+			Help: "The total number of errors while putting objects back to sleep by the proxy",
+		},
+		"trigger": {
+			Help: "The total number of executed triggers",
+		},
+		"trigger_error": {
+			Help: "The total number of errors while executing triggers",
+		},
+		"trigger_queue_full": {
+			Help: "The total number of triggers that were rejected because the trigger queue was full",
+		},
+		"condition_error": {
+			Help: "The total number of errors while evaluating schedule conditions",
+		},
 		"resync_error": {
 			Help: "The total number errors while resyncing objects",
 		},
@@ -48,6 +118,111 @@ var (
 		},
 		[]string{"target", "scanner"},
 	)
+	// custom metric for exporting current number of replicas per cluster
+	clusterReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsPrefix + "cluster_replicas",
+			Help: "Current expected number of nightshift scaled replicas per cluster",
+		},
+		[]string{"target", "scanner", "cluster"},
+	)
+	// custom metric for exporting the exit status of exec triggers
+	exitStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsPrefix + "trigger_exit_status",
+			Help: "Exit status of the last execution of an exec trigger",
+		},
+		[]string{"trigger"},
+	)
+	// custom metrics for exporting the resources saved by scaling down
+	savedCPU = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsPrefix + "saved_cpu_core_hours_total",
+			Help: "The total number of cpu core hours saved by nightshift",
+		},
+		[]string{"namespace", "scanner", "cluster"},
+	)
+	savedMemory = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsPrefix + "saved_memory_gb_hours_total",
+			Help: "The total number of memory GB hours saved by nightshift",
+		},
+		[]string{"namespace", "scanner", "cluster"},
+	)
+	// custom metrics for exporting the state of each object
+	objectReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsPrefix + "object_replicas",
+			Help: "Current number of replicas of each scheduled object",
+		},
+		objectLabels,
+	)
+	nextScale = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsPrefix + "next_scale_timestamp_seconds",
+			Help: "Time of the next scheduled scale event of each object",
+		},
+		objectLabels,
+	)
+	objectScale = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsPrefix + "object_scale_total",
+			Help: "The total number of scale actions of each object",
+		},
+		append(objectLabels, "result"),
+	)
+	// custom metrics for exporting the latency of scaling
+	scaleDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricsPrefix + "scale_duration_seconds",
+			Help:    "Duration of the scale api calls",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"type", "cluster", "result"},
+	)
+	scaleDelay = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricsPrefix + "scale_delay_seconds",
+			Help:    "Delay between the scheduled time of a scale event and the actual scaling",
+			Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+		},
+		[]string{"type", "cluster"},
+	)
+	// custom metrics for exporting trigger executions
+	triggerExecutions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsPrefix + "trigger_executions_total",
+			Help: "The total number of executions of each trigger",
+		},
+		[]string{"trigger", "type", "result"},
+	)
+	triggerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricsPrefix + "trigger_duration_seconds",
+			Help:    "Duration of the executions of each trigger",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+		},
+		[]string{"trigger", "type"},
+	)
+	// custom metric for exporting the connection state of watchers
+	watchConnected = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsPrefix + "watch_connected",
+			Help: "Connection state of the watcher of each scanner (1 if connected)",
+		},
+		[]string{"namespace", "type", "scanner", "cluster"},
+	)
+	savedCost = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsPrefix + "saved_cost_total",
+			Help: "The total cost saved by nightshift, based on the configured prices",
+		},
+		[]string{"namespace", "scanner", "cluster"},
+	)
+	// objects contains the labels of the objects that have per object
+	// metrics, by uid.
+	objects = map[string]prometheus.Labels{}
+	om      sync.Mutex
 )
 
 func init() {
@@ -59,6 +234,19 @@ func init() {
 		prometheus.MustRegister(m.prom)
 	}
 	prometheus.MustRegister(replicas)
+	prometheus.MustRegister(clusterReplicas)
+	prometheus.MustRegister(exitStatus)
+	prometheus.MustRegister(savedCPU)
+	prometheus.MustRegister(savedMemory)
+	prometheus.MustRegister(savedCost)
+	prometheus.MustRegister(objectReplicas)
+	prometheus.MustRegister(nextScale)
+	prometheus.MustRegister(objectScale)
+	prometheus.MustRegister(scaleDuration)
+	prometheus.MustRegister(scaleDelay)
+	prometheus.MustRegister(triggerExecutions)
+	prometheus.MustRegister(triggerDuration)
+	prometheus.MustRegister(watchConnected)
 }
 
 // Increase will increase given metric with 1
@@ -70,13 +258,165 @@ func Increase(metr string) {
 }
 
 // SetReplicas will set the replicas metric to given value for given namespace
-// and scanner id.
-func SetReplicas(ns, scanid string, repl int) {
-	if scanid == "" {
-		// don't store metrics without scanner id's configured.
-		return
-	}
+// and scanner id, and the cluster replicas metric for given namespace, scanner
+// id and cluster. Scanners without id are labeled with an empty scanner id.
+func SetReplicas(ns, scanid, cluster string, repl int) {
 	replicas.With(prometheus.Labels{
 		"target":  ns,
 		"scanner": scanid}).Set(float64(repl))
+	clusterReplicas.With(prometheus.Labels{
+		"target":  ns,
+		"scanner": scanid,
+		"cluster": cluster}).Set(float64(repl))
+}
+
+// SetExitStatus will set the exit status metric to given value for given
+// trigger id.
+func SetExitStatus(id string, status int) {
+	exitStatus.With(prometheus.Labels{"trigger": id}).Set(float64(status))
+}
+
+// AddSavings will increase the saved resources metrics with given cpu core
+// hours, memory GB hours and cost for given namespace, scanner id and cluster.
+func AddSavings(ns, scanid, cluster string, cpu, mem, cost float64) {
+	lbls := prometheus.Labels{
+		"namespace": ns,
+		"scanner":   scanid,
+		"cluster":   cluster}
+	for _, m := range []struct {
+		vec *prometheus.CounterVec
+		val float64
+	}{{savedCPU, cpu}, {savedMemory, mem}, {savedCost, cost}} {
+		if m.val > 0 {
+			m.vec.With(lbls).Add(m.val)
+		}
+	}
+}
+
+// result will return the result label for given error.
+func result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+// labels will return the per object metric labels of the object.
+func (obj Object) labels() prometheus.Labels {
+	return prometheus.Labels{
+		"namespace": obj.Namespace,
+		"object":    obj.Name,
+		"type":      obj.Type,
+		"scanner":   obj.Scanner,
+		"cluster":   obj.Cluster}
+}
+
+// SetObject will set the replicas and next scale metrics of given object. If
+// next is nil, the object has no upcoming scale event. If the labels of the
+// object changed, the metrics with the previous labels are removed.
+func SetObject(obj Object, repl int, next *time.Time) {
+	om.Lock()
+	defer om.Unlock()
+	lbls := obj.labels()
+	if prev, ok := objects[obj.UID]; ok && !equalLabels(prev, lbls) {
+		deleteObject(prev)
+	}
+	objects[obj.UID] = lbls
+	objectReplicas.With(lbls).Set(float64(repl))
+	if next == nil {
+		nextScale.Delete(lbls)
+		return
+	}
+	nextScale.With(lbls).Set(float64(next.Unix()))
+}
+
+// PruneObjects will remove the per object metrics of all objects, except the
+// objects of which the uid is in given keep map.
+func PruneObjects(keep map[string]bool) {
+	om.Lock()
+	defer om.Unlock()
+	for uid, lbls := range objects {
+		if !keep[uid] {
+			deleteObject(lbls)
+			delete(objects, uid)
+		}
+	}
+}
+
+// deleteObject will remove the per object metrics with given labels.
+func deleteObject(lbls prometheus.Labels) {
+	objectReplicas.Delete(lbls)
+	nextScale.Delete(lbls)
+	objectScale.DeletePartialMatch(lbls)
+}
+
+// equalLabels will check if both given label sets are identical.
+func equalLabels(a, b prometheus.Labels) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ObserveScale will count the scale action of given object, and will record
+// the duration of the scale api call.
+func ObserveScale(obj Object, d time.Duration, err error) {
+	res := result(err)
+	lbls := obj.labels()
+	lbls["result"] = res
+	objectScale.With(lbls).Inc()
+	scaleDuration.With(prometheus.Labels{
+		"type":    obj.Type,
+		"cluster": obj.Cluster,
+		"result":  res}).Observe(d.Seconds())
+}
+
+// ObserveScaleDelay will record the delay between the scheduled time of a
+// scale event and the actual scaling for given object type and cluster.
+func ObserveScaleDelay(typ, cluster string, d time.Duration) {
+	scaleDelay.With(prometheus.Labels{
+		"type":    typ,
+		"cluster": cluster}).Observe(d.Seconds())
+}
+
+// ObserveTrigger will count the execution of given trigger, and will record
+// its duration.
+func ObserveTrigger(id, typ string, d time.Duration, err error) {
+	triggerExecutions.With(prometheus.Labels{
+		"trigger": id,
+		"type":    typ,
+		"result":  result(err)}).Inc()
+	triggerDuration.With(prometheus.Labels{
+		"trigger": id,
+		"type":    typ}).Observe(d.Seconds())
+}
+
+// SetWatchConnected will set the connection state of the watcher of the
+// scanner with given namespace, type, id and cluster.
+func SetWatchConnected(ns, typ, scanid, cluster string, connected bool) {
+	val := 0.0
+	if connected {
+		val = 1
+	}
+	watchConnected.With(watchLabels(ns, typ, scanid, cluster)).Set(val)
+}
+
+// DeleteWatch will remove the connection state of the watcher of the scanner
+// with given namespace, type, id and cluster.
+func DeleteWatch(ns, typ, scanid, cluster string) {
+	watchConnected.Delete(watchLabels(ns, typ, scanid, cluster))
+}
+
+// watchLabels will return the labels of the watcher metrics.
+func watchLabels(ns, typ, scanid, cluster string) prometheus.Labels {
+	return prometheus.Labels{
+		"namespace": ns,
+		"type":      typ,
+		"scanner":   scanid,
+		"cluster":   cluster}
 }
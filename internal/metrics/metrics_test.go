@@ -1,7 +1,9 @@
 package metrics
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
@@ -37,3 +39,125 @@ func TestIncrease(t *testing.T) {
 		}
 	}
 }
+
+func TestSetReplicas(t *testing.T) {
+	SetReplicas("test", "", "", 1)
+	SetReplicas("test", "test-default", "", 2)
+	SetReplicas("test", "test-default", "remote", 3)
+
+	m := &dto.Metric{}
+	if err := replicas.With(prometheus.Labels{"target": "test", "scanner": "test-default"}).Write(m); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if m.GetGauge().GetValue() != 3 {
+		t.Errorf("failed test - expected 3 replicas, got %f", m.GetGauge().GetValue())
+	}
+	m = &dto.Metric{}
+	if err := clusterReplicas.With(prometheus.Labels{"target": "test", "scanner": "", "cluster": ""}).Write(m); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if m.GetGauge().GetValue() != 1 {
+		t.Errorf("failed test - expected 1 replica without scanner id, got %f", m.GetGauge().GetValue())
+	}
+	if n := testCount(replicas); n != 2 {
+		t.Errorf("failed test - expected 2 replicas gauges, got %d", n)
+	}
+	if n := testCount(clusterReplicas); n != 3 {
+		t.Errorf("failed test - expected 3 cluster replicas gauges, got %d", n)
+	}
+}
+
+func TestAddSavings(t *testing.T) {
+	AddSavings("test", "scanner", "", 1.5, 2, 0)
+	AddSavings("test", "scanner", "", 0.5, -1, 0)
+	lbls := prometheus.Labels{"namespace": "test", "scanner": "scanner", "cluster": ""}
+	for i, tst := range []struct {
+		vec *prometheus.CounterVec
+		val float64
+	}{{savedCPU, 2}, {savedMemory, 2}, {savedCost, 0}} {
+		m := &dto.Metric{}
+		if err := tst.vec.With(lbls).Write(m); err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if m.GetCounter().GetValue() != tst.val {
+			t.Errorf("failed test %d - expected %f, got %f", i, tst.val, m.GetCounter().GetValue())
+		}
+	}
+}
+
+func TestObjectMetrics(t *testing.T) {
+	obj := Object{UID: "1", Namespace: "test", Name: "app", Type: "deployment", Scanner: "test-default"}
+	next := time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)
+	SetObject(obj, 2, &next)
+	ObserveScale(obj, time.Second, nil)
+	ObserveScale(obj, time.Second, errors.New("oops"))
+
+	m := &dto.Metric{}
+	if err := nextScale.With(obj.labels()).Write(m); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if m.GetGauge().GetValue() != float64(next.Unix()) {
+		t.Errorf("failed test - expected next scale %d, got %f", next.Unix(), m.GetGauge().GetValue())
+	}
+	if n := testCount(objectScale); n != 2 {
+		t.Errorf("failed test - expected 2 scale counters, got %d", n)
+	}
+
+	// changed labels should remove the previous metrics
+	obj.Scanner = "test-other"
+	SetObject(obj, 0, nil)
+	if n := testCount(objectReplicas); n != 1 {
+		t.Errorf("failed test - expected 1 replicas gauge, got %d", n)
+	}
+	if n := testCount(nextScale); n != 0 {
+		t.Errorf("failed test - expected no next scale gauge, got %d", n)
+	}
+	if n := testCount(objectScale); n != 0 {
+		t.Errorf("failed test - expected no scale counters, got %d", n)
+	}
+
+	PruneObjects(map[string]bool{})
+	if n := testCount(objectReplicas); n != 0 {
+		t.Errorf("failed test - expected no replicas gauge, got %d", n)
+	}
+}
+
+func TestWatchMetrics(t *testing.T) {
+	SetWatchConnected("test", "deployment", "", "", true)
+	SetWatchConnected("test", "deployment", "", "", false)
+	m := &dto.Metric{}
+	if err := watchConnected.With(watchLabels("test", "deployment", "", "")).Write(m); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if m.GetGauge().GetValue() != 0 {
+		t.Errorf("failed test - expected disconnected, got %f", m.GetGauge().GetValue())
+	}
+	DeleteWatch("test", "deployment", "", "")
+	if n := testCount(watchConnected); n != 0 {
+		t.Errorf("failed test - expected no watch gauge, got %d", n)
+	}
+}
+
+func TestObserveTrigger(t *testing.T) {
+	ObserveTrigger("webhook", "webhook", 2*time.Second, nil)
+	ObserveTrigger("webhook", "webhook", time.Second, errors.New("oops"))
+	if n := testCount(triggerExecutions); n != 2 {
+		t.Errorf("failed test - expected 2 execution counters, got %d", n)
+	}
+	m := &dto.Metric{}
+	obs := triggerDuration.With(prometheus.Labels{"trigger": "webhook", "type": "webhook"})
+	if err := obs.(prometheus.Metric).Write(m); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if m.GetHistogram().GetSampleCount() != 2 || m.GetHistogram().GetSampleSum() != 3 {
+		t.Errorf("failed test - unexpected histogram: %v", m.GetHistogram())
+	}
+}
+
+// testCount will return the number of metrics collected by given collector.
+func testCount(c prometheus.Collector) int {
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(ch)
+	close(ch)
+	return len(ch)
+}
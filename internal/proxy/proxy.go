@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/agent"
+	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/logging"
+)
+
+// sleepInterval is the interval at which idle routes are checked, and their
+// objects are put back to sleep.
+const sleepInterval = time.Minute
+
+type proxy struct {
+	Addr   string
+	Routes []*config.Route
+
+	m    sync.Mutex
+	srv  *http.Server
+	done chan bool
+}
+
+// handler is the http.Handler that will dispatch requests to the route that
+// is configured for the requested host.
+type handler struct {
+	routes map[string]*route
+}
+
+var instance *proxy
+var once sync.Once
+
+var logger = logging.For(logging.Proxy)
+
+// New will instantiate a new proxy object.
+func New() *proxy {
+	once.Do(func() {
+		instance = &proxy{
+			done: make(chan bool),
+		}
+	})
+	return instance
+}
+
+// Start will start the wake-on-request proxy server, as well as the loop that
+// will put idle objects back to sleep.
+func (a *proxy) Start() {
+	hndlr := newHandler(a.Routes, agent.New().GetObjects)
+	a.m.Lock()
+	srv := &http.Server{
+		Addr:        a.Addr,
+		Handler:     hndlr,
+		ReadTimeout: 30 * time.Second,
+		IdleTimeout: 30 * time.Second,
+	}
+	a.srv = srv
+	a.m.Unlock()
+	go func() {
+		logger.Info("Starting wake-on-request proxy", "addr", a.Addr)
+		err := srv.ListenAndServe()
+		if err == http.ErrServerClosed {
+			return
+		}
+		logger.Error("Error serving proxy", logging.Err(err))
+		os.Exit(1)
+	}()
+	go func() {
+		tick := time.NewTicker(sleepInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-a.done:
+				return
+			case <-tick.C:
+				hndlr.sleepIdle(time.Now())
+			}
+		}
+	}()
+}
+
+// Stop will stop the proxy server. It will do nothing if the proxy was not
+// started.
+func (a *proxy) Stop() error {
+	a.m.Lock()
+	defer a.m.Unlock()
+	if a.srv == nil {
+		return nil
+	}
+
+	if err := a.srv.Shutdown(context.TODO()); err != nil {
+		return err
+	}
+	a.srv = nil
+
+	a.done <- true
+	return nil
+}
+
+// newHandler will create a new handler for the given list of proxy
+// configurations. The objects function is used to retrieve the current list
+// of objects managed by nightshift.
+func newHandler(cfgs []*config.Route, objects objectsFunc) *handler {
+	h := &handler{routes: map[string]*route{}}
+	for _, cfg := range cfgs {
+		rt, err := newRoute(cfg, objects)
+		if err != nil {
+			logger.Error("Error adding proxy", "proxy", cfg.Id, logging.Err(err))
+			continue
+		}
+		for _, host := range cfg.Host {
+			h.routes[host] = rt
+		}
+	}
+	return h
+}
+
+// ServeHTTP will dispatch the request to the route that is configured for the
+// requested host.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := strings.ToLower(r.Host)
+	if hst, _, err := net.SplitHostPort(host); err == nil {
+		host = hst
+	}
+	rt, ok := h.routes[host]
+	if !ok {
+		http.Error(w, "no proxy configured for "+host, http.StatusNotFound)
+		return
+	}
+	rt.ServeHTTP(w, r)
+}
+
+// sleepIdle will put the objects of all routes that have been idle for longer
+// than their configured idle timeout back to sleep.
+func (h *handler) sleepIdle(now time.Time) {
+	done := map[*route]bool{}
+	for _, rt := range h.routes {
+		if done[rt] {
+			continue
+		}
+		done[rt] = true
+		if err := rt.sleep(now); err != nil {
+			logger.Error("Error putting proxy to sleep", "proxy", rt.config.Id, logging.Err(err))
+		}
+	}
+}
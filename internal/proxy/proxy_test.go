@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+func TestHandlerServeHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	mock.reset(&scanner.Object{UID: "1", Name: "frontend", Namespace: "dev", Labels: map[string]string{"app": "frontend"}, Replicas: 1})
+	hndlr := newHandler([]*config.Route{newTestConfig(srv.URL, true)}, mock.get)
+
+	tests := []struct {
+		host   string
+		status int
+	}{
+		{host: "dev.example.com", status: http.StatusOK},
+		{host: "DEV.example.com:8081", status: http.StatusOK},
+		{host: "test.example.com", status: http.StatusNotFound},
+	}
+	for i, tst := range tests {
+		req := httptest.NewRequest("GET", "http://"+tst.host+"/", nil)
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(w, req)
+		if w.Code != tst.status {
+			t.Errorf("failed test %d - expected status %d, got %d", i, tst.status, w.Code)
+		}
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	prxy := &proxy{Addr: "127.0.0.1:0", done: make(chan bool)}
+	if err := prxy.Stop(); err != nil {
+		t.Errorf("failed test - unexpected err stopping a proxy that was not started: %s", err)
+	}
+	prxy.Start()
+	if err := prxy.Stop(); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if err := prxy.Stop(); err != nil {
+		t.Errorf("failed test - unexpected err stopping a stopped proxy: %s", err)
+	}
+}
@@ -0,0 +1,287 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/metrics"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// pollInterval is the interval at which the target is probed while waiting
+// for the objects to wake up.
+const pollInterval = 500 * time.Millisecond
+
+// objectsFunc returns the current list of objects managed by nightshift.
+type objectsFunc func() map[string]*scanner.Object
+
+// route is a proxy route that will wake up its objects on request, and will
+// forward the requests to the configured target once these are ready.
+type route struct {
+	config   *config.Route
+	selector labels.Selector
+	health   string
+	proxy    *httputil.ReverseProxy
+	client   *http.Client
+	objects  objectsFunc
+
+	m      sync.Mutex
+	last   time.Time
+	wokeAt time.Time
+	awake  bool
+	ready  bool
+	woken  map[string]int
+}
+
+// wakingPage is the page that is shown while the objects are waking up, and
+// the request is not held until the objects are ready.
+var wakingPage = template.Must(template.New("waking").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta http-equiv="refresh" content="5">
+  <title>Waking up {{ . }}</title>
+</head>
+<body>
+  <h1>Waking up {{ . }}</h1>
+  <p>This service was asleep and is starting now. This page will refresh automatically.</p>
+</body>
+</html>
+`))
+
+// newRoute will create a new route for the given proxy configuration.
+func newRoute(cfg *config.Route, objects objectsFunc) (*route, error) {
+	target, err := url.Parse(cfg.Target)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := labels.Parse(cfg.Selector)
+	if err != nil {
+		return nil, err
+	}
+	health, err := target.Parse(cfg.Health)
+	if err != nil {
+		return nil, err
+	}
+	rt := &route{
+		config:   cfg,
+		selector: sel,
+		health:   health.String(),
+		proxy:    httputil.NewSingleHostReverseProxy(target),
+		client:   &http.Client{Timeout: 2 * time.Second},
+		objects:  objects,
+		woken:    map[string]int{},
+	}
+	rt.proxy.ErrorHandler = rt.proxyError
+	return rt, nil
+}
+
+// ServeHTTP will wake up the objects of this route if these are asleep, and
+// will forward the request to the target once the objects are ready. If the
+// route is not configured to wait, a waking up page is shown instead while
+// the objects are not ready yet.
+func (r *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	objs := r.getObjects()
+	if len(objs) == 0 {
+		http.Error(w, fmt.Sprintf("no objects found for proxy %s", r.config.Id), http.StatusBadGateway)
+		return
+	}
+	if err := r.wake(objs, time.Now()); err != nil {
+		logger.Error("Error waking up proxy", "proxy", r.config.Id, logging.Err(err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !r.isReady() {
+		if !r.config.Wait {
+			r.serveWaking(w)
+			return
+		}
+		if err := r.waitReady(req.Context()); err != nil {
+			http.Error(w, fmt.Sprintf("%s did not wake up in time", r.config.Id), http.StatusGatewayTimeout)
+			return
+		}
+	}
+	r.proxy.ServeHTTP(w, req)
+}
+
+// getObjects will return the objects that are managed by this route.
+func (r *route) getObjects() []*scanner.Object {
+	objs := []*scanner.Object{}
+	for _, obj := range r.objects() {
+		if obj.Namespace == r.config.Namespace && r.selector.Matches(labels.Set(obj.Labels)) {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// wake will register the activity on this route, and will scale the given
+// objects that are asleep (scaled to 0 replicas) to their saved state, or
+// the configured number of replicas if no state is available. Objects will
+// not be scaled again while a previous wake up is still in progress. The
+// replicas each object is woken up with are recorded, so only these objects
+// are put back to sleep.
+func (r *route) wake(objs []*scanner.Object, now time.Time) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.last = now
+	if r.awake && now.Sub(r.wokeAt) < r.config.Timeout {
+		return nil
+	}
+	errs := []string{}
+	woken := false
+	for _, obj := range objs {
+		if obj.Replicas > 0 {
+			continue
+		}
+		replicas := r.config.Replicas
+		if obj.State != nil && obj.State.Replicas > 0 {
+			replicas = obj.State.Replicas
+		}
+		logger.Info("Waking up", obj.LogAttr(), "proxy", r.config.Id)
+		metrics.Increase("proxy_wake")
+		if err := obj.Scale(nil, replicas); err != nil {
+			metrics.Increase("proxy_wake_error")
+			errs = append(errs, err.Error())
+			continue
+		}
+		r.woken[obj.UID] = replicas
+		woken = true
+	}
+	if woken {
+		r.awake = true
+		r.wokeAt = now
+		r.ready = false
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ","))
+	}
+	return nil
+}
+
+// sleep will scale the objects of this route back to 0 replicas, if these
+// were woken up by the proxy, and no requests have been received within the
+// configured idle timeout. Objects of which the replicas have changed since
+// these were woken up, or that had a scheduled scale event since, are left
+// alone, as these are managed by their schedule again. The replicas are saved
+// as state, so these can be restored later on.
+func (r *route) sleep(now time.Time) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if !r.awake || now.Sub(r.last) < r.config.Idle {
+		return nil
+	}
+	errs := []string{}
+	for _, obj := range r.getObjects() {
+		replicas, ok := r.woken[obj.UID]
+		if !ok {
+			continue
+		}
+		if obj.Replicas == 0 || obj.Replicas != replicas || scheduledSince(obj, r.wokeAt, now) {
+			delete(r.woken, obj.UID)
+			continue
+		}
+		logger.Info("Putting back to sleep", obj.LogAttr(), "proxy", r.config.Id)
+		metrics.Increase("proxy_sleep")
+		if err := obj.Scale(&replicas, 0); err != nil {
+			metrics.Increase("proxy_sleep_error")
+			errs = append(errs, err.Error())
+			continue
+		}
+		delete(r.woken, obj.UID)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ","))
+	}
+	r.woken = map[string]int{}
+	r.awake = false
+	r.ready = false
+	return nil
+}
+
+// scheduledSince will return true if given object had a schedule that scales
+// the object between given times.
+func scheduledSince(obj *scanner.Object, since, now time.Time) bool {
+	for _, s := range obj.Schedule {
+		if !s.HasReplicas() && !s.HasResources() {
+			continue
+		}
+		next, err := s.GetNextTrigger(since)
+		if err == nil && !next.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// isReady will return true if the target is ready to receive requests. The
+// target is ready when its health endpoint responds with a non server error
+// status code.
+func (r *route) isReady() bool {
+	r.m.Lock()
+	ready := r.ready
+	r.m.Unlock()
+	if ready {
+		return true
+	}
+	resp, err := r.client.Get(r.health)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return false
+	}
+	r.setReady(true)
+	return true
+}
+
+// setReady will update the readiness of the target.
+func (r *route) setReady(ready bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.ready = ready
+}
+
+// waitReady will wait until the target is ready, or until the configured
+// timeout has expired.
+func (r *route) waitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+	tick := time.NewTicker(pollInterval)
+	defer tick.Stop()
+	for !r.isReady() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+	return nil
+}
+
+// serveWaking will write the waking up page.
+func (r *route) serveWaking(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Retry-After", "5")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	wakingPage.Execute(w, r.config.Id)
+}
+
+// proxyError is called when the request could not be forwarded to the
+// target, in which case the target is considered not ready anymore.
+func (r *route) proxyError(w http.ResponseWriter, req *http.Request, err error) {
+	logger.Error("Error proxying request", "proxy", r.config.Id, logging.Err(err))
+	r.setReady(false)
+	w.WriteHeader(http.StatusBadGateway)
+}
@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+func newTestConfig(target string, wait bool) *config.Route {
+	return &config.Route{
+		Id:        "dev",
+		Host:      []string{"dev.example.com"},
+		Namespace: "dev",
+		Selector:  "app=frontend",
+		Target:    target,
+		Health:    "/healthz",
+		Replicas:  1,
+		Idle:      time.Hour,
+		Timeout:   2 * time.Second,
+		Wait:      wait,
+	}
+}
+
+func TestRouteServeHTTP(t *testing.T) {
+	tests := []struct {
+		objs    []*scanner.Object
+		wait    bool
+		healthy int32
+		status  int
+		body    string
+		scaled  map[string]int
+	}{
+		{
+			objs: []*scanner.Object{
+				{UID: "1", Name: "frontend", Namespace: "dev", Labels: map[string]string{"app": "frontend"}, Replicas: 2},
+			},
+			wait:    false,
+			healthy: 1,
+			status:  http.StatusOK,
+			body:    "hello",
+			scaled:  map[string]int{},
+		},
+		{
+			objs: []*scanner.Object{
+				{UID: "1", Name: "frontend", Namespace: "dev", Labels: map[string]string{"app": "frontend"}, Replicas: 0, State: &scanner.State{Replicas: 3}},
+				{UID: "2", Name: "cache", Namespace: "dev", Labels: map[string]string{"app": "frontend"}, Replicas: 0},
+				{UID: "3", Name: "backend", Namespace: "dev", Labels: map[string]string{"app": "backend"}, Replicas: 0},
+				{UID: "4", Name: "frontend", Namespace: "test", Labels: map[string]string{"app": "frontend"}, Replicas: 0},
+			},
+			wait:    false,
+			healthy: 0,
+			status:  http.StatusServiceUnavailable,
+			body:    "Waking up dev",
+			scaled:  map[string]int{"frontend": 3, "cache": 1},
+		},
+		{
+			objs: []*scanner.Object{
+				{UID: "1", Name: "frontend", Namespace: "dev", Labels: map[string]string{"app": "frontend"}, Replicas: 0},
+			},
+			wait:    true,
+			healthy: 0,
+			status:  http.StatusOK,
+			body:    "hello",
+			scaled:  map[string]int{"frontend": 1},
+		},
+		{
+			objs: []*scanner.Object{
+				{UID: "3", Name: "backend", Namespace: "dev", Labels: map[string]string{"app": "backend"}, Replicas: 0},
+			},
+			wait:    true,
+			healthy: 1,
+			status:  http.StatusBadGateway,
+			body:    "no objects found",
+			scaled:  map[string]int{},
+		},
+	}
+
+	for i, tst := range tests {
+		healthy := tst.healthy
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&healthy) == 0 {
+				// become healthy after the first probe
+				atomic.StoreInt32(&healthy, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("hello"))
+		}))
+		mock.reset(tst.objs...)
+		rt, err := newRoute(newTestConfig(srv.URL, tst.wait), mock.get)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+			srv.Close()
+			continue
+		}
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest("GET", "http://dev.example.com/", nil))
+		if w.Code != tst.status {
+			t.Errorf("failed test %d - expected status %d, got %d", i, tst.status, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), tst.body) {
+			t.Errorf("failed test %d - expected body containing %s, got %s", i, tst.body, w.Body.String())
+		}
+		if scaled := mock.getScaled(); !reflect.DeepEqual(scaled, tst.scaled) {
+			t.Errorf("failed test %d - expected scaled %v, got %v", i, tst.scaled, scaled)
+		}
+		srv.Close()
+	}
+}
+
+func TestRouteWakeSleep(t *testing.T) {
+	mock.reset(&scanner.Object{UID: "1", Name: "frontend", Namespace: "dev", Replicas: 0, State: &scanner.State{Replicas: 2}})
+	cfg := newTestConfig("http://localhost", false)
+	cfg.Selector = ""
+	rt, err := newRoute(cfg, mock.get)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	now := time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC)
+
+	// not woken by the proxy, should not be put to sleep
+	if err := rt.sleep(now.Add(2 * time.Hour)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if scaled := mock.getScaled(); len(scaled) != 0 {
+		t.Errorf("expected no scale actions, got %v", scaled)
+	}
+
+	if err := rt.wake(rt.getObjects(), now); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if scaled := mock.getScaled(); scaled["frontend"] != 2 {
+		t.Errorf("expected frontend to be scaled to 2, got %v", scaled)
+	}
+
+	// activity within the idle timeout should keep the objects awake
+	if err := rt.wake(rt.getObjects(), now.Add(45*time.Minute)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := rt.sleep(now.Add(90 * time.Minute)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if scaled := mock.getScaled(); scaled["frontend"] != 2 {
+		t.Errorf("expected frontend to be still awake, got %v", scaled)
+	}
+
+	// idle for longer than the idle timeout should put the objects to sleep
+	if err := rt.sleep(now.Add(106 * time.Minute)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if scaled := mock.getScaled(); scaled["frontend"] != 0 {
+		t.Errorf("expected frontend to be put to sleep, got %v", scaled)
+	}
+	if rt.awake {
+		t.Errorf("expected route to be asleep")
+	}
+}
+
+func TestRouteSleepAfterSchedule(t *testing.T) {
+	sched, err := schedule.New("Mon-Fri 8:00 replicas=5")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tests := []struct {
+		replicas int
+		schedule []*schedule.Schedule
+		sleep    bool
+	}{
+		// not changed since woken up
+		{replicas: 2, sleep: true},
+		// scaled up by the schedule at 8:00
+		{replicas: 5, schedule: []*schedule.Schedule{sched}, sleep: false},
+		// scheduled at 8:00 to the same replicas
+		{replicas: 2, schedule: []*schedule.Schedule{sched}, sleep: false},
+		// scaled manually
+		{replicas: 3, sleep: false},
+	}
+	// monday 7:30
+	now := time.Date(2026, 10, 19, 7, 30, 0, 0, schedule.GetTimeZone())
+	for i, tst := range tests {
+		mock.reset(&scanner.Object{UID: "1", Name: "frontend", Namespace: "dev", Replicas: 0, State: &scanner.State{Replicas: 2}, Schedule: tst.schedule})
+		cfg := newTestConfig("http://localhost", false)
+		cfg.Selector = ""
+		rt, err := newRoute(cfg, mock.get)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := rt.wake(rt.getObjects(), now); err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+		}
+		mock.setReplicas("1", tst.replicas)
+		if err := rt.sleep(now.Add(2 * time.Hour)); err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+		}
+		scaled := mock.getScaled()
+		if tst.sleep && (scaled["frontend"] != 0 || mock.getStates()["frontend"] != 2) {
+			t.Errorf("failed test %d - expected frontend to be put to sleep with state 2, got %v %v", i, scaled, mock.getStates())
+		}
+		if !tst.sleep && scaled["frontend"] != 2 {
+			t.Errorf("failed test %d - expected frontend to be left alone, got %v", i, scaled)
+		}
+		if rt.awake {
+			t.Errorf("failed test %d - expected route to be asleep", i)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"sync"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// mockScanner is a scanner that will record the scale actions on objects,
+// and will update the replicas of the objects in the mockObjects accordingly.
+type mockScanner struct {
+	objects *mockObjects
+}
+
+// mockObjects is a thread safe collection of objects, that will be returned
+// as copies, similar to the agent.
+type mockObjects struct {
+	m      sync.Mutex
+	objs   map[string]*scanner.Object
+	scaled map[string]int
+	states map[string]int
+}
+
+var mock = &mockObjects{}
+
+func init() {
+	scanner.RegisterModule("mock", func() (scanner.Scanner, error) {
+		return &mockScanner{objects: mock}, nil
+	})
+}
+
+// reset will set the objects that are managed by the mock.
+func (m *mockObjects) reset(objs ...*scanner.Object) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.objs = map[string]*scanner.Object{}
+	m.scaled = map[string]int{}
+	m.states = map[string]int{}
+	for _, obj := range objs {
+		obj.Type = "mock"
+		m.objs[obj.UID] = obj
+	}
+}
+
+// get will return a copy of the objects managed by the mock.
+func (m *mockObjects) get() map[string]*scanner.Object {
+	m.m.Lock()
+	defer m.m.Unlock()
+	objs := map[string]*scanner.Object{}
+	for uid, obj := range m.objs {
+		objs[uid] = obj.Copy()
+	}
+	return objs
+}
+
+// getStates will return the state that was saved for each object.
+func (m *mockObjects) getStates() map[string]int {
+	m.m.Lock()
+	defer m.m.Unlock()
+	states := map[string]int{}
+	for k, v := range m.states {
+		states[k] = v
+	}
+	return states
+}
+
+// setReplicas will update the replicas of given object, as if it was scaled
+// outside of the proxy.
+func (m *mockObjects) setReplicas(uid string, replicas int) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.objs[uid].Replicas = replicas
+}
+
+// getScaled will return the number of replicas each object was scaled to.
+func (m *mockObjects) getScaled() map[string]int {
+	m.m.Lock()
+	defer m.m.Unlock()
+	scaled := map[string]int{}
+	for k, v := range m.scaled {
+		scaled[k] = v
+	}
+	return scaled
+}
+
+func (s *mockScanner) SetConfig(cfg scanner.Config)                      {}
+func (s *mockScanner) GetConfig() scanner.Config                         { return scanner.Config{} }
+func (s *mockScanner) GetObjects() ([]*scanner.Object, error)            { return nil, nil }
+func (s *mockScanner) GetState(obj *scanner.Object) (int, error)         { return 0, nil }
+func (s *mockScanner) Annotate(*scanner.Object, map[string]string) error { return nil }
+func (s *mockScanner) Watch(_stop chan bool) (chan scanner.Event, error) { return nil, nil }
+func (s *mockScanner) Scale(obj *scanner.Object, state *int, replicas int, p *scanner.Profile) error {
+	s.objects.m.Lock()
+	defer s.objects.m.Unlock()
+	s.objects.scaled[obj.Name] = replicas
+	if state != nil {
+		s.objects.states[obj.Name] = *state
+	}
+	if o, ok := s.objects.objs[obj.UID]; ok {
+		o.Replicas = replicas
+	}
+	return nil
+}
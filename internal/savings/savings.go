@@ -0,0 +1,260 @@
+package savings
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/joyrex2001/nightshift/internal/metrics"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// gb is the number of bytes in a GB, as used for memory requests.
+const gb = 1 << 30
+
+// staleObservation is the period after which an object that is no longer
+// observed is forgotten.
+const staleObservation = time.Hour
+
+// Prices describes the price of a cpu core hour and of a memory GB hour.
+type Prices struct {
+	CPU      float64 `json:"cpu"`
+	Memory   float64 `json:"memory"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// Amount describes an amount of saved resources, and the cost thereof.
+type Amount struct {
+	CPUCoreHours  float64 `json:"cpu_core_hours"`
+	MemoryGBHours float64 `json:"memory_gb_hours"`
+	Cost          float64 `json:"cost"`
+}
+
+// Savings describes the resources that are saved for the objects of a scanner
+// within a namespace.
+type Savings struct {
+	Namespace string `json:"namespace"`
+	ScannerId string `json:"scanner_id"`
+	Type      string `json:"type"`
+	Cluster   string `json:"cluster,omitempty"`
+	Amount
+}
+
+// Report describes the resources that are saved since nightshift started. The
+// savings are kept in memory only, and are reset when nightshift restarts; the
+// Since field contains the start of the reported period.
+type Report struct {
+	Since   time.Time `json:"since"`
+	Prices  Prices    `json:"prices"`
+	Total   Amount    `json:"total"`
+	Savings []Savings `json:"savings"`
+}
+
+// key identifies the objects of a scanner within a namespace.
+type key struct {
+	namespace string
+	scannerId string
+	typ       string
+	cluster   string
+}
+
+// observation is the last observed state of an object. The baseline is the
+// number of replicas the object had before nightshift scaled it down, if no
+// state is saved. The cpu and memory are the resources per hour that are saved
+// at the time of the observation.
+type observation struct {
+	key      key
+	time     time.Time
+	baseline int
+	cpu      float64
+	memory   float64
+}
+
+var (
+	m            sync.Mutex
+	prices       Prices
+	since        = time.Now()
+	observations = map[string]*observation{}
+	saved        = map[key]*Savings{}
+)
+
+// SetPrices will configure the prices that are used to calculate the cost
+// that has been saved.
+func SetPrices(p Prices) {
+	m.Lock()
+	defer m.Unlock()
+	prices = p
+}
+
+// Observe will account the resources that were saved by the given objects
+// since their previous observation, and will record their current state. The
+// resources saved are the resource requests of the replicas that are scaled
+// down, compared to the state saved by nightshift, or if no state is saved,
+// compared to the replicas before nightshift scaled the object down (see
+// Scaled). Objects that are scaled down otherwise (e.g. by a horizontal pod
+// autoscaler) are not accounted. Resources that are reduced by a resources
+// profile are accounted as well.
+func Observe(objs []*scanner.Object, now time.Time) {
+	m.Lock()
+	defer m.Unlock()
+	for _, obj := range objs {
+		k := key{
+			namespace: obj.Namespace,
+			scannerId: obj.ScannerId,
+			typ:       obj.Type,
+			cluster:   obj.Cluster,
+		}
+		obs, ok := observations[obj.UID]
+		if ok && now.After(obs.time) {
+			hours := now.Sub(obs.time).Hours()
+			account(obs.key, obs.cpu*hours, obs.memory*hours)
+		}
+		if !ok {
+			obs = &observation{}
+			observations[obj.UID] = obs
+		}
+		obs.key = k
+		obs.time = now
+		if obj.Replicas >= obs.baseline {
+			obs.baseline = 0
+		}
+		obs.cpu, obs.memory = savedRate(obj, obs.baseline)
+	}
+	for uid, obs := range observations {
+		if now.Sub(obs.time) > staleObservation {
+			delete(observations, uid)
+		}
+	}
+}
+
+// Scaled will record that nightshift has scaled the given object from given
+// number of replicas to its current replicas. If it was scaled down, the
+// original replicas are used as the baseline of the savings, until the object
+// is scaled up again.
+func Scaled(obj *scanner.Object, from int) {
+	m.Lock()
+	defer m.Unlock()
+	obs, ok := observations[obj.UID]
+	if !ok {
+		obs = &observation{}
+		observations[obj.UID] = obs
+	}
+	switch {
+	case obj.Replicas < from && from > obs.baseline:
+		obs.baseline = from
+	case obj.Replicas >= obs.baseline:
+		obs.baseline = 0
+	}
+}
+
+// account will add the given cpu core hours and memory GB hours to the
+// savings of given key, and will update the metrics accordingly.
+func account(k key, cpu, mem float64) {
+	if cpu <= 0 && mem <= 0 {
+		return
+	}
+	s, ok := saved[k]
+	if !ok {
+		s = &Savings{
+			Namespace: k.namespace,
+			ScannerId: k.scannerId,
+			Type:      k.typ,
+			Cluster:   k.cluster,
+		}
+		saved[k] = s
+	}
+	cost := cpu*prices.CPU + mem*prices.Memory
+	s.CPUCoreHours += cpu
+	s.MemoryGBHours += mem
+	s.Cost += cost
+	metrics.AddSavings(k.namespace, k.scannerId, k.cluster, cpu, mem, cost)
+}
+
+// savedRate will return the cpu cores and memory GB that are currently saved
+// for given object. The baseline is the saved state, or the given baseline
+// number of replicas if no state is saved, with the original container
+// resources if a resources profile is applied.
+func savedRate(obj *scanner.Object, baseline int) (float64, float64) {
+	repl := baseline
+	var orig map[string]corev1.ResourceRequirements
+	if obj.State != nil {
+		repl = obj.State.Replicas
+		orig = obj.State.Resources
+	}
+	var cpu, mem, origCPU, origMem float64
+	for c, reqs := range obj.Requests {
+		cpu += cores(reqs)
+		mem += memory(reqs)
+		if res, ok := orig[c]; ok {
+			reqs = res.Requests
+		}
+		origCPU += cores(reqs)
+		origMem += memory(reqs)
+	}
+	cpu = float64(repl)*origCPU - float64(obj.Replicas)*cpu
+	mem = float64(repl)*origMem - float64(obj.Replicas)*mem
+	if cpu < 0 {
+		cpu = 0
+	}
+	if mem < 0 {
+		mem = 0
+	}
+	return cpu, mem
+}
+
+// cores will return the cpu request in cores of given resource list.
+func cores(reqs corev1.ResourceList) float64 {
+	q, ok := reqs[corev1.ResourceCPU]
+	if !ok {
+		return 0
+	}
+	return float64(q.MilliValue()) / 1000
+}
+
+// memory will return the memory request in GB of given resource list.
+func memory(reqs corev1.ResourceList) float64 {
+	q, ok := reqs[corev1.ResourceMemory]
+	if !ok {
+		return 0
+	}
+	return float64(q.Value()) / gb
+}
+
+// GetReport will return the resources that are saved since nightshift started,
+// for all namespaces and scanners for which given filter returns true. The
+// filter is called without holding the lock, as it may be slow (e.g. when it
+// reviews access of the user).
+func GetReport(filter func(Savings) bool) Report {
+	m.Lock()
+	rep := Report{Since: since, Prices: prices, Savings: []Savings{}}
+	all := make([]Savings, 0, len(saved))
+	for _, s := range saved {
+		all = append(all, *s)
+	}
+	m.Unlock()
+	for _, s := range all {
+		if filter != nil && !filter(s) {
+			continue
+		}
+		rep.Savings = append(rep.Savings, s)
+		rep.Total.CPUCoreHours += s.CPUCoreHours
+		rep.Total.MemoryGBHours += s.MemoryGBHours
+		rep.Total.Cost += s.Cost
+	}
+	sort.Slice(rep.Savings, func(i, j int) bool {
+		a, b := rep.Savings[i], rep.Savings[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.ScannerId != b.ScannerId {
+			return a.ScannerId < b.ScannerId
+		}
+		return a.Type < b.Type
+	})
+	return rep
+}
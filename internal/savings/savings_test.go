@@ -0,0 +1,153 @@
+package savings
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+func reset() {
+	observations = map[string]*observation{}
+	saved = map[key]*Savings{}
+	prices = Prices{}
+}
+
+func requests(cpu, mem string) map[string]corev1.ResourceList {
+	return map[string]corev1.ResourceList{
+		"app": {
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(mem),
+		},
+	}
+}
+
+func TestSavedRate(t *testing.T) {
+	tests := []struct {
+		obj      *scanner.Object
+		baseline int
+		cpu      float64
+		mem      float64
+	}{
+		{
+			obj:      &scanner.Object{Replicas: 0, Requests: requests("500m", "1Gi")},
+			baseline: 2,
+			cpu:      1,
+			mem:      2,
+		},
+		{
+			obj:      &scanner.Object{Replicas: 2, Requests: requests("500m", "1Gi")},
+			baseline: 2,
+			cpu:      0,
+			mem:      0,
+		},
+		{
+			obj:      &scanner.Object{Replicas: 1, Requests: requests("500m", "1Gi"), State: &scanner.State{Replicas: 4}},
+			baseline: 1,
+			cpu:      1.5,
+			mem:      3,
+		},
+		{
+			obj:      &scanner.Object{Replicas: 3, Requests: requests("500m", "1Gi"), State: &scanner.State{Replicas: 1}},
+			baseline: 3,
+			cpu:      0,
+			mem:      0,
+		},
+		{
+			obj: &scanner.Object{Replicas: 1, Requests: requests("250m", "512Mi"), State: &scanner.State{
+				Replicas: 1,
+				Resources: map[string]corev1.ResourceRequirements{
+					"app": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+				},
+			}},
+			baseline: 1,
+			cpu:      0.75,
+			mem:      0,
+		},
+		{
+			obj:      &scanner.Object{Replicas: 0},
+			baseline: 3,
+			cpu:      0,
+			mem:      0,
+		},
+	}
+	for i, tst := range tests {
+		cpu, mem := savedRate(tst.obj, tst.baseline)
+		if math.Abs(cpu-tst.cpu) > 1e-9 || math.Abs(mem-tst.mem) > 1e-9 {
+			t.Errorf("failed test %d - expected %f cpu and %f mem, got %f and %f", i, tst.cpu, tst.mem, cpu, mem)
+		}
+	}
+}
+
+func TestObserve(t *testing.T) {
+	reset()
+	SetPrices(Prices{CPU: 0.1, Memory: 0.01, Currency: "EUR"})
+	now := time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC)
+	obj := &scanner.Object{
+		UID:       "1",
+		Namespace: "test",
+		ScannerId: "test-default",
+		Type:      "deployment",
+		Replicas:  2,
+		Requests:  requests("1", "2Gi"),
+	}
+	// scaled down by a horizontal pod autoscaler
+	hpa := &scanner.Object{
+		UID:       "2",
+		Namespace: "test",
+		ScannerId: "test-hpa",
+		Type:      "deployment",
+		Replicas:  4,
+		Requests:  requests("1", "2Gi"),
+	}
+	Observe([]*scanner.Object{obj, hpa}, now)
+	obj.Replicas = 0
+	Scaled(obj, 2)
+	hpa.Replicas = 1
+	Observe([]*scanner.Object{obj, hpa}, now.Add(time.Hour))
+	Observe([]*scanner.Object{obj, hpa}, now.Add(2*time.Hour))
+	Observe([]*scanner.Object{obj}, now.Add(11*time.Hour))
+	obj.Replicas = 2
+	Scaled(obj, 0)
+	Observe([]*scanner.Object{obj}, now.Add(13*time.Hour))
+	Observe([]*scanner.Object{obj}, now.Add(14*time.Hour))
+
+	rep := GetReport(nil)
+	if len(rep.Savings) != 1 {
+		t.Fatalf("failed test - expected 1 savings entry, got %d", len(rep.Savings))
+	}
+	s := rep.Savings[0]
+	if s.Namespace != "test" || s.ScannerId != "test-default" || s.Type != "deployment" {
+		t.Errorf("failed test - unexpected savings entry: %v", s)
+	}
+	if math.Abs(s.CPUCoreHours-24) > 1e-9 {
+		t.Errorf("failed test - expected 24 cpu core hours, got %f", s.CPUCoreHours)
+	}
+	if math.Abs(s.MemoryGBHours-48) > 1e-9 {
+		t.Errorf("failed test - expected 48 memory GB hours, got %f", s.MemoryGBHours)
+	}
+	if math.Abs(s.Cost-2.88) > 1e-9 {
+		t.Errorf("failed test - expected cost 2.88, got %f", s.Cost)
+	}
+	if rep.Total != s.Amount {
+		t.Errorf("failed test - expected total %v, got %v", s.Amount, rep.Total)
+	}
+	if rep.Prices.Currency != "EUR" {
+		t.Errorf("failed test - expected currency EUR, got %s", rep.Prices.Currency)
+	}
+
+	rep = GetReport(func(s Savings) bool { return s.Namespace != "test" })
+	if len(rep.Savings) != 0 || rep.Total.CPUCoreHours != 0 {
+		t.Errorf("failed test - expected filtered report to be empty, got %v", rep)
+	}
+
+	// objects that are no longer observed are forgotten
+	Observe([]*scanner.Object{}, now.Add(16*time.Hour))
+	if len(observations) != 0 {
+		t.Errorf("failed test - expected no observations, got %d", len(observations))
+	}
+}
@@ -0,0 +1,153 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+)
+
+// Cluster describes how to connect to a named cluster. The credentials are
+// either taken from a context in a kubeconfig file, or from a secret in the
+// cluster nightshift is running in. This secret should either contain a
+// kubeconfig (key kubeconfig), or the server url, a bearer token and
+// optionally a ca certificate (keys server, token and ca.crt).
+type Cluster struct {
+	Name       string
+	Kubeconfig string
+	Context    string
+	Secret     string
+}
+
+var (
+	clusters = map[string]Cluster{}
+	configs  = map[string]*rest.Config{}
+	cm       sync.Mutex
+)
+
+// unavailableCluster is the kubernetes config that is used by scanners for
+// which the configured cluster is not available. Requests using this config
+// will fail, preventing objects being scaled in the wrong cluster.
+var unavailableCluster = &rest.Config{Host: "https://unavailable.invalid"}
+
+// AddCluster will add the given cluster to the list of available clusters.
+func AddCluster(c Cluster) {
+	cm.Lock()
+	defer cm.Unlock()
+	c.Name = strings.ToLower(c.Name)
+	clusters[c.Name] = c
+	delete(configs, c.Name)
+}
+
+// GetKubernetesForCluster will return a kubernetes config object for the
+// cluster with given name. If no name is given, the default kubernetes config
+// is returned.
+func GetKubernetesForCluster(name string) (*rest.Config, error) {
+	name = strings.ToLower(name)
+	if name == "" {
+		return GetKubernetes()
+	}
+	cm.Lock()
+	defer cm.Unlock()
+	if cfg, ok := configs[name]; ok {
+		return cfg, nil
+	}
+	c, ok := clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown cluster %s", name)
+	}
+	cfg, err := c.getKubernetes()
+	if err != nil {
+		return nil, fmt.Errorf("failed connecting to cluster %s: %s", name, err)
+	}
+	configs[name] = cfg
+	return cfg, nil
+}
+
+// getKubernetes will create the kubernetes config object for the cluster.
+func (c Cluster) getKubernetes() (*rest.Config, error) {
+	if c.Secret != "" {
+		data, err := readSecret(c.Secret)
+		if err != nil {
+			return nil, err
+		}
+		return configFromSecret(data, c.Context)
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = c.Kubeconfig
+	if rules.ExplicitPath == "" {
+		rules.ExplicitPath = viper.GetString("openshift.kubeconfig")
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: c.Context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// readSecret will read the data of the secret referenced as namespace/name
+// from the cluster nightshift is running in.
+func readSecret(ref string) (map[string][]byte, error) {
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid secret reference '%s', expected namespace/name", ref)
+	}
+	cfg, err := GetKubernetes()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := client.CoreV1().Secrets(parts[0]).Get(context.Background(), parts[1], metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
+// configFromSecret will create a kubernetes config object based on the data
+// of a credentials secret. If the secret contains a kubeconfig, the given
+// context will be used (or the current context if empty).
+func configFromSecret(data map[string][]byte, ctx string) (*rest.Config, error) {
+	if kc, ok := data["kubeconfig"]; ok {
+		raw, err := clientcmd.Load(kc)
+		if err != nil {
+			return nil, err
+		}
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: ctx}
+		return clientcmd.NewDefaultClientConfig(*raw, overrides).ClientConfig()
+	}
+	server := strings.TrimSpace(string(data["server"]))
+	if server == "" {
+		return nil, fmt.Errorf("no kubeconfig or server specified in secret")
+	}
+	cfg := &rest.Config{
+		Host:        server,
+		BearerToken: strings.TrimSpace(string(data["token"])),
+	}
+	cfg.TLSClientConfig.CAData = data["ca.crt"]
+	return cfg, nil
+}
+
+// getClusterKubernetes will return the kubernetes config object for the given
+// cluster. If no cluster is given, the given default config is returned. If
+// the cluster is not available, a config that will fail all requests is
+// returned.
+func getClusterKubernetes(name string, def *rest.Config) *rest.Config {
+	if name == "" {
+		return def
+	}
+	cfg, err := GetKubernetesForCluster(name)
+	if err != nil {
+		logger.Error("Error connecting to cluster", logging.KeyCluster, name, logging.Err(err))
+		return unavailableCluster
+	}
+	return cfg
+}
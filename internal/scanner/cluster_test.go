@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+users:
+- name: nightshift
+  user:
+    token: secret
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: nightshift
+- name: prod
+  context:
+    cluster: prod
+    user: nightshift
+current-context: dev
+`
+
+func TestConfigFromSecret(t *testing.T) {
+	tests := []struct {
+		data  map[string][]byte
+		ctx   string
+		host  string
+		token string
+		err   bool
+	}{
+		{
+			data:  map[string][]byte{"kubeconfig": []byte(testKubeconfig)},
+			host:  "https://dev.example.com:6443",
+			token: "secret",
+		},
+		{
+			data:  map[string][]byte{"kubeconfig": []byte(testKubeconfig)},
+			ctx:   "prod",
+			host:  "https://prod.example.com:6443",
+			token: "secret",
+		},
+		{
+			data: map[string][]byte{"kubeconfig": []byte(testKubeconfig)},
+			ctx:  "test",
+			err:  true,
+		},
+		{
+			data:  map[string][]byte{"server": []byte("https://test.example.com:6443\n"), "token": []byte("abc\n")},
+			host:  "https://test.example.com:6443",
+			token: "abc",
+		},
+		{
+			data: map[string][]byte{"token": []byte("abc")},
+			err:  true,
+		},
+	}
+	for i, tst := range tests {
+		cfg, err := configFromSecret(tst.data, tst.ctx)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err == nil && (cfg.Host != tst.host || cfg.BearerToken != tst.token) {
+			t.Errorf("failed test %d - expected: %s (%s), got %s (%s)", i, tst.host, tst.token, cfg.Host, cfg.BearerToken)
+		}
+	}
+}
+
+func TestGetKubernetesForCluster(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nightshift")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "kubeconfig")
+	if err := ioutil.WriteFile(file, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	AddCluster(Cluster{Name: "Prod", Kubeconfig: file, Context: "prod"})
+	AddCluster(Cluster{Name: "broken", Kubeconfig: file, Context: "test"})
+
+	tests := []struct {
+		name string
+		host string
+		err  bool
+	}{
+		{name: "prod", host: "https://prod.example.com:6443"},
+		{name: "PROD", host: "https://prod.example.com:6443"},
+		{name: "broken", err: true},
+		{name: "unknown", err: true},
+	}
+	for i, tst := range tests {
+		cfg, err := GetKubernetesForCluster(tst.name)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err == nil && cfg.Host != tst.host {
+			t.Errorf("failed test %d - expected: %s, got %s", i, tst.host, cfg.Host)
+		}
+		if cfg := getClusterKubernetes(tst.name, nil); tst.err && cfg != unavailableCluster {
+			t.Errorf("failed test %d - expected unavailable cluster, got %v", i, cfg)
+		}
+	}
+}
@@ -4,14 +4,16 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/golang/glog"
 	v1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth/azure"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
 )
 
 // DeploymentScanner is the object that implements scanning of kubernetes
@@ -27,7 +29,7 @@ func init() {
 
 // NewDeploymentScanner will instantiate a new DeploymentScanner object.
 func NewDeploymentScanner() (Scanner, error) {
-	kubernetes, err := getKubernetes()
+	kubernetes, err := GetKubernetes()
 	if err != nil {
 		return nil, fmt.Errorf("failed instantiating k8s client: %s", err)
 	}
@@ -36,9 +38,11 @@ func NewDeploymentScanner() (Scanner, error) {
 	}, nil
 }
 
-// SetConfig will set the generic configuration for this scanner.
+// SetConfig will set the generic configuration for this scanner, and will
+// connect to the configured cluster.
 func (s *DeploymentScanner) SetConfig(cfg Config) {
 	s.config = cfg
+	s.kubernetes = getClusterKubernetes(cfg.Cluster, s.kubernetes)
 }
 
 // GetConfig will return the config applied for this scanner.
@@ -56,9 +60,10 @@ func (s *DeploymentScanner) GetObjects() ([]*Object, error) {
 	return s.getObjects(rcs)
 }
 
-// Scale will scale a given object to given amount of replicas.
-func (s *DeploymentScanner) Scale(obj *Object, state *int, replicas int) error {
-	glog.Infof("Scaling %s/%s to %d replicas", obj.Namespace, obj.Name, replicas)
+// Scale will scale a given object to given amount of replicas, and will apply
+// the given resources profile, if any.
+func (s *DeploymentScanner) Scale(obj *Object, state *int, replicas int, profile *Profile) error {
+	logger.Info("Scaling", obj.LogAttr(), "replicas", replicas)
 	apps, err := kubernetes.NewForConfig(s.kubernetes)
 	if err != nil {
 		return err
@@ -67,6 +72,12 @@ func (s *DeploymentScanner) Scale(obj *Object, state *int, replicas int) error {
 	if err != nil {
 		return fmt.Errorf("GetScale failed with: %s", err)
 	}
+	if profile != nil {
+		dp.ObjectMeta, err = updateResources(dp.ObjectMeta, &dp.Spec.Template.Spec, int(*dp.Spec.Replicas), profile)
+		if err != nil {
+			return err
+		}
+	}
 	repl := int32(replicas)
 	dp.Spec.Replicas = &repl
 	if state != nil {
@@ -76,6 +87,20 @@ func (s *DeploymentScanner) Scale(obj *Object, state *int, replicas int) error {
 	return err
 }
 
+// Annotate will update the annotations of a given object.
+func (s *DeploymentScanner) Annotate(obj *Object, annotations map[string]string) error {
+	patch, err := annotationPatch(annotations)
+	if err != nil {
+		return err
+	}
+	apps, err := kubernetes.NewForConfig(s.kubernetes)
+	if err != nil {
+		return err
+	}
+	_, err = apps.AppsV1().Deployments(obj.Namespace).Patch(context.Background(), obj.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
 // GetState will return the current number of replicas.
 func (s *DeploymentScanner) GetState(obj *Object) (int, error) {
 	dc, err := s.getDeployment(obj)
@@ -126,7 +151,7 @@ func (s *DeploymentScanner) getObjects(rcs *v1.DeploymentList) ([]*Object, error
 // Watch will return a channel on which Event objects will be published that
 // describe change events in the cluster.
 func (s *DeploymentScanner) Watch(_stop chan bool) (chan Event, error) {
-	return watcher(_stop, s.getWatcher, s.unmarshall)
+	return watcher(_stop, s.config, s.getWatcher, s.unmarshall)
 }
 
 // getWatcher will return a watcher for Deployments
@@ -148,8 +173,9 @@ func (s *DeploymentScanner) unmarshall(kobj interface{}) (*Object, error) {
 	}
 	obj := NewObjectForScanner(s)
 	if err := obj.updateWithMeta(m.ObjectMeta); err != nil {
-		glog.Error(err)
+		logger.Error("Error reading object", obj.LogAttr(), logging.Err(err))
 	}
 	obj.Replicas = int(*m.Spec.Replicas)
+	obj.Requests = containerRequests(&m.Spec.Template.Spec)
 	return obj, nil
 }
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/golang/glog"
 	v1 "github.com/openshift/api/apps/v1"
 	appsv1 "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
 )
 
 // OpenShiftScanner is the object that implements scanning of OpenShift
@@ -25,7 +27,7 @@ func init() {
 
 // NewOpenShiftScanner will instantiate a new OpenShiftScanner object.
 func NewOpenShiftScanner() (Scanner, error) {
-	kubernetes, err := getKubernetes()
+	kubernetes, err := GetKubernetes()
 	if err != nil {
 		return nil, fmt.Errorf("failed instantiating k8s client: %s", err)
 	}
@@ -34,9 +36,11 @@ func NewOpenShiftScanner() (Scanner, error) {
 	}, nil
 }
 
-// SetConfig will set the generic configuration for this scanner.
+// SetConfig will set the generic configuration for this scanner, and will
+// connect to the configured cluster.
 func (s *OpenShiftScanner) SetConfig(cfg Config) {
 	s.config = cfg
+	s.kubernetes = getClusterKubernetes(cfg.Cluster, s.kubernetes)
 }
 
 // GetConfig will return the config applied for this scanner.
@@ -54,9 +58,10 @@ func (s *OpenShiftScanner) GetObjects() ([]*Object, error) {
 	return s.getObjects(rcs)
 }
 
-// Scale will scale a given object to given amount of replicas.
-func (s *OpenShiftScanner) Scale(obj *Object, state *int, replicas int) error {
-	glog.Infof("Scaling %s/%s to %d replicas", obj.Namespace, obj.Name, replicas)
+// Scale will scale a given object to given amount of replicas, and will apply
+// the given resources profile, if any.
+func (s *OpenShiftScanner) Scale(obj *Object, state *int, replicas int, profile *Profile) error {
+	logger.Info("Scaling", obj.LogAttr(), "replicas", replicas)
 	apps, err := appsv1.NewForConfig(s.kubernetes)
 	if err != nil {
 		return err
@@ -65,6 +70,12 @@ func (s *OpenShiftScanner) Scale(obj *Object, state *int, replicas int) error {
 	if err != nil {
 		return fmt.Errorf("GetScale failed with: %s", err)
 	}
+	if profile != nil && dc.Spec.Template != nil {
+		dc.ObjectMeta, err = updateResources(dc.ObjectMeta, &dc.Spec.Template.Spec, int(dc.Spec.Replicas), profile)
+		if err != nil {
+			return err
+		}
+	}
 	if state != nil {
 		dc.ObjectMeta = updateState(dc.ObjectMeta, *state)
 	}
@@ -73,6 +84,20 @@ func (s *OpenShiftScanner) Scale(obj *Object, state *int, replicas int) error {
 	return err
 }
 
+// Annotate will update the annotations of a given object.
+func (s *OpenShiftScanner) Annotate(obj *Object, annotations map[string]string) error {
+	patch, err := annotationPatch(annotations)
+	if err != nil {
+		return err
+	}
+	apps, err := appsv1.NewForConfig(s.kubernetes)
+	if err != nil {
+		return err
+	}
+	_, err = apps.DeploymentConfigs(obj.Namespace).Patch(context.Background(), obj.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
 // GetState will save the current number of replicas.
 func (s *OpenShiftScanner) GetState(obj *Object) (int, error) {
 	dc, err := s.getDeploymentConfig(obj)
@@ -123,7 +148,7 @@ func (s *OpenShiftScanner) getObjects(rcs *v1.DeploymentConfigList) ([]*Object,
 // Watch will return a channel on which Event objects will be published that
 // describe change events in the cluster.
 func (s *OpenShiftScanner) Watch(_stop chan bool) (chan Event, error) {
-	return watcher(_stop, s.getWatcher, s.unmarshall)
+	return watcher(_stop, s.config, s.getWatcher, s.unmarshall)
 }
 
 // getWatcher will return a watcher for DeploymentConfigs
@@ -145,8 +170,11 @@ func (s *OpenShiftScanner) unmarshall(kobj interface{}) (*Object, error) {
 	}
 	obj := NewObjectForScanner(s)
 	if err := obj.updateWithMeta(m.ObjectMeta); err != nil {
-		glog.Error(err)
+		logger.Error("Error reading object", obj.LogAttr(), logging.Err(err))
 	}
 	obj.Replicas = int(m.Spec.Replicas)
+	if m.Spec.Template != nil {
+		obj.Requests = containerRequests(&m.Spec.Template.Spec)
+	}
 	return obj, nil
 }
@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Profile describes the resource requests and limits that are applied to the
+// containers of an object by the resources setting of a schedule. If no
+// containers are specified, the profile will be applied to all containers.
+type Profile struct {
+	Name       string
+	Containers []string
+	Resources  corev1.ResourceRequirements
+}
+
+// RestoreProfile is the profile that will restore the container resources
+// that were saved in the state annotation when a profile was applied.
+var RestoreProfile = &Profile{Name: "restore"}
+
+var (
+	profiles = map[string]*Profile{}
+	pm       sync.Mutex
+)
+
+// AddProfile will add the given profile to the list of available resource
+// profiles.
+func AddProfile(profile *Profile) {
+	pm.Lock()
+	defer pm.Unlock()
+	profiles[strings.ToLower(profile.Name)] = profile
+}
+
+// GetProfile will return the resource profile with given name. The restore
+// profile is always available.
+func GetProfile(name string) (*Profile, error) {
+	name = strings.ToLower(name)
+	if name == RestoreProfile.Name {
+		return RestoreProfile, nil
+	}
+	pm.Lock()
+	defer pm.Unlock()
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown resources profile %s", name)
+	}
+	return profile, nil
+}
+
+// appliesTo will check if the profile should be applied to the container with
+// given name.
+func (p *Profile) appliesTo(container string) bool {
+	if len(p.Containers) == 0 {
+		return true
+	}
+	for _, c := range p.Containers {
+		if c == container {
+			return true
+		}
+	}
+	return false
+}
+
+// updateResources will apply the given profile to the containers in the given
+// pod spec, and will save the original resources of these containers in the
+// savestate annotation, unless these were saved before. If no state was saved
+// before, the given amount of replicas is saved as state as well. If the
+// RestoreProfile is given, the saved resources are restored and removed from
+// the savestate annotation instead. It will return the updated ObjectMeta.
+func updateResources(meta metav1.ObjectMeta, spec *corev1.PodSpec, repl int, profile *Profile) (metav1.ObjectMeta, error) {
+	state, err := getState(meta.Annotations)
+	if err != nil {
+		return meta, err
+	}
+	if profile == RestoreProfile {
+		if state == nil || len(state.Resources) == 0 {
+			return meta, nil
+		}
+		for i, c := range spec.Containers {
+			if res, ok := state.Resources[c.Name]; ok {
+				spec.Containers[i].Resources = res
+			}
+		}
+		state.Resources = nil
+		return setState(meta, state)
+	}
+	if state == nil {
+		state = &State{Replicas: repl}
+	}
+	if state.Resources == nil {
+		state.Resources = map[string]corev1.ResourceRequirements{}
+	}
+	for i, c := range spec.Containers {
+		if !profile.appliesTo(c.Name) {
+			continue
+		}
+		if _, ok := state.Resources[c.Name]; !ok {
+			state.Resources[c.Name] = *c.Resources.DeepCopy()
+		}
+		spec.Containers[i].Resources = mergeResources(c.Resources, profile.Resources)
+	}
+	return setState(meta, state)
+}
+
+// mergeResources will return a copy of given resource requirements, updated
+// with the requests and limits specified in the given profile resources.
+// Requests that exceed the resulting limits are lowered to these limits, as
+// kubernetes won't accept requests above the limits.
+func mergeResources(res, profile corev1.ResourceRequirements) corev1.ResourceRequirements {
+	out := *res.DeepCopy()
+	if len(profile.Requests) > 0 && out.Requests == nil {
+		out.Requests = corev1.ResourceList{}
+	}
+	for k, v := range profile.Requests {
+		out.Requests[k] = v.DeepCopy()
+	}
+	if len(profile.Limits) > 0 && out.Limits == nil {
+		out.Limits = corev1.ResourceList{}
+	}
+	for k, v := range profile.Limits {
+		out.Limits[k] = v.DeepCopy()
+	}
+	for k, v := range out.Requests {
+		if lim, ok := out.Limits[k]; ok && v.Cmp(lim) > 0 {
+			out.Requests[k] = lim.DeepCopy()
+		}
+	}
+	return out
+}
+
+// containerRequests will return the resource requests of each container in
+// the given pod spec.
+func containerRequests(spec *corev1.PodSpec) map[string]corev1.ResourceList {
+	reqs := map[string]corev1.ResourceList{}
+	for _, c := range spec.Containers {
+		reqs[c.Name] = c.Resources.Requests.DeepCopy()
+	}
+	return reqs
+}
@@ -0,0 +1,196 @@
+package scanner
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetProfile(t *testing.T) {
+	AddProfile(&Profile{Name: "Small"})
+	tests := []struct {
+		name string
+		err  bool
+	}{
+		{name: "small", err: false},
+		{name: "SMALL", err: false},
+		{name: "restore", err: false},
+		{name: "large", err: true},
+	}
+	for i, tst := range tests {
+		_, err := GetProfile(tst.name)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+	}
+}
+
+func TestUpdateResources(t *testing.T) {
+	small := &Profile{
+		Name:       "small",
+		Containers: []string{"app"},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+		},
+	}
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				},
+			},
+			{
+				Name: "sidecar",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				},
+			},
+		},
+	}
+	meta := metav1.ObjectMeta{}
+
+	// apply profile, and save the original resources
+	meta, err := updateResources(meta, spec, 3, small)
+	if err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if cpu := spec.Containers[0].Resources.Requests[corev1.ResourceCPU]; cpu.String() != "50m" {
+		t.Errorf("failed test - expected cpu request 50m, got %s", cpu.String())
+	}
+	if mem := spec.Containers[0].Resources.Limits[corev1.ResourceMemory]; mem.String() != "128Mi" {
+		t.Errorf("failed test - expected memory limit 128Mi, got %s", mem.String())
+	}
+	if cpu := spec.Containers[1].Resources.Requests[corev1.ResourceCPU]; cpu.String() != "100m" {
+		t.Errorf("failed test - expected unchanged sidecar, got %s", cpu.String())
+	}
+	st := meta.Annotations[SaveStateAnnotation]
+	if st != `{"replicas":3,"resources":{"app":{"requests":{"cpu":"1"}}}}` {
+		t.Errorf("failed test - unexpected state: %s", st)
+	}
+
+	// applying a profile again should keep the original resources
+	meta, err = updateResources(meta, spec, 1, small)
+	if err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if st2 := meta.Annotations[SaveStateAnnotation]; st2 != st {
+		t.Errorf("failed test - expected: %s, got %s", st, st2)
+	}
+
+	// restore the original resources
+	meta, err = updateResources(meta, spec, 1, RestoreProfile)
+	if err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if cpu := spec.Containers[0].Resources.Requests[corev1.ResourceCPU]; cpu.String() != "1" {
+		t.Errorf("failed test - expected restored cpu request 1, got %s", cpu.String())
+	}
+	if len(spec.Containers[0].Resources.Limits) != 0 {
+		t.Errorf("failed test - expected no limits, got %v", spec.Containers[0].Resources.Limits)
+	}
+	if st := meta.Annotations[SaveStateAnnotation]; st != "3" {
+		t.Errorf("failed test - expected: 3, got %s", st)
+	}
+
+	// invalid state annotation
+	meta.Annotations[SaveStateAnnotation] = "a"
+	if _, err := updateResources(meta, spec, 1, small); err == nil {
+		t.Errorf("failed test - expected err, but got none")
+	}
+}
+
+func TestMergeResources(t *testing.T) {
+	list := func(cpu, mem string) corev1.ResourceList {
+		res := corev1.ResourceList{}
+		if cpu != "" {
+			res[corev1.ResourceCPU] = resource.MustParse(cpu)
+		}
+		if mem != "" {
+			res[corev1.ResourceMemory] = resource.MustParse(mem)
+		}
+		return res
+	}
+	str := func(res corev1.ResourceRequirements) string {
+		out := map[string]map[corev1.ResourceName]string{"requests": {}, "limits": {}}
+		for k, v := range res.Requests {
+			out["requests"][k] = v.String()
+		}
+		for k, v := range res.Limits {
+			out["limits"][k] = v.String()
+		}
+		return fmt.Sprint(out)
+	}
+	tests := []struct {
+		res     corev1.ResourceRequirements
+		profile corev1.ResourceRequirements
+		out     corev1.ResourceRequirements
+	}{
+		{
+			res:     corev1.ResourceRequirements{},
+			profile: corev1.ResourceRequirements{Requests: list("50m", ""), Limits: list("", "128Mi")},
+			out:     corev1.ResourceRequirements{Requests: list("50m", ""), Limits: list("", "128Mi")},
+		},
+		{
+			res:     corev1.ResourceRequirements{Requests: list("1", "1Gi"), Limits: list("2", "2Gi")},
+			profile: corev1.ResourceRequirements{Requests: list("100m", "")},
+			out:     corev1.ResourceRequirements{Requests: list("100m", "1Gi"), Limits: list("2", "2Gi")},
+		},
+		{
+			// profile requests above the limits of the container
+			res:     corev1.ResourceRequirements{Requests: list("100m", "128Mi"), Limits: list("200m", "256Mi")},
+			profile: corev1.ResourceRequirements{Requests: list("500m", "1Gi")},
+			out:     corev1.ResourceRequirements{Requests: list("200m", "256Mi"), Limits: list("200m", "256Mi")},
+		},
+		{
+			// profile limits below the requests of the container
+			res:     corev1.ResourceRequirements{Requests: list("1", "1Gi")},
+			profile: corev1.ResourceRequirements{Limits: list("250m", "")},
+			out:     corev1.ResourceRequirements{Requests: list("250m", "1Gi"), Limits: list("250m", "")},
+		},
+		{
+			res:     corev1.ResourceRequirements{Requests: list("1", ""), Limits: list("2", "")},
+			profile: corev1.ResourceRequirements{Requests: list("4", ""), Limits: list("8", "")},
+			out:     corev1.ResourceRequirements{Requests: list("4", ""), Limits: list("8", "")},
+		},
+	}
+	for i, tst := range tests {
+		orig := str(tst.res)
+		out := mergeResources(tst.res, tst.profile)
+		if str(out) != str(tst.out) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, str(tst.out), str(out))
+		}
+		if str(tst.res) != orig {
+			t.Errorf("failed test %d - expected a copy, but given resources changed to %s", i, str(tst.res))
+		}
+	}
+}
+
+func TestContainerRequests(t *testing.T) {
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "app", Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+			}},
+			{Name: "sidecar"},
+		},
+	}
+	reqs := containerRequests(spec)
+	if len(reqs) != 2 {
+		t.Errorf("failed test - expected 2 containers, got %d", len(reqs))
+	}
+	if cpu := reqs["app"][corev1.ResourceCPU]; cpu.String() != "500m" {
+		t.Errorf("failed test - expected cpu request 500m, got %s", cpu.String())
+	}
+	if len(reqs["sidecar"]) != 0 {
+		t.Errorf("failed test - expected no requests, got %v", reqs["sidecar"])
+	}
+}
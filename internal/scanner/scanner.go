@@ -2,10 +2,15 @@ package scanner
 
 import (
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/metrics"
 	"github.com/joyrex2001/nightshift/internal/schedule"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -15,7 +20,8 @@ type Scanner interface {
 	GetConfig() Config
 	GetObjects() ([]*Object, error)
 	GetState(*Object) (int, error)
-	Scale(*Object, *int, int) error
+	Scale(*Object, *int, int, *Profile) error
+	Annotate(*Object, map[string]string) error
 	Watch(chan bool) (chan Event, error)
 }
 
@@ -31,25 +37,35 @@ type Config struct {
 	Schedule  []*schedule.Schedule `json:"schedule"`
 	Type      string               `json:"type"`
 	Priority  int                  `json:"priority"`
+	Cluster   string               `json:"cluster"`
 }
 
-// Object is an object found by the scanner.
+// Object is an object found by the scanner. Requests contains the resource
+// requests of each container of a single replica.
 type Object struct {
-	Namespace string               `json:"namespace"`
-	UID       string               `json:"uid"`
-	Name      string               `json:"name"`
-	Type      string               `json:"type"`
-	Schedule  []*schedule.Schedule `json:"schedule"`
-	State     *State               `json:"state"`
-	Replicas  int                  `json:"replicas"`
-	Priority  int                  `json:"priority"`
-	ScannerId string               `json:"scanner_id"`
-	scanner   Scanner
+	Namespace   string                         `json:"namespace"`
+	UID         string                         `json:"uid"`
+	Name        string                         `json:"name"`
+	Type        string                         `json:"type"`
+	Labels      map[string]string              `json:"labels"`
+	Annotations map[string]string              `json:"annotations"`
+	Schedule    []*schedule.Schedule           `json:"schedule"`
+	State       *State                         `json:"state"`
+	Replicas    int                            `json:"replicas"`
+	Priority    int                            `json:"priority"`
+	ScannerId   string                         `json:"scanner_id"`
+	Cluster     string                         `json:"cluster"`
+	SnoozeUntil *time.Time                     `json:"snooze_until"`
+	Requests    map[string]corev1.ResourceList `json:"requests,omitempty"`
+	scanner     Scanner
 }
 
-// State defines a state of the object.
+// State defines a state of the object. Resources contains the original
+// resources per container, which are saved when a resources profile is
+// applied.
 type State struct {
-	Replicas int `json:"replicas"`
+	Replicas  int                                    `json:"replicas"`
+	Resources map[string]corev1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 // Event is the structure that is send by the watch method over the channel.
@@ -69,6 +85,8 @@ const (
 
 var modules map[string]Factory
 
+var logger = logging.For(logging.Scanner)
+
 // RegisterModule will add the provided module, with given factory method to
 // the list of available modules in order to support dependency injection, as
 // well as easing up modular development for scanners.
@@ -91,7 +109,13 @@ func New(typ string) (Scanner, error) {
 }
 
 // NewForConfig will return a Scanner object based on the given Config object.
+// It will return an error if the configured cluster is not available.
 func NewForConfig(cfg Config) (Scanner, error) {
+	if cfg.Cluster != "" {
+		if _, err := GetKubernetesForCluster(cfg.Cluster); err != nil {
+			return nil, err
+		}
+	}
 	scnr, err := New(cfg.Type)
 	if err != nil {
 		return nil, err
@@ -110,6 +134,7 @@ func NewObjectForScanner(scnr Scanner) *Object {
 		Type:      cfg.Type,
 		Schedule:  cfg.Schedule,
 		ScannerId: cfg.Id,
+		Cluster:   cfg.Cluster,
 		scanner:   scnr,
 	}
 }
@@ -121,6 +146,22 @@ func (obj *Object) Copy() *Object {
 	if new.State != nil {
 		new.State = &State{}
 		*(new.State) = *(obj.State)
+		if obj.State.Resources != nil {
+			new.State.Resources = map[string]corev1.ResourceRequirements{}
+			for k, v := range obj.State.Resources {
+				new.State.Resources[k] = *v.DeepCopy()
+			}
+		}
+	}
+	if new.SnoozeUntil != nil {
+		until := *obj.SnoozeUntil
+		new.SnoozeUntil = &until
+	}
+	if obj.Requests != nil {
+		new.Requests = map[string]corev1.ResourceList{}
+		for k, v := range obj.Requests {
+			new.Requests[k] = v.DeepCopy()
+		}
 	}
 	new.Schedule = []*schedule.Schedule{}
 	for _, sched := range obj.Schedule {
@@ -135,6 +176,8 @@ func (obj *Object) updateWithMeta(meta metav1.ObjectMeta) error {
 	var err error
 	obj.Name = meta.Name
 	obj.UID = string(meta.UID)
+	obj.Labels = meta.Labels
+	obj.Annotations = meta.Annotations
 	obj.Schedule, err = getSchedule(obj.Schedule, meta.Annotations)
 	if err != nil {
 		return fmt.Errorf("error parsing schedule annotation for %s (%s); %s", meta.UID, meta.Name, err)
@@ -143,6 +186,10 @@ func (obj *Object) updateWithMeta(meta metav1.ObjectMeta) error {
 	if err != nil {
 		return fmt.Errorf("error parsing state annotation for %s (%s); %s", meta.UID, meta.Name, err)
 	}
+	obj.SnoozeUntil, err = getSnooze(meta.Annotations)
+	if err != nil {
+		return fmt.Errorf("error parsing snooze annotation for %s (%s); %s", meta.UID, meta.Name, err)
+	}
 	return nil
 }
 
@@ -150,7 +197,7 @@ func (obj *Object) updateWithMeta(meta metav1.ObjectMeta) error {
 func (obj *Object) getScanner() (Scanner, error) {
 	var err error
 	if obj.scanner == nil {
-		obj.scanner, err = New(obj.Type)
+		obj.scanner, err = NewForConfig(Config{Type: obj.Type, Namespace: obj.Namespace, Cluster: obj.Cluster})
 		if err != nil {
 			return nil, err
 		}
@@ -160,17 +207,114 @@ func (obj *Object) getScanner() (Scanner, error) {
 
 // Scale will scale the Object to the given amount of replicas.
 func (obj *Object) Scale(state *int, replicas int) error {
+	return obj.ScaleWithProfile(state, replicas, nil)
+}
+
+// ScaleWithProfile will scale the Object to the given amount of replicas, and
+// will apply the given resources profile to its containers. If the profile is
+// nil, the container resources are not changed.
+func (obj *Object) ScaleWithProfile(state *int, replicas int, profile *Profile) error {
 	scanner, err := obj.getScanner()
 	if err != nil {
 		return err
 	}
-	if err := scanner.Scale(obj, state, replicas); err != nil {
+	start := time.Now()
+	err = scanner.Scale(obj, state, replicas, profile)
+	metrics.ObserveScale(obj.MetricsObject(), time.Since(start), err)
+	if err != nil {
 		return err
 	}
 	obj.Replicas = replicas
 	return nil
 }
 
+// LogAttr will return the fields that identify the scanner in log messages.
+func (cfg Config) LogAttr() slog.Attr {
+	return logging.ScannerConfig(cfg.Namespace, cfg.Type, cfg.Id, cfg.Cluster)
+}
+
+// LogAttr will return the fields that identify the Object in log messages.
+func (obj *Object) LogAttr() slog.Attr {
+	return logging.Object(obj.Namespace, obj.Name, obj.Type, obj.ScannerId, obj.Cluster)
+}
+
+// MetricsObject will return the identification of the Object that is used by
+// the per object metrics.
+func (obj *Object) MetricsObject() metrics.Object {
+	return metrics.Object{
+		UID:       obj.UID,
+		Namespace: obj.Namespace,
+		Name:      obj.Name,
+		Type:      obj.Type,
+		Scanner:   obj.ScannerId,
+		Cluster:   obj.Cluster,
+	}
+}
+
+// Annotate will update the annotations of the Object with the given
+// annotations. Annotations with an empty value will be removed.
+func (obj *Object) Annotate(annotations map[string]string) error {
+	scanner, err := obj.getScanner()
+	if err != nil {
+		return err
+	}
+	return scanner.Annotate(obj, annotations)
+}
+
+// Snooze will suspend the schedule of the Object until given time. If nil is
+// given, the schedule will be resumed.
+func (obj *Object) Snooze(until *time.Time) error {
+	val := ""
+	if until != nil {
+		val = until.Format(time.RFC3339)
+	}
+	if err := obj.Annotate(map[string]string{SnoozeAnnotation: val}); err != nil {
+		return err
+	}
+	obj.SnoozeUntil = until
+	return nil
+}
+
+// SetSchedule will validate the given schedule description, in which multiple
+// schedules are separated with a semicolon, and will store it in the schedule
+// annotation of the Object. The ignore annotation is removed. If an empty
+// description is given, the schedule annotation and the schedule of the Object
+// are removed, and the schedule of the scanner will be applied again once the
+// object is rescanned.
+func (obj *Object) SetSchedule(text string) error {
+	sched, err := annotationToSchedule(text)
+	if err != nil {
+		return err
+	}
+	if err := obj.Annotate(map[string]string{ScheduleAnnotation: text, IgnoreAnnotation: ""}); err != nil {
+		return err
+	}
+	obj.Schedule = sched
+	return nil
+}
+
+// Ignore will set the ignore annotation of the Object, so its schedule will
+// no longer be applied. If false is given, the annotation is removed.
+func (obj *Object) Ignore(ignore bool) error {
+	val := ""
+	if ignore {
+		val = "true"
+	}
+	if err := obj.Annotate(map[string]string{IgnoreAnnotation: val}); err != nil {
+		return err
+	}
+	if ignore {
+		obj.Schedule = nil
+	}
+	return nil
+}
+
+// IsSnoozed will return true if the schedule of the Object is suspended at
+// given time.
+func (obj *Object) IsSnoozed(at time.Time) bool {
+	return obj.SnoozeUntil != nil && at.Before(*obj.SnoozeUntil)
+}
+
 // GetState will return the current number of replicas.
 func (obj *Object) GetState() (*int, error) {
 	scanner, err := obj.getScanner()
@@ -4,19 +4,23 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/joyrex2001/nightshift/internal/schedule"
 )
 
 type mock struct {
-	typ      string
-	cfg      Config
-	state    *Object
-	scale    *Object
-	replicas int
-	err      error
+	typ         string
+	cfg         Config
+	state       *Object
+	scale       *Object
+	replicas    int
+	annotations map[string]string
+	err         error
 }
 
 func (m *mock) SetConfig(c Config) {
@@ -36,12 +40,17 @@ func (m *mock) GetState(obj *Object) (int, error) {
 	return 0, nil
 }
 
-func (m *mock) Scale(obj *Object, state *int, r int) error {
+func (m *mock) Scale(obj *Object, state *int, r int, p *Profile) error {
 	m.scale = obj
 	m.replicas = r
 	return m.err
 }
 
+func (m *mock) Annotate(obj *Object, annotations map[string]string) error {
+	m.annotations = annotations
+	return m.err
+}
+
 func (m *mock) Watch(_stop chan bool) (chan Event, error) {
 	return make(chan Event), nil
 }
@@ -160,6 +169,119 @@ func TestSavetState(t *testing.T) {
 	}
 }
 
+func TestSnooze(t *testing.T) {
+	state := &mock{}
+	RegisterModule("mock", getFactory("mock", state))
+	until := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	obj := &Object{Type: "mock"}
+
+	if err := obj.Snooze(&until); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if state.annotations[SnoozeAnnotation] != "2026-10-18T23:00:00Z" {
+		t.Errorf("failed test - invalid snooze annotation: %v", state.annotations)
+	}
+	if !obj.IsSnoozed(until.Add(-time.Minute)) {
+		t.Errorf("failed test - expected object to be snoozed before deadline")
+	}
+	if obj.IsSnoozed(until) {
+		t.Errorf("failed test - expected object not to be snoozed at deadline")
+	}
+
+	if err := obj.Snooze(nil); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if val, ok := state.annotations[SnoozeAnnotation]; !ok || val != "" {
+		t.Errorf("failed test - expected snooze annotation to be removed: %v", state.annotations)
+	}
+	if obj.IsSnoozed(until.Add(-time.Minute)) {
+		t.Errorf("failed test - expected object not to be snoozed")
+	}
+
+	state.err = errors.New("some error")
+	if err := obj.Snooze(&until); err == nil {
+		t.Errorf("failed test - expected an error, but got none")
+	}
+	if obj.SnoozeUntil != nil {
+		t.Errorf("failed test - snooze set, while annotate failed")
+	}
+}
+
+func TestSetSchedule(t *testing.T) {
+	state := &mock{}
+	RegisterModule("mock", getFactory("mock", state))
+	tests := []struct {
+		text  string
+		count int
+		err   bool
+	}{
+		{"Mon-Fri 9:00 replicas=1;Mon-Fri 18:00 replicas=0", 2, false},
+		{"Sat 10:00 state=restore", 1, false},
+		{"Mon-Fri 25:00 replicas=1", 1, true},
+		{"", 0, false},
+		{"Someday 10:00 replicas=1", 0, true},
+	}
+	obj := &Object{Type: "mock"}
+	for i, tst := range tests {
+		state.annotations = nil
+		err := obj.SetSchedule(tst.text)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected error, but succeeded", i)
+		}
+		if tst.err {
+			if state.annotations != nil {
+				t.Errorf("failed test %d - annotated invalid schedule: %v", i, state.annotations)
+			}
+			continue
+		}
+		if val, ok := state.annotations[ScheduleAnnotation]; !ok || val != tst.text {
+			t.Errorf("failed test %d - invalid schedule annotation: %v", i, state.annotations)
+		}
+		if val, ok := state.annotations[IgnoreAnnotation]; !ok || val != "" {
+			t.Errorf("failed test %d - expected ignore annotation to be removed: %v", i, state.annotations)
+		}
+		if len(obj.Schedule) != tst.count {
+			t.Errorf("failed test %d - expected %d schedules, got %d", i, tst.count, len(obj.Schedule))
+		}
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	state := &mock{}
+	RegisterModule("mock", getFactory("mock", state))
+	sched, _ := schedule.New("Mon-Fri 9:00 replicas=1")
+	obj := &Object{Type: "mock", Schedule: []*schedule.Schedule{sched}}
+
+	state.err = errors.New("some error")
+	if err := obj.Ignore(true); err == nil {
+		t.Errorf("failed test - expected an error, but got none")
+	}
+	if len(obj.Schedule) != 1 {
+		t.Errorf("failed test - schedule removed, while annotate failed")
+	}
+
+	state.err = nil
+	if err := obj.Ignore(true); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if state.annotations[IgnoreAnnotation] != "true" {
+		t.Errorf("failed test - invalid ignore annotation: %v", state.annotations)
+	}
+	if obj.Schedule != nil {
+		t.Errorf("failed test - expected schedule to be removed")
+	}
+
+	if err := obj.Ignore(false); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if val, ok := state.annotations[IgnoreAnnotation]; !ok || val != "" {
+		t.Errorf("failed test - expected ignore annotation to be removed: %v", state.annotations)
+	}
+}
+
 func TestNewObjectForScanner(t *testing.T) {
 	scnr := &mock{typ: "mock"}
 	sched := []*schedule.Schedule{{}, {}}
@@ -183,7 +305,12 @@ func TestNewObjectForScanner(t *testing.T) {
 
 func TestUpdateWithMeta(t *testing.T) {
 	obj := &Object{}
-	meta := metav1.ObjectMeta{UID: "abc", Name: "something"}
+	meta := metav1.ObjectMeta{
+		UID:         "abc",
+		Name:        "something",
+		Labels:      map[string]string{"app": "shell"},
+		Annotations: map[string]string{SnoozeAnnotation: "2026-10-18T23:00Z"},
+	}
 	obj.updateWithMeta(meta)
 	if obj.UID != "abc" {
 		t.Errorf("failed test - expected UID 'abc', got: %s", obj.UID)
@@ -191,16 +318,28 @@ func TestUpdateWithMeta(t *testing.T) {
 	if obj.Name != "something" {
 		t.Errorf("failed test - expected UID 'something', got: %s", obj.Name)
 	}
+	if obj.Labels["app"] != "shell" {
+		t.Errorf("failed test - expected label app 'shell', got: %s", obj.Labels["app"])
+	}
+	if obj.Annotations[SnoozeAnnotation] != "2026-10-18T23:00Z" {
+		t.Errorf("failed test - expected snooze annotation '2026-10-18T23:00Z', got: %s", obj.Annotations[SnoozeAnnotation])
+	}
+	if obj.SnoozeUntil == nil || !obj.SnoozeUntil.Equal(time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("failed test - expected snooze until '2026-10-18T23:00Z', got: %v", obj.SnoozeUntil)
+	}
 }
 
 func TestCopy(t *testing.T) {
 	sched1, _ := schedule.New("Mon-Fri 10:00 replicas=2")
 	sched2, _ := schedule.New("Thu 10:00 state=save replicas=0")
+	until := time.Now()
 	tests := []*Object{
 		{UID: "123", Name: "Something"},
 		{UID: "123", Name: "Something", ScannerId: "somescanner"},
 		{UID: "123", Name: "Something", State: &State{Replicas: 1}},
 		{UID: "123", Name: "Something", Schedule: []*schedule.Schedule{sched1, sched2}},
+		{UID: "123", Name: "Something", SnoozeUntil: &until},
+		{UID: "123", Name: "Something", Requests: map[string]corev1.ResourceList{"app": {corev1.ResourceCPU: resource.MustParse("1")}}},
 	}
 	for i, obj := range tests {
 		new := obj.Copy()
@@ -221,6 +360,15 @@ func TestCopy(t *testing.T) {
 		if new.State != nil && new.State == obj.State {
 			t.Errorf("failed test %d - object State attribute is identical (%p,%p)", i, new.State, obj.State)
 		}
+		if new.SnoozeUntil != nil && new.SnoozeUntil == obj.SnoozeUntil {
+			t.Errorf("failed test %d - object SnoozeUntil attribute is identical (%p,%p)", i, new.SnoozeUntil, obj.SnoozeUntil)
+		}
+		if !reflect.DeepEqual(obj.Requests, new.Requests) {
+			t.Errorf("failed test %d - failed copying requests (%v vs %v)", i, obj.Requests, new.Requests)
+		}
+		if obj.Requests != nil && reflect.ValueOf(obj.Requests).Pointer() == reflect.ValueOf(new.Requests).Pointer() {
+			t.Errorf("failed test %d - object Requests attribute is identical", i)
+		}
 		if len(obj.Schedule) != len(new.Schedule) {
 			t.Errorf("failed test %d - failed copying schedule length is not identical", i)
 		}
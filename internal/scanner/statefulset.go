@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/golang/glog"
 	v1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	appsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
 	"k8s.io/client-go/rest"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
 )
 
 // StatefulSetScanner is the object that implements scanning of OpenShift/k8s
@@ -25,7 +27,7 @@ func init() {
 
 // NewStatefulSetScanner will instantiate a new StatefulSetScanner object.
 func NewStatefulSetScanner() (Scanner, error) {
-	kubernetes, err := getKubernetes()
+	kubernetes, err := GetKubernetes()
 	if err != nil {
 		return nil, fmt.Errorf("failed instantiating k8s client: %s", err)
 	}
@@ -34,9 +36,11 @@ func NewStatefulSetScanner() (Scanner, error) {
 	}, nil
 }
 
-// SetConfig will set the generic configuration for this scanner.
+// SetConfig will set the generic configuration for this scanner, and will
+// connect to the configured cluster.
 func (s *StatefulSetScanner) SetConfig(cfg Config) {
 	s.config = cfg
+	s.kubernetes = getClusterKubernetes(cfg.Cluster, s.kubernetes)
 }
 
 // GetConfig will return the config applied for this scanner.
@@ -54,13 +58,20 @@ func (s *StatefulSetScanner) GetObjects() ([]*Object, error) {
 	return s.getObjects(rcs)
 }
 
-// Scale will scale a given object to given amount of replicas.
-func (s *StatefulSetScanner) Scale(obj *Object, state *int, replicas int) error {
-	glog.Infof("Scaling %s/%s to %d replicas", obj.Namespace, obj.Name, replicas)
+// Scale will scale a given object to given amount of replicas, and will apply
+// the given resources profile, if any.
+func (s *StatefulSetScanner) Scale(obj *Object, state *int, replicas int, profile *Profile) error {
+	logger.Info("Scaling", obj.LogAttr(), "replicas", replicas)
 	ss, err := s.getStatefulSet(obj)
 	if err != nil {
 		return fmt.Errorf("GetScale failed with: %s", err)
 	}
+	if profile != nil {
+		ss.ObjectMeta, err = updateResources(ss.ObjectMeta, &ss.Spec.Template.Spec, int(*ss.Spec.Replicas), profile)
+		if err != nil {
+			return err
+		}
+	}
 	repl := int32(replicas)
 	ss.Spec.Replicas = &repl
 	if state != nil {
@@ -71,6 +82,20 @@ func (s *StatefulSetScanner) Scale(obj *Object, state *int, replicas int) error
 	return err
 }
 
+// Annotate will update the annotations of a given object.
+func (s *StatefulSetScanner) Annotate(obj *Object, annotations map[string]string) error {
+	patch, err := annotationPatch(annotations)
+	if err != nil {
+		return err
+	}
+	apps, err := appsv1.NewForConfig(s.kubernetes)
+	if err != nil {
+		return err
+	}
+	_, err = apps.StatefulSets(obj.Namespace).Patch(context.Background(), obj.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
 // GetState will save the current number of replicas.
 func (s *StatefulSetScanner) GetState(obj *Object) (int, error) {
 	ss, err := s.getStatefulSet(obj)
@@ -121,7 +146,7 @@ func (s *StatefulSetScanner) getObjects(rcs *v1.StatefulSetList) ([]*Object, err
 // Watch will return a channel on which Event objects will be published that
 // describe change events in the cluster.
 func (s *StatefulSetScanner) Watch(_stop chan bool) (chan Event, error) {
-	return watcher(_stop, s.getWatcher, s.unmarshall)
+	return watcher(_stop, s.config, s.getWatcher, s.unmarshall)
 }
 
 // getWatcher will return a watcher for DeploymentConfigs
@@ -143,8 +168,9 @@ func (s *StatefulSetScanner) unmarshall(kobj interface{}) (*Object, error) {
 	}
 	obj := NewObjectForScanner(s)
 	if err := obj.updateWithMeta(m.ObjectMeta); err != nil {
-		glog.Error(err)
+		logger.Error("Error reading object", obj.LogAttr(), logging.Err(err))
 	}
 	obj.Replicas = int(*m.Spec.Replicas)
+	obj.Requests = containerRequests(&m.Spec.Template.Spec)
 	return obj, nil
 }
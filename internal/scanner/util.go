@@ -1,12 +1,13 @@
 package scanner
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/spf13/viper"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,6 +15,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/joyrex2001/nightshift/internal/logging"
 	"github.com/joyrex2001/nightshift/internal/metrics"
 	"github.com/joyrex2001/nightshift/internal/schedule"
 )
@@ -25,10 +27,14 @@ const (
 	IgnoreAnnotation string = "joyrex2001.com/nightshift.ignore"
 	// SaveStateAnnotation is the annotation used to store the state.
 	SaveStateAnnotation string = "joyrex2001.com/nightshift.savestate"
+	// SnoozeAnnotation is the annotation used to suspend the schedule of a
+	// resource until a given time.
+	SnoozeAnnotation string = "joyrex2001.com/nightshift.snooze-until"
 )
 
-// getKubernetes will return a kubernetes config object.
-func getKubernetes() (*rest.Config, error) {
+// GetKubernetes will return a kubernetes config object for the configured
+// kubeconfig, or the in-cluster config if no kubeconfig is available.
+func GetKubernetes() (*rest.Config, error) {
 	kubeconfig := viper.GetString("openshift.kubeconfig")
 	if kubeconfig != "" {
 		config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
@@ -40,14 +46,22 @@ func getKubernetes() (*rest.Config, error) {
 }
 
 // getState will return a State object based on the value of the State
-// annotation on the deployment config. If no annotation exist, it will return
-// nil.
+// annotation on the deployment config. The annotation either contains the
+// number of replicas, or a json document when container resources are saved
+// as well. If no annotation exist, it will return nil.
 func getState(annotations map[string]string) (*State, error) {
 	repls, ok := annotations[SaveStateAnnotation]
 	if !ok {
-		glog.V(5).Info("no previous state available")
+		logger.Log(context.Background(), logging.LevelTrace, "No previous state available")
 		return nil, nil
 	}
+	if strings.HasPrefix(strings.TrimSpace(repls), "{") {
+		state := &State{}
+		if err := json.Unmarshal([]byte(repls), state); err != nil {
+			return nil, err
+		}
+		return state, nil
+	}
 	repl, err := strconv.Atoi(repls)
 	if err != nil {
 		return nil, err
@@ -56,14 +70,82 @@ func getState(annotations map[string]string) (*State, error) {
 }
 
 // updateState will update a kubernetes ObjectMeta struct by either adding or
-// updating the savestate annotation with the given amount of replicas. It
-// will return the updated struct.
+// updating the savestate annotation with the given amount of replicas. Saved
+// container resources are preserved. It will return the updated struct.
 func updateState(meta metav1.ObjectMeta, repl int) metav1.ObjectMeta {
+	state, err := getState(meta.Annotations)
+	if err != nil || state == nil {
+		state = &State{}
+	}
+	state.Replicas = repl
+	meta, err = setState(meta, state)
+	if err != nil {
+		logger.Error("Error saving state", logging.KeyNamespace, meta.Namespace, logging.KeyObject, meta.Name, logging.Err(err))
+	}
+	return meta
+}
+
+// setState will update a kubernetes ObjectMeta struct by either adding or
+// updating the savestate annotation with the given state. The state is stored
+// as a plain number of replicas, unless it contains container resources. It
+// will return the updated struct.
+func setState(meta metav1.ObjectMeta, state *State) (metav1.ObjectMeta, error) {
 	if meta.Annotations == nil {
 		meta.Annotations = map[string]string{}
 	}
-	meta.Annotations[SaveStateAnnotation] = strconv.Itoa(repl)
-	return meta
+	if len(state.Resources) == 0 {
+		meta.Annotations[SaveStateAnnotation] = strconv.Itoa(state.Replicas)
+		return meta, nil
+	}
+	val, err := json.Marshal(state)
+	if err != nil {
+		return meta, err
+	}
+	meta.Annotations[SaveStateAnnotation] = string(val)
+	return meta, nil
+}
+
+// getSnooze will return the time until which the schedule is suspended, based
+// on the value of the snooze annotation. If no annotation exist, it will return
+// nil.
+func getSnooze(annotations map[string]string) (*time.Time, error) {
+	val := strings.TrimSpace(annotations[SnoozeAnnotation])
+	if val == "" {
+		return nil, nil
+	}
+	until, err := ParseTime(val)
+	if err != nil {
+		return nil, err
+	}
+	return &until, nil
+}
+
+// ParseTime will parse given time string, which should be in RFC3339 format,
+// optionally without seconds (e.g. 2026-10-18T23:00Z).
+func ParseTime(val string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s', expected RFC3339", val)
+}
+
+// annotationPatch will return a json merge patch that will update the
+// annotations with the given annotations. Annotations with an empty value
+// will be removed.
+func annotationPatch(annotations map[string]string) ([]byte, error) {
+	anns := map[string]interface{}{}
+	for k, v := range annotations {
+		if v == "" {
+			anns[k] = nil
+		} else {
+			anns[k] = v
+		}
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": anns},
+	})
 }
 
 // getSchedule will return a list of schedules, taken the annotations and
@@ -107,34 +189,39 @@ type unmarshaller func(interface{}) (*Object, error)
 // with the provided connect function. If the initial connection fails it will
 // return an error, otherwise it will return a channel on which the scanner
 // events will be published. It will stop watching when the given _stop channel
-// will contain a message.
-func watcher(_stop chan bool, connect connector, unmarshall unmarshaller) (chan Event, error) {
+// will contain a message. The connection state is exported as metric for the
+// scanner with given config.
+func watcher(_stop chan bool, cfg Config, connect connector, unmarshall unmarshaller) (chan Event, error) {
 	watcher, err := connect()
 	if err != nil {
 		return nil, err
 	}
+	metrics.SetWatchConnected(cfg.Namespace, cfg.Type, cfg.Id, cfg.Cluster, true)
 
 	out := make(chan Event, 50)
 	go func() {
 		for {
 			select {
 			case evt := <-watcher.ResultChan():
-				glog.V(5).Infof("Received event: %v", evt)
+				logger.Log(context.Background(), logging.LevelTrace, "Received event", cfg.LogAttr(), "event", evt.Type)
 				if evt.Type == watch.Error {
-					glog.Errorf("Error watching: %v", evt)
+					logger.Error("Error watching", cfg.LogAttr(), "event", evt.Object)
 					metrics.Increase("watch_event_error")
 				}
 				if evt.Object == nil {
-					watcher = reconnectWatcher(connect)
+					metrics.SetWatchConnected(cfg.Namespace, cfg.Type, cfg.Id, cfg.Cluster, false)
+					watcher = reconnectWatcher(cfg, connect)
+					metrics.SetWatchConnected(cfg.Namespace, cfg.Type, cfg.Id, cfg.Cluster, true)
 				} else {
 					obj, err := unmarshall(evt.Object)
 					if err != nil {
-						glog.Errorf("Error watching: %v", evt)
+						logger.Error("Error watching", cfg.LogAttr(), logging.Err(err))
 					} else {
 						publishWatchEvent(out, obj, evt)
 					}
 				}
 			case <-_stop:
+				metrics.DeleteWatch(cfg.Namespace, cfg.Type, cfg.Id, cfg.Cluster)
 				return
 			}
 		}
@@ -162,14 +249,14 @@ func publishWatchEvent(out chan Event, obj *Object, evt watch.Event) {
 // reconnectWatcher will reconnect a disconnected watcher, and will retry
 // connecting with given connect method. It will apply an exponential backoff
 // if it fails.
-func reconnectWatcher(connect connector) watch.Interface {
+func reconnectWatcher(cfg Config, connect connector) watch.Interface {
 	backoff := time.Second
 	for {
-		glog.V(4).Infof("Attempting to reconnect scanner...")
+		logger.Debug("Attempting to reconnect scanner", cfg.LogAttr())
 		metrics.Increase("watch_retries")
 		watcher, err := connect()
 		if err == nil {
-			glog.V(4).Infof("Reconnected scanner...")
+			logger.Debug("Reconnected scanner", cfg.LogAttr())
 			return watcher
 		}
 		time.Sleep(backoff)
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 
@@ -146,6 +147,20 @@ func TestGetState(t *testing.T) {
 			err:   true,
 			state: nil,
 		},
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.savestate": `{"replicas":3,"resources":{"app":{}}}`,
+			},
+			err:   false,
+			state: &State{Replicas: 3, Resources: map[string]corev1.ResourceRequirements{"app": {}}},
+		},
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.savestate": `{"replicas":`,
+			},
+			err:   true,
+			state: nil,
+		},
 		{
 			data:  map[string]string{},
 			err:   false,
@@ -166,6 +181,78 @@ func TestGetState(t *testing.T) {
 	}
 }
 
+func TestGetSnooze(t *testing.T) {
+	until := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	tests := []struct {
+		data  map[string]string
+		until *time.Time
+		err   bool
+	}{
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.snooze-until": `2026-10-18T23:00:00Z`,
+			},
+			err:   false,
+			until: &until,
+		},
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.snooze-until": `2026-10-18T23:00Z`,
+			},
+			err:   false,
+			until: &until,
+		},
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.snooze-until": `2026-10-19T01:00+02:00`,
+			},
+			err:   false,
+			until: &until,
+		},
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.snooze-until": `tomorrow`,
+			},
+			err:   true,
+			until: nil,
+		},
+		{
+			data:  map[string]string{},
+			err:   false,
+			until: nil,
+		},
+	}
+	for i, tst := range tests {
+		res, err := getSnooze(tst.data)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if tst.err {
+			continue
+		}
+		if (res == nil) != (tst.until == nil) || (res != nil && !res.Equal(*tst.until)) {
+			t.Errorf("failed test %d - expected: %v, got %v", i, tst.until, res)
+		}
+	}
+}
+
+func TestAnnotationPatch(t *testing.T) {
+	patch, err := annotationPatch(map[string]string{
+		"joyrex2001.com/nightshift.snooze-until": "2026-10-18T23:00:00Z",
+		"joyrex2001.com/nightshift.ignore":       "",
+	})
+	if err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	exp := `{"metadata":{"annotations":{"joyrex2001.com/nightshift.ignore":null,"joyrex2001.com/nightshift.snooze-until":"2026-10-18T23:00:00Z"}}}`
+	if string(patch) != exp {
+		t.Errorf("failed test - expected: %s, got %s", exp, patch)
+	}
+}
+
 func TestUpdateState(t *testing.T) {
 	meta := metav1.ObjectMeta{}
 	meta = updateState(meta, 10)
@@ -178,6 +265,12 @@ func TestUpdateState(t *testing.T) {
 	if st != "5" {
 		t.Errorf("failed test - expected: 5, got %s", st)
 	}
+	meta.Annotations["joyrex2001.com/nightshift.savestate"] = `{"replicas":3,"resources":{"app":{}}}`
+	meta = updateState(meta, 2)
+	st = meta.Annotations["joyrex2001.com/nightshift.savestate"]
+	if st != `{"replicas":2,"resources":{"app":{}}}` {
+		t.Errorf("failed test - expected: saved resources, got %s", st)
+	}
 }
 
 func TestPublishWatchEvent(t *testing.T) {
@@ -249,14 +342,14 @@ func TestWatcher(t *testing.T) {
 
 	// test error connecting
 	doerr = fmt.Errorf("oops")
-	_, err := watcher(stop, connect, unmarsh)
+	_, err := watcher(stop, Config{}, connect, unmarsh)
 	if err == nil {
 		t.Errorf("failed test watcher - expected error but got none")
 	}
 
 	// check successful connect
 	doerr = nil
-	out, err := watcher(stop, connect, unmarsh)
+	out, err := watcher(stop, Config{}, connect, unmarsh)
 	if err != nil {
 		t.Errorf("failed test watcher - unexpected error: %s", err)
 	}
@@ -7,15 +7,95 @@ import (
 )
 
 // GetReplicas will return the number of replicas that should be applied
-// according to the schedule.
+// according to the schedule. It will return an error if the replicas are
+// relative, in which case ResolveReplicas should be used instead.
 func (s *Schedule) GetReplicas() (int, error) {
 	r, ok := s.settings["replicas"]
 	if !ok {
 		return 0, fmt.Errorf("replicas definition not found in schedule")
 	}
+	if s.IsRelative() {
+		return 0, fmt.Errorf("relative replicas %s can't be resolved without state", r)
+	}
 	return strconv.Atoi(r)
 }
 
+// ResolveReplicas will return the number of replicas that should be applied
+// according to the schedule, for given current number of replicas and saved
+// state. Next to absolute numbers, the replicas can be relative to the current
+// number of replicas (e.g. -2), a percentage of the saved state (e.g. 50%,
+// rounded up), or an expression (e.g. max(1,state/2)). If no state is given,
+// the current number of replicas is used as state. The result is clamped with
+// the min and max settings, and will never be negative.
+func (s *Schedule) ResolveReplicas(current int, state *int) (int, error) {
+	r, ok := s.settings["replicas"]
+	if !ok {
+		return 0, fmt.Errorf("replicas definition not found in schedule")
+	}
+	st := current
+	if state != nil {
+		st = *state
+	}
+	repl, err := evalReplicas(r, current, st)
+	if err != nil {
+		return 0, err
+	}
+	for _, lim := range []string{"min", "max"} {
+		v, ok := s.settings[lim]
+		if !ok {
+			continue
+		}
+		l, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s replicas %s", lim, v)
+		}
+		if (lim == "min" && repl < l) || (lim == "max" && repl > l) {
+			repl = l
+		}
+	}
+	if repl < 0 {
+		repl = 0
+	}
+	return repl, nil
+}
+
+// validateReplicas will check if the replicas, min and max settings of the
+// schedule are valid, by resolving the replicas for some sample values of the
+// current replicas and state. An expression is valid if it can be resolved
+// for any of these (e.g. replicas/(state-1) can't be resolved for state 1).
+func (s *Schedule) validateReplicas() error {
+	for _, lim := range []string{"min", "max"} {
+		if v, ok := s.settings[lim]; ok {
+			if _, err := strconv.Atoi(v); err != nil {
+				return fmt.Errorf("invalid %s replicas %s", lim, v)
+			}
+		}
+	}
+	if !s.HasReplicas() {
+		return nil
+	}
+	var err error
+	for _, sample := range [][2]int{{1, 1}, {3, 2}} {
+		state := sample[1]
+		if _, err = s.ResolveReplicas(sample[0], &state); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// IsRelative checks if the replicas setting of the schedule is not an
+// absolute number, but depends on the current number of replicas, or the
+// saved state.
+func (s *Schedule) IsRelative() bool {
+	r, ok := s.settings["replicas"]
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(r)
+	return err != nil || isRelative(r)
+}
+
 // HasReplicas checks if the given schedule has a replicas settings that
 // should be applied.
 func (s *Schedule) HasReplicas() bool {
@@ -51,3 +131,42 @@ func (s *Schedule) GetTriggers() []string {
 	}
 	return trgs
 }
+
+// GetIdle will return the PromQL query of the idle condition that should hold
+// before the schedule is applied. It will return an empty string if no idle
+// condition is configured.
+func (s *Schedule) GetIdle() string {
+	return strings.TrimSpace(s.settings["idle"])
+}
+
+// HasIdle checks if the given schedule has an idle condition that should hold
+// before the schedule is applied.
+func (s *Schedule) HasIdle() bool {
+	return s.GetIdle() != ""
+}
+
+// GetWhen will return the expression that should evaluate to true for the
+// schedule to be applied. It will return an empty string if no expression is
+// configured.
+func (s *Schedule) GetWhen() string {
+	return strings.TrimSpace(s.settings["when"])
+}
+
+// HasWhen checks if the given schedule has an expression that should evaluate
+// to true for the schedule to be applied.
+func (s *Schedule) HasWhen() bool {
+	return s.GetWhen() != ""
+}
+
+// GetResources will return the name of the resources profile that should be
+// applied to the containers according to the schedule. It will return an
+// empty string if no resources profile is configured.
+func (s *Schedule) GetResources() string {
+	return strings.ToLower(strings.TrimSpace(s.settings["resources"]))
+}
+
+// HasResources checks if the given schedule has a resources profile that
+// should be applied.
+func (s *Schedule) HasResources() bool {
+	return s.GetResources() != ""
+}
@@ -36,6 +36,15 @@ func TestGetReplicas(t *testing.T) {
 				settings: map[string]string{},
 			},
 		},
+		{
+			replicas: 0,
+			err:      true,
+			sched: &Schedule{
+				settings: map[string]string{
+					"replicas": "-1",
+				},
+			},
+		},
 	}
 	for i, tst := range tests {
 		r, err := tst.sched.GetReplicas()
@@ -51,6 +60,47 @@ func TestGetReplicas(t *testing.T) {
 	}
 }
 
+func TestResolveReplicas(t *testing.T) {
+	state := 4
+	tests := []struct {
+		settings map[string]string
+		current  int
+		state    *int
+		replicas int
+		relative bool
+		err      bool
+	}{
+		{settings: map[string]string{"replicas": "2"}, current: 3, replicas: 2},
+		{settings: map[string]string{"replicas": "-2"}, current: 3, replicas: 1, relative: true},
+		{settings: map[string]string{"replicas": "-2"}, current: 1, replicas: 0, relative: true},
+		{settings: map[string]string{"replicas": "-2", "min": "1"}, current: 2, replicas: 1, relative: true},
+		{settings: map[string]string{"replicas": "+2", "max": "4"}, current: 3, replicas: 4, relative: true},
+		{settings: map[string]string{"replicas": "50%"}, current: 0, state: &state, replicas: 2, relative: true},
+		{settings: map[string]string{"replicas": "50%"}, current: 6, replicas: 3, relative: true},
+		{settings: map[string]string{"replicas": "max(1,state/2)"}, current: 0, state: &state, replicas: 2, relative: true},
+		{settings: map[string]string{"replicas": "10", "max": "5", "min": "1"}, current: 0, replicas: 5},
+		{settings: map[string]string{"replicas": "1", "min": "x"}, err: true},
+		{settings: map[string]string{"replicas": "state*"}, err: true, relative: true},
+		{settings: map[string]string{}, err: true},
+	}
+	for i, tst := range tests {
+		sched := &Schedule{settings: tst.settings}
+		r, err := sched.ResolveReplicas(tst.current, tst.state)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if r != tst.replicas {
+			t.Errorf("failed test %d; expected %d replicas, got %d", i, tst.replicas, r)
+		}
+		if rel := sched.IsRelative(); rel != tst.relative {
+			t.Errorf("failed test %d; expected relative %t, got %t", i, tst.relative, rel)
+		}
+	}
+}
+
 func TestHasReplicas(t *testing.T) {
 	tests := []struct {
 		res   bool
@@ -217,3 +267,111 @@ func TestGetTriggers(t *testing.T) {
 		}
 	}
 }
+
+func TestGetIdle(t *testing.T) {
+	tests := []struct {
+		idle  string
+		has   bool
+		sched *Schedule
+	}{
+		{
+			idle: "",
+			has:  false,
+			sched: &Schedule{
+				settings: map[string]string{},
+			},
+		},
+		{
+			idle: "",
+			has:  false,
+			sched: &Schedule{
+				settings: map[string]string{
+					"idle": " ",
+				},
+			},
+		},
+		{
+			idle: "sum(rate(http_requests_total[2h])) == 0",
+			has:  true,
+			sched: &Schedule{
+				settings: map[string]string{
+					"idle": "sum(rate(http_requests_total[2h])) == 0",
+				},
+			},
+		},
+	}
+	for i, tst := range tests {
+		if idle := tst.sched.GetIdle(); idle != tst.idle {
+			t.Errorf("failed test %d; expected %s, got %s", i, tst.idle, idle)
+		}
+		if has := tst.sched.HasIdle(); has != tst.has {
+			t.Errorf("failed test %d; expected %t, got %t", i, tst.has, has)
+		}
+	}
+}
+
+func TestGetWhen(t *testing.T) {
+	tests := []struct {
+		when  string
+		has   bool
+		sched *Schedule
+	}{
+		{
+			when: "",
+			has:  false,
+			sched: &Schedule{
+				settings: map[string]string{},
+			},
+		},
+		{
+			when: "labels.tier != 'critical'",
+			has:  true,
+			sched: &Schedule{
+				settings: map[string]string{
+					"when": " labels.tier != 'critical' ",
+				},
+			},
+		},
+	}
+	for i, tst := range tests {
+		if when := tst.sched.GetWhen(); when != tst.when {
+			t.Errorf("failed test %d; expected %s, got %s", i, tst.when, when)
+		}
+		if has := tst.sched.HasWhen(); has != tst.has {
+			t.Errorf("failed test %d; expected %t, got %t", i, tst.has, has)
+		}
+	}
+}
+
+func TestGetResources(t *testing.T) {
+	tests := []struct {
+		resources string
+		has       bool
+		sched     *Schedule
+	}{
+		{
+			resources: "",
+			has:       false,
+			sched: &Schedule{
+				settings: map[string]string{},
+			},
+		},
+		{
+			resources: "small",
+			has:       true,
+			sched: &Schedule{
+				settings: map[string]string{
+					"resources": "Small",
+				},
+			},
+		},
+	}
+	for i, tst := range tests {
+		if res := tst.sched.GetResources(); res != tst.resources {
+			t.Errorf("failed test %d; expected %s, got %s", i, tst.resources, res)
+		}
+		if has := tst.sched.HasResources(); has != tst.has {
+			t.Errorf("failed test %d; expected %t, got %t", i, tst.has, has)
+		}
+	}
+}
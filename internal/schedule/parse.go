@@ -8,18 +8,21 @@ import (
 )
 
 // parse will parse the given schedule description and store its equivalent
-// attributes inside the structure.
+// attributes inside the structure. Setting values can be quoted with single or
+// double quotes, in which case these are taken literally, including spaces.
 func (s *Schedule) parse(text string) error {
 	var err error
 
-	text = trimSpaces(text)
-	text = strings.Replace(text, ", ", ",", -1)
-	text = strings.Replace(text, "- ", "-", -1)
-	text = strings.ToLower(text)
+	text, err = normalize(text)
+	if err != nil {
+		return err
+	}
 	s.Description = text
-	text = strings.Replace(text, ":", " ", -1)
 
-	flds := strings.Split(text, " ")
+	flds := splitFields(text)
+	if len(flds) < 3 {
+		return fmt.Errorf("invalid schedule %s", text)
+	}
 	if err := s.parseWeekday(flds[0]); err != nil {
 		return err
 	}
@@ -35,14 +38,114 @@ func (s *Schedule) parse(text string) error {
 	}
 
 	for _, kv := range flds[3:] {
-		kvf := strings.Split(kv, "=")
-		if len(kvf) != 2 {
-			return fmt.Errorf("invalid setting %s", kv)
+		k, v, err := parseSetting(kv)
+		if err != nil {
+			return err
 		}
-		s.settings[kvf[0]] = kvf[1]
+		s.settings[k] = v
 	}
 
-	return nil
+	return s.validateReplicas()
+}
+
+// parseSetting will parse a key=value setting, and will return the key and
+// the (unquoted) value.
+func parseSetting(kv string) (string, string, error) {
+	kvf := strings.SplitN(kv, "=", 2)
+	if len(kvf) != 2 || strings.ContainsAny(kvf[0], `"'`) {
+		return "", "", fmt.Errorf("invalid setting %s", kv)
+	}
+	val := kvf[1]
+	if isQuoted(val) {
+		return kvf[0], val[1 : len(val)-1], nil
+	}
+	if strings.ContainsAny(val, `="'`) {
+		return "", "", fmt.Errorf("invalid setting %s", kv)
+	}
+	return kvf[0], val, nil
+}
+
+// isQuoted will return true if the given text is enclosed in single or
+// double quotes.
+func isQuoted(text string) bool {
+	return len(text) >= 2 && (text[0] == '"' || text[0] == '\'') && text[len(text)-1] == text[0]
+}
+
+// normalize will lowercase the given schedule description, and will remove
+// redundant spaces. Quoted parts of the description are left untouched.
+func normalize(text string) (string, error) {
+	segs, err := splitQuoted(strings.TrimSpace(text))
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < len(segs); i += 2 {
+		seg := segs[i]
+		lead := i > 0 && strings.HasPrefix(seg, " ")
+		trail := i < len(segs)-1 && strings.HasSuffix(seg, " ")
+		seg = trimSpaces(seg)
+		seg = strings.Replace(seg, ", ", ",", -1)
+		seg = strings.Replace(seg, "- ", "-", -1)
+		seg = strings.ToLower(seg)
+		if lead {
+			seg = " " + seg
+		}
+		if trail && seg != " " {
+			seg = seg + " "
+		}
+		segs[i] = seg
+	}
+	return strings.Join(segs, ""), nil
+}
+
+// splitQuoted will split the given text in segments, alternating between
+// unquoted and quoted text; quoted segments include their quotes. It will
+// return an error if a quote is not terminated.
+func splitQuoted(text string) ([]string, error) {
+	segs := []string{}
+	start := 0
+	var quote rune
+	for i, c := range text {
+		switch {
+		case quote == 0 && (c == '"' || c == '\''):
+			segs = append(segs, text[start:i])
+			start = i
+			quote = c
+		case quote != 0 && c == quote:
+			segs = append(segs, text[start:i+1])
+			start = i + 1
+			quote = 0
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %s", text)
+	}
+	return append(segs, text[start:]), nil
+}
+
+// splitFields will split the given normalized schedule description into
+// fields, separated by spaces or colons, ignoring separators within quotes.
+func splitFields(text string) []string {
+	flds := []string{}
+	cur := ""
+	var quote rune
+	for _, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			cur += string(c)
+		case c == '"' || c == '\'':
+			quote = c
+			cur += string(c)
+		case c == ' ' || c == ':':
+			flds = append(flds, cur)
+			cur = ""
+		default:
+			cur += string(c)
+		}
+	}
+	return append(flds, cur)
 }
 
 // parseWeekday will parse the weekday definition of the schedule description.
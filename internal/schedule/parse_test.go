@@ -228,6 +228,58 @@ func TestParse(t *testing.T) {
 				Description: "mon 18:00 replicas=0 trigger=refreshdb,,build,",
 			},
 		},
+		{
+			data: `Mon  18:00 Replicas=0 idle="sum(rate(http_requests_total{namespace='Dev'}[2h]))  == 0"`,
+			err:  false,
+			sched: &Schedule{
+				hour: 18,
+				min:  00,
+				dayOfWeek: map[time.Weekday]bool{
+					1: true,
+				},
+				settings: map[string]string{
+					"replicas": "0",
+					"idle":     "sum(rate(http_requests_total{namespace='Dev'}[2h]))  == 0",
+				},
+				Description: `mon 18:00 replicas=0 idle="sum(rate(http_requests_total{namespace='Dev'}[2h]))  == 0"`,
+			},
+		},
+		{
+			data: `Mon 18:00 idle='up == 0'  Replicas=0`,
+			err:  false,
+			sched: &Schedule{
+				hour: 18,
+				min:  00,
+				dayOfWeek: map[time.Weekday]bool{
+					1: true,
+				},
+				settings: map[string]string{
+					"replicas": "0",
+					"idle":     "up == 0",
+				},
+				Description: `mon 18:00 idle='up == 0' replicas=0`,
+			},
+		},
+		{
+			data:  `Mon 18:00 replicas=0 idle="up == 0`,
+			err:   true,
+			sched: &Schedule{},
+		},
+		{
+			data:  `Mon 18:00 replicas=0 idle="up"x`,
+			err:   true,
+			sched: &Schedule{},
+		},
+		{
+			data:  `Mon 18:00 "replicas"=0`,
+			err:   true,
+			sched: &Schedule{},
+		},
+		{
+			data:  `Mon`,
+			err:   true,
+			sched: &Schedule{},
+		},
 	}
 	for i, tst := range tests {
 		s := &Schedule{
@@ -0,0 +1,218 @@
+package schedule
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// exprParser is a recursive descent parser, that will evaluate integer
+// replica expressions, supporting the +, -, * and / operators, parentheses,
+// the min() and max() functions, and the state and replicas variables.
+type exprParser struct {
+	tokens []string
+	pos    int
+	vars   map[string]int
+}
+
+// evalReplicas will evaluate the given replicas setting for given current
+// number of replicas and saved state. The setting can be an absolute number,
+// a number relative to the current number of replicas (e.g. -2 or +1), a
+// percentage of the saved state (e.g. 50%), or an expression (e.g.
+// max(1,state/2)).
+func evalReplicas(val string, current, state int) (int, error) {
+	if isRelative(val) {
+		r, _ := strconv.Atoi(val)
+		return current + r, nil
+	}
+	if r, err := strconv.Atoi(val); err == nil {
+		return r, nil
+	}
+	if strings.HasSuffix(val, "%") {
+		p, err := strconv.Atoi(strings.TrimSuffix(val, "%"))
+		if err != nil || p < 0 {
+			return 0, fmt.Errorf("invalid percentage replicas %s", val)
+		}
+		return int(math.Ceil(float64(state*p) / 100)), nil
+	}
+	return evalExpr(val, map[string]int{"state": state, "replicas": current})
+}
+
+// isRelative will return true if given replicas setting is a number relative
+// to the current number of replicas.
+func isRelative(val string) bool {
+	if !strings.HasPrefix(val, "+") && !strings.HasPrefix(val, "-") {
+		return false
+	}
+	_, err := strconv.Atoi(val)
+	return err == nil
+}
+
+// evalExpr will evaluate the given integer expression with given variables.
+func evalExpr(expr string, vars map[string]int) (int, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return 0, err
+	}
+	p := &exprParser{tokens: tokens, vars: vars}
+	val, err := p.parseSum()
+	if err != nil {
+		return 0, fmt.Errorf("invalid replicas expression %s; %s", expr, err)
+	}
+	if p.pos != len(p.tokens) {
+		return 0, fmt.Errorf("invalid replicas expression %s; unexpected %s", expr, p.tokens[p.pos])
+	}
+	return val, nil
+}
+
+// tokenize will split given expression into numbers, identifiers and
+// operators.
+func tokenize(expr string) ([]string, error) {
+	tokens := []string{}
+	rs := []rune(expr)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("+-*/(),", c):
+			tokens = append(tokens, string(c))
+			i++
+		case unicode.IsDigit(c) || unicode.IsLetter(c):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || unicode.IsLetter(rs[j])) {
+				j++
+			}
+			tokens = append(tokens, string(rs[i:j]))
+			i = j
+		default:
+			return nil, fmt.Errorf("invalid character '%c' in replicas expression %s", c, expr)
+		}
+	}
+	return tokens, nil
+}
+
+// peek will return the current token, or an empty string if all tokens have
+// been processed.
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// expect will consume the current token, and will return an error if it
+// doesn't match the given token.
+func (p *exprParser) expect(tok string) error {
+	if p.peek() != tok {
+		return fmt.Errorf("expected '%s'", tok)
+	}
+	p.pos++
+	return nil
+}
+
+// parseSum will parse terms separated by + and - operators.
+func (p *exprParser) parseSum() (int, error) {
+	val, err := p.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.peek()
+		p.pos++
+		r, err := p.parseProduct()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			val += r
+		} else {
+			val -= r
+		}
+	}
+	return val, nil
+}
+
+// parseProduct will parse factors separated by * and / operators.
+func (p *exprParser) parseProduct() (int, error) {
+	val, err := p.parseFactor()
+	if err != nil {
+		return 0, err
+	}
+	for p.peek() == "*" || p.peek() == "/" {
+		op := p.peek()
+		p.pos++
+		r, err := p.parseFactor()
+		if err != nil {
+			return 0, err
+		}
+		if op == "*" {
+			val *= r
+		} else if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		} else {
+			val /= r
+		}
+	}
+	return val, nil
+}
+
+// parseFactor will parse a number, variable, function call, negation or
+// parenthesized expression.
+func (p *exprParser) parseFactor() (int, error) {
+	tok := p.peek()
+	p.pos++
+	switch {
+	case tok == "":
+		return 0, fmt.Errorf("unexpected end of expression")
+	case tok == "-":
+		val, err := p.parseFactor()
+		return -val, err
+	case tok == "(":
+		val, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		return val, p.expect(")")
+	case tok == "min" || tok == "max":
+		return p.parseFunc(tok)
+	case unicode.IsDigit(rune(tok[0])):
+		return strconv.Atoi(tok)
+	}
+	if val, ok := p.vars[tok]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("unknown identifier '%s'", tok)
+}
+
+// parseFunc will parse the arguments of the min or max function, and will
+// return the result.
+func (p *exprParser) parseFunc(fn string) (int, error) {
+	if err := p.expect("("); err != nil {
+		return 0, err
+	}
+	args := []int{}
+	for {
+		val, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, val)
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect(")"); err != nil {
+		return 0, err
+	}
+	res := args[0]
+	for _, a := range args[1:] {
+		if (fn == "min" && a < res) || (fn == "max" && a > res) {
+			res = a
+		}
+	}
+	return res, nil
+}
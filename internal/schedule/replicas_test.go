@@ -0,0 +1,50 @@
+package schedule
+
+import (
+	"testing"
+)
+
+func TestEvalReplicas(t *testing.T) {
+	tests := []struct {
+		val      string
+		current  int
+		state    int
+		replicas int
+		err      bool
+	}{
+		{val: "3", current: 1, state: 4, replicas: 3},
+		{val: "0", current: 1, state: 4, replicas: 0},
+		{val: "-2", current: 5, state: 4, replicas: 3},
+		{val: "+1", current: 5, state: 4, replicas: 6},
+		{val: "50%", current: 1, state: 4, replicas: 2},
+		{val: "50%", current: 1, state: 3, replicas: 2},
+		{val: "0%", current: 1, state: 3, replicas: 0},
+		{val: "max(1,state/2)", current: 0, state: 5, replicas: 2},
+		{val: "max(1,state/2)", current: 0, state: 1, replicas: 1},
+		{val: "min(replicas,state,2)", current: 3, state: 4, replicas: 2},
+		{val: "(state+1)*2-replicas", current: 3, state: 4, replicas: 7},
+		{val: "-replicas+state", current: 1, state: 4, replicas: 3},
+		{val: "--2", current: 5, state: 4, replicas: 2},
+		{val: "x%", err: true},
+		{val: "-5%", err: true},
+		{val: "state/0", err: true},
+		{val: "max(1,", err: true},
+		{val: "max 1", err: true},
+		{val: "state)", err: true},
+		{val: "pods/2", err: true},
+		{val: "state^2", err: true},
+		{val: "", err: true},
+	}
+	for i, tst := range tests {
+		r, err := evalReplicas(tst.val, tst.current, tst.state)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if !tst.err && r != tst.replicas {
+			t.Errorf("failed test %d; expected %d replicas, got %d", i, tst.replicas, r)
+		}
+	}
+}
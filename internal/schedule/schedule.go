@@ -34,6 +34,12 @@ func SetTimeZone(tz string) error {
 	return err
 }
 
+// GetTimeZone will return the timezone in which schedule strings are
+// defined.
+func GetTimeZone() *time.Location {
+	return timezone
+}
+
 // Copy will return a fresh copy of the Schedule object.
 func (s *Schedule) Copy() *Schedule {
 	new := &Schedule{}
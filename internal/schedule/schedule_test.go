@@ -19,6 +19,50 @@ func TestNew(t *testing.T) {
 			schedule: `Mon-sun 10:00 replicas=1`,
 			err:      false,
 		},
+		{
+			schedule: `Mon-Fri 18:00 replicas=max(1,state/2) min=1 max=5`,
+			err:      false,
+		},
+		{
+			schedule: `Mon-Fri 18:00 replicas=replicas/(state-1)`,
+			err:      false,
+		},
+		{
+			schedule: `Mon-Fri 18:00 min=1`,
+			err:      false,
+		},
+		{
+			schedule: `Mon-Fri 18:00 replicas=pods/2`,
+			err:      true,
+		},
+		{
+			schedule: `Mon-Fri 18:00 replicas=max(1,`,
+			err:      true,
+		},
+		{
+			schedule: `Mon-Fri 18:00 replicas=x%`,
+			err:      true,
+		},
+		{
+			schedule: `Mon-Fri 18:00 replicas=-5%`,
+			err:      true,
+		},
+		{
+			schedule: `Mon-Fri 18:00 replicas=state/0`,
+			err:      true,
+		},
+		{
+			schedule: `Mon-Fri 18:00 replicas=1 min=abc`,
+			err:      true,
+		},
+		{
+			schedule: `Mon-Fri 18:00 replicas=1 max=`,
+			err:      true,
+		},
+		{
+			schedule: `Mon-Fri 18:00 max=x`,
+			err:      true,
+		},
 	}
 	for i, tst := range tests {
 		s, err := New(tst.schedule)
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpans(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ctx, parent := Start(nil, "scaleObjects")
+	_, child := Start(ctx, "scale", attribute.String("nightshift.object", "app"))
+	End(child, errors.New("oops"))
+	End(parent, nil)
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("failed test - expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("failed test - expected scale span to be a child of the scaleObjects span")
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("failed test - expected error status, got %v", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Unset {
+		t.Errorf("failed test - expected unset status, got %v", spans[1].Status())
+	}
+
+	hdr := http.Header{}
+	Inject(ctx, hdr)
+	if hdr.Get("traceparent") == "" {
+		t.Errorf("failed test - expected traceparent header, got %v", hdr)
+	}
+	hdr = http.Header{}
+	Inject(context.Background(), hdr)
+	if len(hdr) != 0 {
+		t.Errorf("failed test - expected no headers, got %v", hdr)
+	}
+}
+
+func TestSetExporter(t *testing.T) {
+	if err := SetExporter("http://localhost:4318", "test"); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	for _, endpoint := range []string{"http://[invalid", "localhost:4318"} {
+		if err := SetExporter(endpoint, "test"); err == nil {
+			t.Errorf("failed test %s - expected err, but got none", endpoint)
+		}
+	}
+}
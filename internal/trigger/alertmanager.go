@@ -0,0 +1,369 @@
+package trigger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// AlertmanagerTrigger is the object that implements triggers that silence
+// alerts in Alertmanager for scaled down objects, and expire these silences
+// when the objects are scaled up again.
+type AlertmanagerTrigger struct {
+	config   Config
+	m        sync.Mutex
+	silences map[string]string
+}
+
+// silence is the silence definition as expected by the Alertmanager v2 api.
+type silence struct {
+	Matchers  []matcher `json:"matchers"`
+	StartsAt  string    `json:"startsAt"`
+	EndsAt    string    `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+}
+
+// gettableSilence is the silence definition as returned by the Alertmanager v2
+// api.
+type gettableSilence struct {
+	silence
+	ID     string `json:"id"`
+	Status struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// matcher is the matcher definition as expected by the Alertmanager v2 api.
+type matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+func init() {
+	RegisterModule("alertmanager", NewAlertmanagerTrigger)
+}
+
+// NewAlertmanagerTrigger will instantiate a new AlertmanagerTrigger object.
+func NewAlertmanagerTrigger() (Trigger, error) {
+	return &AlertmanagerTrigger{config: Config{}, silences: map[string]string{}}, nil
+}
+
+// SetConfig will set the generic configuration for this trigger.
+func (s *AlertmanagerTrigger) SetConfig(cfg Config) {
+	s.config = cfg
+}
+
+// GetConfig will return the config applied for this trigger.
+func (s *AlertmanagerTrigger) GetConfig() Config {
+	return s.config
+}
+
+// Execute will create a silence for each namespace of which the objects are
+// scaled down, and will expire the previously created silence for each
+// namespace of which the objects are scaled up. If the id of the previously
+// created silence is not known (e.g. after a restart), the active silences
+// created by nightshift for the namespace are looked up in Alertmanager.
+func (s *AlertmanagerTrigger) Execute(evt Event) (Result, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	vars := getEventVars(s.config, evt)
+	url, err := RenderTemplate(strings.TrimSpace(s.config.Settings["url"]), vars)
+	if err != nil {
+		return nil, err
+	}
+	if url == "" {
+		return nil, fmt.Errorf("no url specified")
+	}
+	url = strings.TrimSuffix(url, "/")
+	cli, err := s.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	res := Result{}
+	errs := []string{}
+	nss, names := groupByNamespace(evt.Objects)
+	for _, ns := range names {
+		down, err := s.isDownscale(nss[ns])
+		if err != nil {
+			return nil, err
+		}
+		ids := []string{}
+		if id, ok := s.silences[ns]; ok {
+			ids = append(ids, id)
+		} else if ids, err = s.findSilences(cli, url, ns); err != nil {
+			errs = append(errs, err.Error())
+		}
+		expired := []string{}
+		for _, id := range ids {
+			if err := s.expireSilence(cli, url, id); err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			expired = append(expired, id)
+		}
+		if len(expired) > 0 {
+			res["expired/"+ns] = strings.Join(expired, ",")
+		}
+		delete(s.silences, ns)
+		if !down {
+			continue
+		}
+		sil, err := s.newSilence(ns, nss[ns], vars)
+		if err != nil {
+			return res, err
+		}
+		id, err := s.createSilence(cli, url, sil)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		s.silences[ns] = id
+		res["silence/"+ns] = id
+	}
+	if len(errs) > 0 {
+		return res, fmt.Errorf("%s", strings.Join(errs, ","))
+	}
+	return res, nil
+}
+
+// isDownscale will determine if the given objects are scaled down, and hence
+// a silence should be created. This is determined by the configured action;
+// "silence" will always create a silence, "expire" will only expire existing
+// silences and "auto" (default) will create a silence if all given objects
+// are scaled to 0 replicas.
+func (s *AlertmanagerTrigger) isDownscale(objs []*scanner.Object) (bool, error) {
+	switch strings.ToLower(s.config.Settings["action"]) {
+	case "silence":
+		return true, nil
+	case "expire":
+		return false, nil
+	case "", "auto":
+		for _, obj := range objs {
+			if obj.Replicas > 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid action '%s'", s.config.Settings["action"])
+}
+
+// newSilence will create a silence for the given namespace and objects. The
+// silence will match alerts with the namespace label (configured with the
+// namespacelabel setting) and, for each label configured in the labels
+// setting, the values of these labels on the given objects. Additional
+// matchers can be configured with the matchers setting.
+func (s *AlertmanagerTrigger) newSilence(ns string, objs []*scanner.Object, vars map[string]interface{}) (silence, error) {
+	duration, err := s.getDuration()
+	if err != nil {
+		return silence{}, err
+	}
+	mtchrs := []matcher{{Name: s.namespaceLabel(), Value: ns, IsEqual: true}}
+	for _, label := range strings.Split(s.config.Settings["labels"], ",") {
+		if label = strings.TrimSpace(label); label == "" {
+			continue
+		}
+		vals := map[string]bool{}
+		for _, obj := range objs {
+			if val, ok := obj.Labels[label]; ok {
+				vals[regexp.QuoteMeta(val)] = true
+			}
+		}
+		if len(vals) > 0 {
+			mtchrs = append(mtchrs, matcher{Name: label, Value: strings.Join(sortedKeys(vals), "|"), IsRegex: true, IsEqual: true})
+		}
+	}
+	extra, err := renderKeyValues(s.config.Settings["matchers"], vars)
+	if err != nil {
+		return silence{}, err
+	}
+	for _, kv := range extra {
+		m := matcher{Name: kv.Key, Value: kv.Value, IsEqual: true}
+		if strings.HasPrefix(kv.Value, "~") {
+			m.Value = strings.TrimPrefix(kv.Value, "~")
+			m.IsRegex = true
+		}
+		mtchrs = append(mtchrs, m)
+	}
+	comment, err := RenderTemplate(s.config.Settings["comment"], vars)
+	if err != nil {
+		return silence{}, err
+	}
+	if comment == "" {
+		comment = fmt.Sprintf("Scaled down by nightshift (%s)", ns)
+	}
+	now := time.Now()
+	return silence{
+		Matchers:  mtchrs,
+		StartsAt:  now.Format(time.RFC3339),
+		EndsAt:    now.Add(duration).Format(time.RFC3339),
+		CreatedBy: s.createdBy(),
+		Comment:   comment,
+	}, nil
+}
+
+// createSilence will post the given silence to alertmanager, and will return
+// the id of the created silence.
+func (s *AlertmanagerTrigger) createSilence(cli *http.Client, url string, sil silence) (string, error) {
+	body, err := json.Marshal(sil)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", url+"/api/v2/silences", bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := cli.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("error creating silence; status=%s(%d)", resp.Status, resp.StatusCode)
+	}
+	out := struct {
+		SilenceID string `json:"silenceID"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("error creating silence; invalid response: %s", err)
+	}
+	logger.Debug("Created silence", logging.TriggerId(s.config.Id), "silence", out.SilenceID, "matchers", sil.Matchers)
+	return out.SilenceID, nil
+}
+
+// findSilences will return the ids of the active silences that were created
+// by nightshift for given namespace.
+func (s *AlertmanagerTrigger) findSilences(cli *http.Client, url, ns string) ([]string, error) {
+	req, err := http.NewRequest("GET", url+"/api/v2/silences", nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Set("filter", fmt.Sprintf("%s=%q", s.namespaceLabel(), ns))
+	req.URL.RawQuery = q.Encode()
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("error listing silences; status=%s(%d)", resp.Status, resp.StatusCode)
+	}
+	sils := []gettableSilence{}
+	if err := json.NewDecoder(resp.Body).Decode(&sils); err != nil {
+		return nil, fmt.Errorf("error listing silences; invalid response: %s", err)
+	}
+	ids := []string{}
+	for _, sil := range sils {
+		if sil.Status.State == "expired" || sil.CreatedBy != s.createdBy() {
+			continue
+		}
+		for _, m := range sil.Matchers {
+			if m.Name == s.namespaceLabel() && m.Value == ns && m.IsEqual && !m.IsRegex {
+				ids = append(ids, sil.ID)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+// expireSilence will expire the silence with given id.
+func (s *AlertmanagerTrigger) expireSilence(cli *http.Client, url, id string) error {
+	req, err := http.NewRequest("DELETE", url+"/api/v2/silence/"+id, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error expiring silence %s; status=%s(%d) %s", id, resp.Status, resp.StatusCode, body)
+	}
+	logger.Debug("Expired silence", logging.TriggerId(s.config.Id), "silence", id)
+	return nil
+}
+
+// namespaceLabel will return the label that contains the namespace in the
+// alerts.
+func (s *AlertmanagerTrigger) namespaceLabel() string {
+	if label := s.config.Settings["namespacelabel"]; label != "" {
+		return label
+	}
+	return "namespace"
+}
+
+// createdBy will return the creator of the silences.
+func (s *AlertmanagerTrigger) createdBy() string {
+	if by := s.config.Settings["createdby"]; by != "" {
+		return by
+	}
+	return "nightshift"
+}
+
+// newClient will create a new http.Client object with the configured timeout.
+func (s *AlertmanagerTrigger) newClient() (*http.Client, error) {
+	to := s.config.Settings["timeout"]
+	if to == "" {
+		to = "1s"
+	}
+	timeout, err := time.ParseDuration(to)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Timeout: timeout}, nil
+}
+
+// getDuration will return the maximum duration of a silence. The silence will
+// normally be expired when the objects are scaled up again, this duration
+// makes sure the silence is removed if that doesn't happen.
+func (s *AlertmanagerTrigger) getDuration() (time.Duration, error) {
+	dur := s.config.Settings["duration"]
+	if dur == "" {
+		dur = "24h"
+	}
+	return time.ParseDuration(dur)
+}
+
+// groupByNamespace will return the given objects grouped by namespace, as
+// well as the list of namespaces in alphabetical order.
+func groupByNamespace(objs []*scanner.Object) (map[string][]*scanner.Object, []string) {
+	nss := map[string][]*scanner.Object{}
+	names := []string{}
+	for _, obj := range objs {
+		if _, ok := nss[obj.Namespace]; !ok {
+			names = append(names, obj.Namespace)
+		}
+		nss[obj.Namespace] = append(nss[obj.Namespace], obj)
+	}
+	sort.Strings(names)
+	return nss, names
+}
+
+// sortedKeys will return the keys of given map in alphabetical order.
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,230 @@
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+type mockAlertmanager struct {
+	created []silence
+	expired []string
+	listed  []string
+	fail    bool
+}
+
+func (m *mockAlertmanager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/api/v2/silences":
+		sil := silence{}
+		json.NewDecoder(r.Body).Decode(&sil)
+		m.created = append(m.created, sil)
+		fmt.Fprintf(w, `{"silenceID":"id%d"}`, len(m.created))
+	case r.Method == "GET" && r.URL.Path == "/api/v2/silences":
+		m.listed = append(m.listed, r.URL.Query().Get("filter"))
+		sils := []gettableSilence{}
+		for i, sil := range m.created {
+			gs := gettableSilence{silence: sil, ID: fmt.Sprintf("id%d", i+1)}
+			gs.Status.State = "active"
+			for _, id := range m.expired {
+				if id == gs.ID {
+					gs.Status.State = "expired"
+				}
+			}
+			sils = append(sils, gs)
+		}
+		json.NewEncoder(w).Encode(sils)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+		m.expired = append(m.expired, strings.TrimPrefix(r.URL.Path, "/api/v2/silence/"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestNewAlertmanagerTrigger(t *testing.T) {
+	amt, err := New("alertmanager")
+	if err != nil {
+		t.Errorf("failed test - could not instantiate alertmanager module; %s", err)
+	}
+	in := Config{
+		Settings: map[string]string{
+			"url": "http://localhost:9093",
+		},
+	}
+	amt.SetConfig(in)
+	out := amt.GetConfig()
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("failed test - configuration not correctly set; expected %v, got %v", in, out)
+	}
+}
+
+func TestAlertmanagerExecute(t *testing.T) {
+	mock := &mockAlertmanager{}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	trgr, _ := NewAlertmanagerTrigger()
+	amt := trgr.(*AlertmanagerTrigger)
+	amt.SetConfig(Config{
+		Settings: map[string]string{
+			"url":      srv.URL + "/",
+			"labels":   "app, tier",
+			"matchers": "severity=~warning|critical",
+		},
+	})
+
+	down := []*scanner.Object{
+		{Namespace: "dev1", Name: "app1", Labels: map[string]string{"app": "app1"}},
+		{Namespace: "dev1", Name: "app2", Labels: map[string]string{"app": "app.2"}},
+		{Namespace: "dev2", Name: "app3"},
+	}
+	res, err := amt.Execute(Event{Objects: down})
+	if err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	exp := Result{"silence/dev1": "id1", "silence/dev2": "id2"}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("failed test - expected %v, got %v", exp, res)
+	}
+	if len(mock.created) != 2 {
+		t.Fatalf("failed test - expected 2 silences, got %d", len(mock.created))
+	}
+	mtchrs := []matcher{
+		{Name: "namespace", Value: "dev1", IsEqual: true},
+		{Name: "app", Value: `app1|app\.2`, IsRegex: true, IsEqual: true},
+		{Name: "severity", Value: "warning|critical", IsRegex: true, IsEqual: true},
+	}
+	if !reflect.DeepEqual(mtchrs, mock.created[0].Matchers) {
+		t.Errorf("failed test - expected matchers %v, got %v", mtchrs, mock.created[0].Matchers)
+	}
+	if mock.created[0].CreatedBy != "nightshift" || mock.created[0].EndsAt <= mock.created[0].StartsAt {
+		t.Errorf("failed test - invalid silence %#v", mock.created[0])
+	}
+
+	up := []*scanner.Object{{Namespace: "dev1", Name: "app1", Replicas: 1}}
+	res, err = amt.Execute(Event{Objects: up})
+	if err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	exp = Result{"expired/dev1": "id1"}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("failed test - expected %v, got %v", exp, res)
+	}
+	if !reflect.DeepEqual([]string{"id1"}, mock.expired) {
+		t.Errorf("failed test - expected silence id1 to be expired, got %v", mock.expired)
+	}
+
+	res, err = amt.Execute(Event{Objects: up})
+	if err != nil || len(res) != 0 || len(mock.expired) != 1 {
+		t.Errorf("failed test - expected no action on second upscale, got %v, %v", res, err)
+	}
+
+	mock.fail = true
+	_, err = amt.Execute(Event{Objects: down})
+	if err == nil {
+		t.Errorf("failed test - expected err, but got none")
+	}
+	if _, ok := amt.silences["dev2"]; ok {
+		t.Errorf("failed test - silence should be removed after expiring")
+	}
+}
+
+func TestAlertmanagerExpireAfterRestart(t *testing.T) {
+	mock := &mockAlertmanager{}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+	cfg := Config{Settings: map[string]string{"url": srv.URL}}
+
+	trgr, _ := NewAlertmanagerTrigger()
+	trgr.SetConfig(cfg)
+	down := []*scanner.Object{{Namespace: "dev1", Name: "app1"}, {Namespace: "dev2", Name: "app2"}}
+	if _, err := trgr.Execute(Event{Objects: down}); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	// silence created by someone else for the same namespace
+	mock.created = append(mock.created, silence{
+		Matchers:  []matcher{{Name: "namespace", Value: "dev1", IsEqual: true}},
+		CreatedBy: "admin",
+	})
+
+	// a new instance doesn't know the silence ids
+	trgr, _ = NewAlertmanagerTrigger()
+	trgr.SetConfig(cfg)
+	up := []*scanner.Object{{Namespace: "dev1", Name: "app1", Replicas: 1}}
+	res, err := trgr.Execute(Event{Objects: up})
+	if err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	exp := Result{"expired/dev1": "id1"}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("failed test - expected %v, got %v", exp, res)
+	}
+	if !reflect.DeepEqual([]string{"id1"}, mock.expired) {
+		t.Errorf("failed test - expected silence id1 to be expired, got %v", mock.expired)
+	}
+	if last := mock.listed[len(mock.listed)-1]; last != `namespace="dev1"` {
+		t.Errorf("failed test - expected silences to be filtered on namespace, got %s", last)
+	}
+
+	res, err = trgr.Execute(Event{Objects: up})
+	if err != nil || len(res) != 0 || len(mock.expired) != 1 {
+		t.Errorf("failed test - expected no action on second upscale, got %v, %v", res, err)
+	}
+}
+
+func TestAlertmanagerConfig(t *testing.T) {
+	srv := httptest.NewServer(&mockAlertmanager{})
+	defer srv.Close()
+	tests := []struct {
+		cfg Config
+		err bool
+	}{
+		{
+			cfg: Config{Settings: map[string]string{}},
+			err: true,
+		},
+		{
+			cfg: Config{Settings: map[string]string{"url": srv.URL, "action": "invalid"}},
+			err: true,
+		},
+		{
+			cfg: Config{Settings: map[string]string{"url": srv.URL, "duration": "forever"}},
+			err: true,
+		},
+		{
+			cfg: Config{Settings: map[string]string{"url": srv.URL, "timeout": "forever"}},
+			err: true,
+		},
+		{
+			cfg: Config{Settings: map[string]string{"url": srv.URL, "matchers": "invalid"}},
+			err: true,
+		},
+		{
+			cfg: Config{Settings: map[string]string{"url": srv.URL, "action": "expire"}},
+			err: false,
+		},
+	}
+
+	objs := []*scanner.Object{{Namespace: "dev1", Name: "app1"}}
+	for i, tst := range tests {
+		trgr, _ := NewAlertmanagerTrigger()
+		trgr.SetConfig(tst.cfg)
+		_, err := trgr.Execute(Event{Objects: objs})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// namespaceFile is the file containing the namespace nightshift is running
+// in, which is used for secret references that don't specify a namespace.
+var namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+const (
+	secretRefPrefix = "secretref:"
+	filePrefix      = "file:"
+)
+
+// isCredentialRef will return true if given value refers to a secret or file.
+func isCredentialRef(ref string) bool {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	return strings.HasPrefix(ref, secretRefPrefix) || strings.HasPrefix(ref, filePrefix)
+}
+
+// resolveCredential will return the value for given credential reference. The
+// reference is either "secretRef: [namespace/]name/key" which will read the
+// key from the given secret (in the namespace nightshift is running in if no
+// namespace is specified), or "file: /path" which will read the contents of
+// the given (mounted) file. Any other value will be returned as is.
+func resolveCredential(ref string) ([]byte, error) {
+	ref = strings.TrimSpace(ref)
+	lref := strings.ToLower(ref)
+	switch {
+	case strings.HasPrefix(lref, secretRefPrefix):
+		return readSecretRef("", strings.TrimSpace(ref[len(secretRefPrefix):]))
+	case strings.HasPrefix(lref, filePrefix):
+		return ioutil.ReadFile(strings.TrimSpace(ref[len(filePrefix):]))
+	}
+	return []byte(ref), nil
+}
+
+// resolveCredentialString will return the value for given credential
+// reference as a string, with leading and trailing white space removed.
+func resolveCredentialString(ref string) (string, error) {
+	val, err := resolveCredential(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(val)), nil
+}
+
+// readSecretRef will return the value of the key in the secret as referenced
+// by given [namespace/]name/key reference, in given cluster.
+func readSecretRef(cluster, ref string) ([]byte, error) {
+	flds := strings.Split(ref, "/")
+	var ns, name, key string
+	switch len(flds) {
+	case 2:
+		name, key = flds[0], flds[1]
+		data, err := ioutil.ReadFile(namespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("no namespace specified for secretRef '%s'", ref)
+		}
+		ns = strings.TrimSpace(string(data))
+	case 3:
+		ns, name, key = flds[0], flds[1], flds[2]
+	default:
+		return nil, fmt.Errorf("invalid secretRef specified '%s'", ref)
+	}
+	client, err := getKubeClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	sec, err := client.CoreV1().Secrets(ns).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	val, ok := sec.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in secret %s/%s", key, ns, name)
+	}
+	return val, nil
+}
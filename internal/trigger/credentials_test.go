@@ -0,0 +1,63 @@
+package trigger
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResolveCredential(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("filetoken\n"), 0600)
+	nsFile := filepath.Join(dir, "namespace")
+	ioutil.WriteFile(nsFile, []byte("nightshift"), 0600)
+
+	restore := setKubeClient(fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "nightshift", Name: "webhook"},
+			Data:       map[string][]byte{"token": []byte("secrettoken")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "development", Name: "webhook"},
+			Data:       map[string][]byte{"token": []byte("devtoken")},
+		},
+	))
+	defer restore()
+
+	tests := []struct {
+		ref    string
+		nsfile string
+		out    string
+		err    bool
+	}{
+		{ref: "plaintext", out: "plaintext", err: false},
+		{ref: "file:" + tokenFile, out: "filetoken", err: false},
+		{ref: "File: " + tokenFile, out: "filetoken", err: false},
+		{ref: "file:" + filepath.Join(dir, "missing"), err: true},
+		{ref: "secretRef: webhook/token", nsfile: nsFile, out: "secrettoken", err: false},
+		{ref: "secretRef:development/webhook/token", out: "devtoken", err: false},
+		{ref: "secretRef: webhook/token", nsfile: filepath.Join(dir, "missing"), err: true},
+		{ref: "secretRef: webhook/password", nsfile: nsFile, err: true},
+		{ref: "secretRef: default/webhook/token", err: true},
+		{ref: "secretRef: webhook", err: true},
+	}
+
+	for i, tst := range tests {
+		namespaceFile = tst.nsfile
+		out, err := resolveCredentialString(tst.ref)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err == nil && tst.out != out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, out)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package trigger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/metrics"
+)
+
+// maxOutput is the maximum number of bytes of stdout and stderr that will be
+// included in the result of an exec trigger.
+const maxOutput = 4096
+
+// ExecTrigger is the object that implements command based triggers.
+type ExecTrigger struct {
+	config Config
+}
+
+func init() {
+	RegisterModule("exec", NewExecTrigger)
+}
+
+// NewExecTrigger will instantiate a new ExecTrigger object.
+func NewExecTrigger() (Trigger, error) {
+	return &ExecTrigger{config: Config{}}, nil
+}
+
+// SetConfig will set the generic configuration for this trigger.
+func (s *ExecTrigger) SetConfig(cfg Config) {
+	s.config = cfg
+}
+
+// GetConfig will return the config applied for this trigger.
+func (s *ExecTrigger) GetConfig() Config {
+	return s.config
+}
+
+// Execute will run the configured command, and wait until it finished, or
+// until the configured timeout expired.
+func (s *ExecTrigger) Execute(evt Event) (Result, error) {
+	vars := getEventVars(s.config, evt)
+	timeout, err := s.getTimeout()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd, err := s.newCommand(ctx, vars)
+	if err != nil {
+		return nil, err
+	}
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	logger.Debug("Executing command", logging.TriggerId(s.config.Id), "command", cmd.Path, "args", cmd.Args[1:])
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("command timed out after %s", timeout)
+	}
+	code := 0
+	if cmd.ProcessState != nil {
+		code = cmd.ProcessState.ExitCode()
+	} else if err != nil {
+		code = -1
+	}
+	logger.Log(ctx, logging.LevelTrace, "Command finished", logging.TriggerId(s.config.Id), "command", cmd.Path, "exit_status", code, "stdout", stdout.String(), "stderr", stderr.String())
+	if s.config.Id != "" {
+		metrics.SetExitStatus(s.config.Id, code)
+	}
+
+	res := Result{
+		"exitcode": strconv.Itoa(code),
+		"stdout":   truncate(stdout.String(), maxOutput),
+		"stderr":   truncate(stderr.String(), maxOutput),
+	}
+	if err != nil {
+		return res, fmt.Errorf("error exec %s; exit status=%d; %s", cmd.Path, code, err)
+	}
+	return res, nil
+}
+
+// newCommand will create an exec.Cmd for the configured command, arguments and
+// environment variables.
+func (s *ExecTrigger) newCommand(ctx context.Context, vars map[string]interface{}) (*exec.Cmd, error) {
+	command, err := RenderTemplate(strings.TrimSpace(s.config.Settings["command"]), vars)
+	if err != nil {
+		return nil, err
+	}
+	if command == "" {
+		return nil, fmt.Errorf("no command specified")
+	}
+	args, err := s.getArgs(vars)
+	if err != nil {
+		return nil, err
+	}
+	env, err := renderKeyValues(s.config.Settings["env"], vars)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Env = os.Environ()
+	for _, e := range env {
+		cmd.Env = append(cmd.Env, e.Key+"="+e.Value)
+	}
+	cmd.Dir = s.config.Settings["workdir"]
+	// don't wait forever on child processes that keep stdout/stderr open
+	cmd.WaitDelay = time.Second
+	return cmd, nil
+}
+
+// getArgs will parse the args configuration, which contains one argument per
+// line, and will return the rendered list of arguments.
+func (s *ExecTrigger) getArgs(vars map[string]interface{}) ([]string, error) {
+	args := []string{}
+	for _, arg := range getLines(s.config.Settings["args"]) {
+		val, err := RenderTemplate(arg, vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, val)
+	}
+	return args, nil
+}
+
+// getTimeout will return a time.Duration for the configured timeout. If no
+// timeout has been configured it will use a default timeout instead.
+func (s *ExecTrigger) getTimeout() (time.Duration, error) {
+	to := s.config.Settings["timeout"]
+	if to == "" {
+		to = "5m"
+	}
+	return time.ParseDuration(to)
+}
@@ -0,0 +1,229 @@
+package trigger
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+func TestNewExecTrigger(t *testing.T) {
+	ext, err := New("exec")
+	if err != nil {
+		t.Errorf("failed test - could not instantiate exec module; %s", err)
+	}
+	in := Config{
+		Settings: map[string]string{
+			"command": "true",
+		},
+	}
+	ext.SetConfig(in)
+	out := ext.GetConfig()
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("failed test - configuration not correctly set; expected %v, got %v", in, out)
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	tests := []struct {
+		cfg      Config
+		duration time.Duration
+		err      bool
+	}{
+		{
+			cfg:      Config{},
+			duration: 5 * time.Minute,
+			err:      false,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"timeout": "1s",
+				},
+			},
+			duration: 1 * time.Second,
+			err:      false,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"timeout": "forever",
+				},
+			},
+			duration: 0,
+			err:      true,
+		},
+	}
+
+	ext := &ExecTrigger{}
+	for i, tst := range tests {
+		ext.SetConfig(tst.cfg)
+		dur, err := ext.getTimeout()
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err == nil && tst.duration != dur {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.duration, dur)
+		}
+	}
+}
+
+func TestExecCommand(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		args []string
+		env  []string
+		err  bool
+	}{
+		{
+			cfg:  Config{},
+			args: nil,
+			env:  nil,
+			err:  true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"command": "/bin/echo",
+				},
+			},
+			args: []string{"/bin/echo"},
+			env:  []string{},
+			err:  false,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"command": "/bin/echo",
+					"args":    "--namespace\n {{ (index .objects 0).Namespace }} \n\n",
+					"env":     "DB = {{ .settings.db }}\nEMPTY=",
+					"db":      "postgres",
+				},
+			},
+			args: []string{"/bin/echo", "--namespace", "development"},
+			env:  []string{"DB=postgres", "EMPTY="},
+			err:  false,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"command": "/bin/echo",
+					"env":     "NOVALUE",
+				},
+			},
+			err: true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"command": "/bin/echo",
+					"args":    "{{ malformed template }}",
+				},
+			},
+			err: true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"command": "{{ malformed template }}",
+				},
+			},
+			err: true,
+		},
+	}
+
+	objs := []*scanner.Object{{Namespace: "development", Name: "app1"}}
+	ext := &ExecTrigger{}
+	for i, tst := range tests {
+		ext.SetConfig(tst.cfg)
+		vars := getTemplateVars(tst.cfg.Settings, objs)
+		cmd, err := ext.newCommand(context.Background(), vars)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(tst.args, cmd.Args) {
+			t.Errorf("failed test %d - expected args %v, but got %v", i, tst.args, cmd.Args)
+		}
+		env := cmd.Env[len(cmd.Env)-len(tst.env):]
+		if !reflect.DeepEqual(tst.env, env) {
+			t.Errorf("failed test %d - expected env %v, but got %v", i, tst.env, env)
+		}
+	}
+}
+
+func TestExecExecute(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		res Result
+		err bool
+	}{
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"command": "/bin/sh",
+					"args":    "-c\necho $GREETING; echo oops >&2",
+					"env":     "GREETING=hello {{ (index .objects 0).Name }}",
+				},
+			},
+			res: Result{"exitcode": "0", "stdout": "hello app1\n", "stderr": "oops\n"},
+			err: false,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"command": "/bin/sh",
+					"args":    "-c\nexit 3",
+				},
+			},
+			res: Result{"exitcode": "3", "stdout": "", "stderr": ""},
+			err: true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"command": "/bin/sh",
+					"args":    "-c\nsleep 10",
+					"timeout": "100ms",
+				},
+			},
+			res: Result{"exitcode": "-1", "stdout": "", "stderr": ""},
+			err: true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"command": "/non/existing/command",
+				},
+			},
+			res: Result{"exitcode": "-1", "stdout": "", "stderr": ""},
+			err: true,
+		},
+	}
+
+	objs := []*scanner.Object{{Namespace: "development", Name: "app1"}}
+	ext := &ExecTrigger{}
+	for i, tst := range tests {
+		ext.SetConfig(tst.cfg)
+		res, err := ext.Execute(Event{Objects: objs})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if !reflect.DeepEqual(tst.res, res) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.res, res)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+)
+
+// jobPollInterval is the interval at which the status of a job is checked
+// when waiting for completion.
+const jobPollInterval = 2 * time.Second
+
+// JobTrigger is the object that implements triggers that create kubernetes
+// Jobs.
+type JobTrigger struct {
+	config  Config
+	m       sync.Mutex
+	clients map[string]kubernetes.Interface
+}
+
+func init() {
+	RegisterModule("job", NewJobTrigger)
+}
+
+// NewJobTrigger will instantiate a new JobTrigger object.
+func NewJobTrigger() (Trigger, error) {
+	return &JobTrigger{config: Config{}}, nil
+}
+
+// SetConfig will set the generic configuration for this trigger.
+func (s *JobTrigger) SetConfig(cfg Config) {
+	s.config = cfg
+}
+
+// GetConfig will return the config applied for this trigger.
+func (s *JobTrigger) GetConfig() Config {
+	return s.config
+}
+
+// Execute will create the configured job, and will optionally wait for the
+// job to complete.
+func (s *JobTrigger) Execute(evt Event) (Result, error) {
+	vars := getEventVars(s.config, evt)
+	cluster, err := eventCluster(s.config.Settings, evt.Objects)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.getClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := renderNamespace(s.config.Settings["namespace"], vars, evt.Objects)
+	if err != nil {
+		return nil, err
+	}
+	job, err := s.newJob(client, ns, vars)
+	if err != nil {
+		return nil, err
+	}
+	job, err = client.BatchV1().Jobs(ns).Create(context.Background(), job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("Created job", logging.TriggerId(s.config.Id), logging.KeyNamespace, ns, "job", job.Name)
+	res := Result{"namespace": ns, "job": job.Name, "status": "created"}
+	if strings.ToLower(s.config.Settings["wait"]) != "true" {
+		return res, nil
+	}
+	timeout, err := s.getTimeout()
+	if err != nil {
+		return res, err
+	}
+	res["status"], err = s.waitForJob(client, ns, job.Name, jobPollInterval, timeout)
+	return res, err
+}
+
+// getClient will lazy load the kubernetes client for given cluster.
+func (s *JobTrigger) getClient(cluster string) (kubernetes.Interface, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if client, ok := s.clients[cluster]; ok {
+		return client, nil
+	}
+	client, err := newKubeClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if s.clients == nil {
+		s.clients = map[string]kubernetes.Interface{}
+	}
+	s.clients[cluster] = client
+	return client, nil
+}
+
+// newJob will create a Job object based on the configured cronjob, inline job
+// template or template file. The configured name and environment variables
+// will be applied to the job.
+func (s *JobTrigger) newJob(client kubernetes.Interface, ns string, vars map[string]interface{}) (*batchv1.Job, error) {
+	var job *batchv1.Job
+	var err error
+	switch {
+	case s.config.Settings["cronjob"] != "":
+		job, err = s.jobFromCronJob(client, ns, vars)
+	case s.config.Settings["job"] != "":
+		job, err = s.jobFromTemplate(s.config.Settings["job"], vars)
+	case s.config.Settings["template"] != "":
+		var templ []byte
+		templ, err = ioutil.ReadFile(s.config.Settings["template"])
+		if err == nil {
+			job, err = s.jobFromTemplate(string(templ), vars)
+		}
+	default:
+		err = fmt.Errorf("no cronjob, job or template specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.Namespace = ns
+	name, err := RenderTemplate(strings.TrimSpace(s.config.Settings["name"]), vars)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		job.Name = name
+	}
+	if job.Name == "" && job.GenerateName == "" {
+		job.GenerateName = "nightshift-"
+	}
+	env, err := renderKeyValues(s.config.Settings["env"], vars)
+	if err != nil {
+		return nil, err
+	}
+	addJobEnv(job, env)
+	return job, nil
+}
+
+// jobFromTemplate will render the given job template, and will unmarshall the
+// result into a Job object.
+func (s *JobTrigger) jobFromTemplate(templ string, vars map[string]interface{}) (*batchv1.Job, error) {
+	data, err := RenderTemplate(templ, vars)
+	if err != nil {
+		return nil, err
+	}
+	job := &batchv1.Job{}
+	if err := yaml.UnmarshalStrict([]byte(data), job); err != nil {
+		return nil, fmt.Errorf("invalid job template; %s", err)
+	}
+	return job, nil
+}
+
+// jobFromCronJob will create a Job object based on the job template of the
+// configured cronjob, similar to kubectl create job --from=cronjob/name.
+func (s *JobTrigger) jobFromCronJob(client kubernetes.Interface, ns string, vars map[string]interface{}) (*batchv1.Job, error) {
+	name, err := RenderTemplate(strings.TrimSpace(s.config.Settings["cronjob"]), vars)
+	if err != nil {
+		return nil, err
+	}
+	cj, err := client.BatchV1().CronJobs(ns).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for k, v := range cj.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: cj.Name + "-",
+			Annotations:  annotations,
+			Labels:       cj.Spec.JobTemplate.Labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cj, batchv1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cj.Spec.JobTemplate.Spec,
+	}, nil
+}
+
+// addJobEnv will add the given list of environment variables to all
+// containers of the given job.
+func addJobEnv(job *batchv1.Job, env []keyValue) {
+	spec := &job.Spec.Template.Spec
+	for _, e := range env {
+		for i := range spec.Containers {
+			spec.Containers[i].Env = append(spec.Containers[i].Env, corev1.EnvVar{Name: e.Key, Value: e.Value})
+		}
+	}
+}
+
+// waitForJob will wait until the job with the given name has completed, or
+// failed. It will return the final status of the job, or an error if the job
+// failed, or did not finish within given timeout.
+func (s *JobTrigger) waitForJob(client kubernetes.Interface, ns, name string, interval, timeout time.Duration) (string, error) {
+	status := "running"
+	err := wait.PollUntilContextTimeout(context.Background(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		job, err := client.BatchV1().Jobs(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch cond.Type {
+			case batchv1.JobComplete:
+				status = "succeeded"
+				return true, nil
+			case batchv1.JobFailed:
+				status = "failed"
+				return false, fmt.Errorf("job %s/%s failed; %s", ns, name, cond.Message)
+			}
+		}
+		return false, nil
+	})
+	if err != nil && status == "running" {
+		return status, fmt.Errorf("error waiting for job %s/%s; %s", ns, name, err)
+	}
+	logger.Debug("Job finished", logging.TriggerId(s.config.Id), logging.KeyNamespace, ns, "job", name, "status", status)
+	return status, err
+}
+
+// getTimeout will return a time.Duration for the configured timeout to wait
+// for completion of the job. If no timeout has been configured it will use a
+// default timeout instead.
+func (s *JobTrigger) getTimeout() (time.Duration, error) {
+	to := s.config.Settings["timeout"]
+	if to == "" {
+		to = "10m"
+	}
+	return time.ParseDuration(to)
+}
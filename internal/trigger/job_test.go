@@ -0,0 +1,293 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+func TestNewJobTrigger(t *testing.T) {
+	jbt, err := New("job")
+	if err != nil {
+		t.Errorf("failed test - could not instantiate job module; %s", err)
+	}
+	in := Config{
+		Settings: map[string]string{
+			"cronjob": "refreshdb",
+		},
+	}
+	jbt.SetConfig(in)
+	out := jbt.GetConfig()
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("failed test - configuration not correctly set; expected %v, got %v", in, out)
+	}
+}
+
+func TestJobExecute(t *testing.T) {
+	cronjob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "refreshdb", Namespace: "development"},
+		Spec: batchv1.CronJobSpec{
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "refreshdb"}},
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "refreshdb", Image: "refreshdb:latest"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		cfg   Config
+		ns    string
+		name  string
+		image string
+		env   []corev1.EnvVar
+		err   bool
+	}{
+		{
+			cfg: Config{Settings: map[string]string{}},
+			err: true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"cronjob": "refreshdb",
+					"name":    "refreshdb-{{ (index .objects 0).Name }}",
+					"env":     "TARGET={{ (index .objects 0).Namespace }}",
+				},
+			},
+			ns:    "development",
+			name:  "refreshdb-app1",
+			image: "refreshdb:latest",
+			env:   []corev1.EnvVar{{Name: "TARGET", Value: "development"}},
+			err:   false,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"cronjob": "nonexisting",
+				},
+			},
+			err: true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"namespace": "batch",
+					"job": `
+metadata:
+  name: report-{{ (index .objects 0).Name }}
+spec:
+  template:
+    spec:
+      containers:
+        - name: report
+          image: report:latest
+          env:
+            - name: SOURCE
+              value: {{ (index .objects 0).Namespace }}
+`,
+				},
+			},
+			ns:    "batch",
+			name:  "report-app1",
+			image: "report:latest",
+			env:   []corev1.EnvVar{{Name: "SOURCE", Value: "development"}},
+			err:   false,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"template": "testdata/job.yaml",
+				},
+			},
+			ns:    "development",
+			name:  "refreshdb-app1",
+			image: "refreshdb:latest",
+			err:   false,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"template": "testdata/nonexisting.yaml",
+				},
+			},
+			err: true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"job": "spec: {{ malformed template }}",
+				},
+			},
+			err: true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"job": "spec:\n  unknown: field",
+				},
+			},
+			err: true,
+		},
+		{
+			cfg: Config{
+				Settings: map[string]string{
+					"cronjob": "refreshdb",
+					"env":     "INVALID",
+				},
+			},
+			err: true,
+		},
+	}
+
+	objs := []*scanner.Object{{Namespace: "development", Name: "app1"}}
+	for i, tst := range tests {
+		client := fake.NewSimpleClientset(cronjob)
+		jbt := &JobTrigger{clients: map[string]kubernetes.Interface{"": client}}
+		jbt.SetConfig(tst.cfg)
+		res, err := jbt.Execute(Event{Objects: objs})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err != nil {
+			continue
+		}
+		exp := Result{"namespace": tst.ns, "job": tst.name, "status": "created"}
+		if !reflect.DeepEqual(exp, res) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, exp, res)
+		}
+		job, err := client.BatchV1().Jobs(tst.ns).Get(context.Background(), tst.name, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("failed test %d - job not created: %s", i, err)
+			continue
+		}
+		cntr := job.Spec.Template.Spec.Containers[0]
+		if cntr.Image != tst.image {
+			t.Errorf("failed test %d - expected image %s, but got %s", i, tst.image, cntr.Image)
+		}
+		if !reflect.DeepEqual(tst.env, cntr.Env) {
+			t.Errorf("failed test %d - expected env %v, but got %v", i, tst.env, cntr.Env)
+		}
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	tests := []struct {
+		cond   []batchv1.JobCondition
+		status string
+		err    bool
+	}{
+		{
+			cond:   []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+			status: "succeeded",
+			err:    false,
+		},
+		{
+			cond:   []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+			status: "failed",
+			err:    true,
+		},
+		{
+			cond:   []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionFalse}},
+			status: "running",
+			err:    true,
+		},
+		{
+			cond:   nil,
+			status: "running",
+			err:    true,
+		},
+	}
+
+	for i, tst := range tests {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "refreshdb", Namespace: "development"},
+			Status:     batchv1.JobStatus{Conditions: tst.cond},
+		}
+		jbt := &JobTrigger{}
+		status, err := jbt.waitForJob(fake.NewSimpleClientset(job), "development", "refreshdb", 10*time.Millisecond, 50*time.Millisecond)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if status != tst.status {
+			t.Errorf("failed test %d - expected status %s, but got %s", i, tst.status, status)
+		}
+	}
+}
+
+func TestJobCluster(t *testing.T) {
+	clients := map[string]*fake.Clientset{
+		"":       fake.NewSimpleClientset(),
+		"remote": fake.NewSimpleClientset(),
+		"other":  fake.NewSimpleClientset(),
+	}
+	orig := newKubeClient
+	newKubeClient = func(cluster string) (kubernetes.Interface, error) {
+		client, ok := clients[cluster]
+		if !ok {
+			return nil, fmt.Errorf("unknown cluster %s", cluster)
+		}
+		return client, nil
+	}
+	defer func() { newKubeClient = orig }()
+
+	job := "metadata:\n  name: report\nspec:\n  template:\n    spec:\n      containers:\n      - name: report\n        image: report:latest\n"
+	tests := []struct {
+		cluster string
+		objs    []*scanner.Object
+		out     string
+		err     bool
+	}{
+		{objs: []*scanner.Object{{Namespace: "dev", Name: "app1"}}, out: "", err: false},
+		{objs: []*scanner.Object{{Namespace: "dev", Name: "app1", Cluster: "remote"}}, out: "remote", err: false},
+		{cluster: "other", objs: []*scanner.Object{{Namespace: "dev", Name: "app1", Cluster: "remote"}}, out: "other", err: false},
+		{objs: []*scanner.Object{{Namespace: "dev", Name: "app1", Cluster: "remote"}, {Namespace: "dev", Name: "app2"}}, err: true},
+		{cluster: "other", objs: []*scanner.Object{{Namespace: "dev", Name: "app1", Cluster: "remote"}, {Namespace: "dev", Name: "app2"}}, out: "other", err: false},
+		{objs: []*scanner.Object{{Namespace: "dev", Name: "app1", Cluster: "unknown"}}, err: true},
+	}
+
+	for i, tst := range tests {
+		for _, client := range clients {
+			client.BatchV1().Jobs("dev").Delete(context.Background(), "report", metav1.DeleteOptions{})
+		}
+		jbt := &JobTrigger{}
+		jbt.SetConfig(Config{Settings: map[string]string{"job": job, "cluster": tst.cluster}})
+		_, err := jbt.Execute(Event{Objects: tst.objs})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err != nil {
+			continue
+		}
+		for cluster, client := range clients {
+			_, err := client.BatchV1().Jobs("dev").Get(context.Background(), "report", metav1.GetOptions{})
+			if (err == nil) != (cluster == tst.out) {
+				t.Errorf("failed test %d - expected job in cluster '%s' only, found in cluster '%s': %t", i, tst.out, cluster, err == nil)
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// pipelineKind describes the pipeline resource that will be created by a
+// PipelineTrigger.
+type pipelineKind struct {
+	resource schema.GroupVersionResource
+	kind     string
+	ref      string
+	spec     func(ref, sa string, params []keyValue) map[string]interface{}
+}
+
+var (
+	// tektonPipelineRun will start a Tekton PipelineRun for the pipeline
+	// referenced in the "pipeline" setting.
+	tektonPipelineRun = pipelineKind{
+		resource: schema.GroupVersionResource{Group: "tekton.dev", Version: "v1", Resource: "pipelineruns"},
+		kind:     "PipelineRun",
+		ref:      "pipeline",
+		spec: func(ref, sa string, params []keyValue) map[string]interface{} {
+			spec := map[string]interface{}{
+				"pipelineRef": map[string]interface{}{"name": ref},
+				"params":      pipelineParams(params),
+			}
+			if sa != "" {
+				spec["taskRunTemplate"] = map[string]interface{}{"serviceAccountName": sa}
+			}
+			return spec
+		},
+	}
+	// argoWorkflow will start an Argo Workflow for the workflow template
+	// referenced in the "workflowtemplate" setting.
+	argoWorkflow = pipelineKind{
+		resource: schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "workflows"},
+		kind:     "Workflow",
+		ref:      "workflowtemplate",
+		spec: func(ref, sa string, params []keyValue) map[string]interface{} {
+			spec := map[string]interface{}{
+				"workflowTemplateRef": map[string]interface{}{"name": ref},
+				"arguments":           map[string]interface{}{"parameters": pipelineParams(params)},
+			}
+			if sa != "" {
+				spec["serviceAccountName"] = sa
+			}
+			return spec
+		},
+	}
+)
+
+// PipelineTrigger is the object that implements triggers that start in-cluster
+// pipelines, such as Tekton PipelineRuns and Argo Workflows.
+type PipelineTrigger struct {
+	config  Config
+	kind    pipelineKind
+	m       sync.Mutex
+	clients map[string]dynamic.Interface
+}
+
+// newDynamicClient will return a new kubernetes dynamic client for given
+// cluster. An empty cluster is the cluster nightshift is running in.
+var newDynamicClient = func(cluster string) (dynamic.Interface, error) {
+	cfg, err := scanner.GetKubernetesForCluster(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed instantiating k8s client: %s", err)
+	}
+	client, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func init() {
+	RegisterModule("tekton", NewTektonTrigger)
+	RegisterModule("argo", NewArgoTrigger)
+}
+
+// NewTektonTrigger will instantiate a new PipelineTrigger object that will
+// create Tekton PipelineRuns.
+func NewTektonTrigger() (Trigger, error) {
+	return &PipelineTrigger{config: Config{}, kind: tektonPipelineRun}, nil
+}
+
+// NewArgoTrigger will instantiate a new PipelineTrigger object that will
+// create Argo Workflows.
+func NewArgoTrigger() (Trigger, error) {
+	return &PipelineTrigger{config: Config{}, kind: argoWorkflow}, nil
+}
+
+// SetConfig will set the generic configuration for this trigger.
+func (s *PipelineTrigger) SetConfig(cfg Config) {
+	s.config = cfg
+}
+
+// GetConfig will return the config applied for this trigger.
+func (s *PipelineTrigger) GetConfig() Config {
+	return s.config
+}
+
+// Execute will create the pipeline resource for the configured pipeline, and
+// will return the name of the created resource in the result.
+func (s *PipelineTrigger) Execute(evt Event) (Result, error) {
+	vars := getEventVars(s.config, evt)
+	cluster, err := eventCluster(s.config.Settings, evt.Objects)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.getClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := renderNamespace(s.config.Settings["namespace"], vars, evt.Objects)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := s.newResource(ns, vars)
+	if err != nil {
+		return nil, err
+	}
+	obj, err = client.Resource(s.kind.resource).Namespace(ns).Create(context.Background(), obj, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("Created resource", logging.TriggerId(s.config.Id), "kind", s.kind.kind, logging.KeyNamespace, ns, logging.KeyObject, obj.GetName())
+	res := Result{"namespace": ns, "kind": s.kind.kind, "name": obj.GetName()}
+	vars["resource"] = res
+	url, err := RenderTemplate(strings.TrimSpace(s.config.Settings["link"]), vars)
+	if err != nil {
+		return res, err
+	}
+	if url != "" {
+		res["url"] = url
+	}
+	return res, nil
+}
+
+// getClient will lazy load the kubernetes dynamic client for given cluster.
+func (s *PipelineTrigger) getClient(cluster string) (dynamic.Interface, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if client, ok := s.clients[cluster]; ok {
+		return client, nil
+	}
+	client, err := newDynamicClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if s.clients == nil {
+		s.clients = map[string]dynamic.Interface{}
+	}
+	s.clients[cluster] = client
+	return client, nil
+}
+
+// newResource will create the unstructured pipeline resource for the
+// configured pipeline reference and parameters.
+func (s *PipelineTrigger) newResource(ns string, vars map[string]interface{}) (*unstructured.Unstructured, error) {
+	ref, err := RenderTemplate(strings.TrimSpace(s.config.Settings[s.kind.ref]), vars)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		return nil, fmt.Errorf("no %s specified", s.kind.ref)
+	}
+	params, err := renderKeyValues(s.config.Settings["params"], vars)
+	if err != nil {
+		return nil, err
+	}
+	name, err := RenderTemplate(strings.TrimSpace(s.config.Settings["name"]), vars)
+	if err != nil {
+		return nil, err
+	}
+	sa := strings.TrimSpace(s.config.Settings["serviceaccount"])
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": s.kind.spec(ref, sa, params),
+		},
+	}
+	obj.SetAPIVersion(s.kind.resource.GroupVersion().String())
+	obj.SetKind(s.kind.kind)
+	obj.SetNamespace(ns)
+	obj.SetLabels(map[string]string{"app.kubernetes.io/managed-by": "nightshift"})
+	if name != "" {
+		obj.SetName(name)
+	} else {
+		obj.SetGenerateName(ref + "-")
+	}
+	return obj, nil
+}
+
+// pipelineParams will convert the given key value pairs to a list of name
+// value parameters, as used by both Tekton and Argo.
+func pipelineParams(params []keyValue) []interface{} {
+	prms := []interface{}{}
+	for _, p := range params {
+		prms = append(prms, map[string]interface{}{"name": p.Key, "value": p.Value})
+	}
+	return prms
+}
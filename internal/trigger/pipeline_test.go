@@ -0,0 +1,158 @@
+package trigger
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+func TestNewPipelineTrigger(t *testing.T) {
+	for _, typ := range []string{"tekton", "argo"} {
+		plt, err := New(typ)
+		if err != nil {
+			t.Errorf("failed test - could not instantiate %s module; %s", typ, err)
+		}
+		in := Config{
+			Settings: map[string]string{
+				"pipeline": "refreshdb",
+			},
+		}
+		plt.SetConfig(in)
+		out := plt.GetConfig()
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("failed test - configuration not correctly set; expected %v, got %v", in, out)
+		}
+	}
+}
+
+func TestPipelineExecute(t *testing.T) {
+	tests := []struct {
+		kind pipelineKind
+		cfg  Config
+		res  Result
+		spec map[string]interface{}
+		err  bool
+	}{
+		{
+			kind: tektonPipelineRun,
+			cfg:  Config{Settings: map[string]string{}},
+			err:  true,
+		},
+		{
+			kind: tektonPipelineRun,
+			cfg: Config{
+				Settings: map[string]string{
+					"pipeline":       "refreshdb",
+					"name":           "refreshdb-{{ (index .objects 0).Name }}",
+					"params":         "namespace={{ (index .objects 0).Namespace }}\nobjects={{ len .objects }}",
+					"serviceaccount": "pipeline",
+					"link":           "https://tekton/#/namespaces/{{ .resource.namespace }}/pipelineruns/{{ .resource.name }}",
+				},
+			},
+			res: Result{
+				"namespace": "development",
+				"kind":      "PipelineRun",
+				"name":      "refreshdb-app1",
+				"url":       "https://tekton/#/namespaces/development/pipelineruns/refreshdb-app1",
+			},
+			spec: map[string]interface{}{
+				"pipelineRef": map[string]interface{}{"name": "refreshdb"},
+				"params": []interface{}{
+					map[string]interface{}{"name": "namespace", "value": "development"},
+					map[string]interface{}{"name": "objects", "value": "1"},
+				},
+				"taskRunTemplate": map[string]interface{}{"serviceAccountName": "pipeline"},
+			},
+			err: false,
+		},
+		{
+			kind: argoWorkflow,
+			cfg: Config{
+				Settings: map[string]string{
+					"namespace":        "argo",
+					"workflowtemplate": "report",
+					"name":             "report-{{ (index .objects 0).Name }}",
+					"params":           "source={{ (index .objects 0).Namespace }}",
+				},
+			},
+			res: Result{
+				"namespace": "argo",
+				"kind":      "Workflow",
+				"name":      "report-app1",
+			},
+			spec: map[string]interface{}{
+				"workflowTemplateRef": map[string]interface{}{"name": "report"},
+				"arguments": map[string]interface{}{
+					"parameters": []interface{}{
+						map[string]interface{}{"name": "source", "value": "development"},
+					},
+				},
+			},
+			err: false,
+		},
+		{
+			kind: argoWorkflow,
+			cfg: Config{
+				Settings: map[string]string{
+					"pipeline": "report",
+				},
+			},
+			err: true,
+		},
+		{
+			kind: tektonPipelineRun,
+			cfg: Config{
+				Settings: map[string]string{
+					"pipeline": "refreshdb",
+					"params":   "invalid",
+				},
+			},
+			err: true,
+		},
+		{
+			kind: tektonPipelineRun,
+			cfg: Config{
+				Settings: map[string]string{
+					"pipeline": "refreshdb",
+					"name":     "{{ malformed template }}",
+				},
+			},
+			err: true,
+		},
+	}
+
+	objs := []*scanner.Object{{Namespace: "development", Name: "app1"}}
+	for i, tst := range tests {
+		client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+		plt := &PipelineTrigger{kind: tst.kind, clients: map[string]dynamic.Interface{"": client}}
+		plt.SetConfig(tst.cfg)
+		res, err := plt.Execute(Event{Objects: objs})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(tst.res, res) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.res, res)
+		}
+		obj, err := client.Resource(tst.kind.resource).Namespace(res["namespace"]).Get(context.Background(), res["name"], metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("failed test %d - resource not created: %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(tst.spec, obj.Object["spec"]) {
+			t.Errorf("failed test %d - expected spec %v, but got %v", i, tst.spec, obj.Object["spec"])
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package trigger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signatureFormat describes how a webhook payload is signed, and how the
+// signature is added to the request.
+type signatureFormat struct {
+	header    string
+	timestamp bool
+	value     func(ts, sig string) string
+}
+
+// signatureFormats are the supported signature conventions. The "nightshift"
+// format (default) signs "<timestamp>.<body>" and adds the signature as
+// "sha256=<hex>". The "github" format signs the body only, similar to the
+// X-Hub-Signature-256 header of GitHub webhooks. The "stripe" format signs
+// "<timestamp>.<body>" and adds both as "t=<timestamp>,v1=<hex>", similar to
+// the Stripe-Signature header of Stripe webhooks.
+var signatureFormats = map[string]signatureFormat{
+	"nightshift": {
+		header:    "X-Nightshift-Signature",
+		timestamp: true,
+		value:     func(ts, sig string) string { return "sha256=" + sig },
+	},
+	"github": {
+		header:    "X-Hub-Signature-256",
+		timestamp: false,
+		value:     func(ts, sig string) string { return "sha256=" + sig },
+	},
+	"stripe": {
+		header:    "Stripe-Signature",
+		timestamp: true,
+		value:     func(ts, sig string) string { return "t=" + ts + ",v1=" + sig },
+	},
+}
+
+// signatureHeaders will return the headers that should be added to a request
+// with given body, signed with given key at given time. The name of the
+// signature header can be overridden with given header, if not empty. The
+// timestamp is added to the headers as X-Nightshift-Timestamp (or the given
+// timestamp header) so receivers can reject replayed requests.
+func signatureHeaders(format, header, tsheader string, key, body []byte, now time.Time) (map[string]string, error) {
+	if format == "" {
+		format = "nightshift"
+	}
+	sf, ok := signatureFormats[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("invalid signature format '%s'", format)
+	}
+	if header == "" {
+		header = sf.header
+	}
+	if tsheader == "" {
+		tsheader = "X-Nightshift-Timestamp"
+	}
+	ts := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, key)
+	if sf.timestamp {
+		mac.Write([]byte(ts + "."))
+	}
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+	return map[string]string{
+		header:   sf.value(ts, sig),
+		tsheader: ts,
+	}, nil
+}
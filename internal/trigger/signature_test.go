@@ -0,0 +1,114 @@
+package trigger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignatureHeaders(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"a":1}`)
+	tests := []struct {
+		format   string
+		header   string
+		tsheader string
+		out      map[string]string
+		err      bool
+	}{
+		{
+			format: "",
+			out: map[string]string{
+				"X-Nightshift-Signature": "sha256=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686",
+				"X-Nightshift-Timestamp": "1700000000",
+			},
+		},
+		{
+			format: "github",
+			out: map[string]string{
+				"X-Hub-Signature-256":    "sha256=aa9e2e3575f5d7098b6caccd790888c36d5fdb63342a73bada2d6a51747a8494",
+				"X-Nightshift-Timestamp": "1700000000",
+			},
+		},
+		{
+			format:   "Stripe",
+			tsheader: "X-Timestamp",
+			out: map[string]string{
+				"Stripe-Signature": "t=1700000000,v1=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686",
+				"X-Timestamp":      "1700000000",
+			},
+		},
+		{
+			format: "nightshift",
+			header: "X-Signature",
+			out: map[string]string{
+				"X-Signature":            "sha256=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686",
+				"X-Nightshift-Timestamp": "1700000000",
+			},
+		},
+		{
+			format: "md5",
+			err:    true,
+		},
+	}
+
+	for i, tst := range tests {
+		out, err := signatureHeaders(tst.format, tst.header, tst.tsheader, []byte("secret"), body, now)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err == nil && !reflect.DeepEqual(tst.out, out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, out)
+		}
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	var sig, ts string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sig = r.Header.Get("X-Nightshift-Signature")
+		ts = r.Header.Get("X-Nightshift-Timestamp")
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	wht := &WebhookTrigger{}
+	wht.SetConfig(Config{Settings: map[string]string{
+		"url":        srv.URL,
+		"body":       `{"trigger":"{{ .trigger.id }}"}`,
+		"signingkey": "secret",
+	}})
+	if _, err := wht.Execute(Event{}); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	epoch, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || time.Since(time.Unix(epoch, 0)) > time.Minute {
+		t.Errorf("invalid timestamp header; got %s", ts)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	if exp := "sha256=" + hex.EncodeToString(mac.Sum(nil)); sig != exp {
+		t.Errorf("invalid signature; expected %s, got %s", exp, sig)
+	}
+
+	wht.SetConfig(Config{Settings: map[string]string{
+		"url":             srv.URL,
+		"signingkey":      "secret",
+		"signatureformat": "invalid",
+	}})
+	if _, err := wht.Execute(Event{}); err == nil {
+		t.Errorf("expected err for invalid signature format, but got none")
+	}
+}
@@ -2,26 +2,74 @@ package trigger
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
-	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 
+	"github.com/joyrex2001/nightshift/internal/logging"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 )
 
+// kubeClient is the kubernetes client of the cluster nightshift is running
+// in, used by the secret and configmap template functions, and to resolve
+// secret credentials. It is lazy loaded when it is used.
+var (
+	kubeOnce   sync.Once
+	kubeClient kubernetes.Interface
+	kubeErr    error
+)
+
+// newKubeClient will return a new kubernetes client for given cluster. An
+// empty cluster is the cluster nightshift is running in.
+var newKubeClient = func(cluster string) (kubernetes.Interface, error) {
+	cfg, err := scanner.GetKubernetesForCluster(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed instantiating k8s client: %s", err)
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 // RenderTemplate will render provided template. It will return an error if the
-// rendering of the template fails.
+// rendering of the template fails. The secret and configmap functions will
+// read from the cluster in the "cluster" value, if any.
 func RenderTemplate(templ string, values interface{}) (string, error) {
+	cluster := ""
+	if vars, ok := values.(map[string]interface{}); ok {
+		cluster, _ = vars["cluster"].(string)
+	}
 	var funcs = template.FuncMap{
-		"env":  templateEnv,
-		"add":  templateAdd,
-		"now":  templateNow,
-		"time": templateTime,
+		"env":         templateEnv,
+		"add":         templateAdd,
+		"now":         templateNow,
+		"time":        templateTime,
+		"addDuration": templateAddDuration,
+		"toJson":      templateToJson,
+		"join":        templateJoin,
+		"upper":       strings.ToUpper,
+		"lower":       strings.ToLower,
+		"replace":     templateReplace,
+		"quote":       strconv.Quote,
+		"namespaces":  templateNamespaces,
+		"names":       templateNames,
+		"secret": func(namespace, name, key string) (string, error) {
+			return templateSecret(cluster, namespace, name, key)
+		},
+		"configmap": func(namespace, name, key string) (string, error) {
+			return templateConfigMap(cluster, namespace, name, key)
+		},
 	}
 	// render template
 	tobj, err := template.New("template").Funcs(funcs).Parse(templ)
@@ -50,11 +98,11 @@ func templateNow() string {
 func templateAdd(a, b string) string {
 	aa, err := strconv.ParseInt(a, 10, 64)
 	if err != nil {
-		glog.Errorf("invalid value given for add: %s", err)
+		logger.Error("Invalid value given for add", logging.Err(err))
 	}
 	bb, err := strconv.ParseInt(b, 10, 64)
 	if err != nil {
-		glog.Errorf("invalid value given for add: %s", err)
+		logger.Error("Invalid value given for add", logging.Err(err))
 	}
 	return fmt.Sprintf("%d", aa+bb)
 }
@@ -67,7 +115,7 @@ func templateAdd(a, b string) string {
 func templateTime(template, epoch string) string {
 	ep, err := strconv.ParseInt(epoch, 10, 64)
 	if err != nil {
-		glog.Errorf("invalid time given for time function: %s", err)
+		logger.Error("Invalid time given for time function", logging.Err(err))
 	}
 	switch strings.ToLower(template) {
 	case "rfc3339":
@@ -81,6 +129,97 @@ func templateTime(template, epoch string) string {
 	return t.Format(template)
 }
 
+// templateAddDuration will add the given duration (e.g. "24h" or "-30m") to
+// the given epoch, and will return the result as an epoch.
+func templateAddDuration(duration, epoch string) (string, error) {
+	dur, err := time.ParseDuration(duration)
+	if err != nil {
+		return "", err
+	}
+	ep, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid time given for addDuration: %s", err)
+	}
+	return fmt.Sprintf("%d", time.Unix(ep, 0).Add(dur).Unix()), nil
+}
+
+// templateToJson will return the json representation of given value.
+func templateToJson(v interface{}) (string, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// templateJoin will concatenate the given list of strings, separated by the
+// given separator.
+func templateJoin(sep string, list []string) string {
+	return strings.Join(list, sep)
+}
+
+// templateReplace will replace all occurences of old with new in given
+// string.
+func templateReplace(old, new, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
+// templateNamespaces will return the unique namespaces of given objects in
+// alphabetical order.
+func templateNamespaces(objs []*scanner.Object) []string {
+	_, nss := groupByNamespace(objs)
+	return nss
+}
+
+// templateNames will return the names of given objects.
+func templateNames(objs []*scanner.Object) []string {
+	names := []string{}
+	for _, obj := range objs {
+		names = append(names, obj.Name)
+	}
+	return names
+}
+
+// templateSecret will return the value of given key in the given secret in
+// given cluster.
+func templateSecret(cluster, namespace, name, key string) (string, error) {
+	val, err := readSecretRef(cluster, namespace+"/"+name+"/"+key)
+	if err != nil {
+		return "", err
+	}
+	return string(val), nil
+}
+
+// templateConfigMap will return the value of given key in the given
+// configmap in given cluster.
+func templateConfigMap(cluster, namespace, name, key string) (string, error) {
+	client, err := getKubeClient(cluster)
+	if err != nil {
+		return "", err
+	}
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	val, ok := cm.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in configmap %s/%s", key, namespace, name)
+	}
+	return val, nil
+}
+
+// getKubeClient will return the kubernetes client for given cluster. The
+// client of the cluster nightshift is running in is lazy loaded once.
+func getKubeClient(cluster string) (kubernetes.Interface, error) {
+	if cluster != "" {
+		return newKubeClient(cluster)
+	}
+	kubeOnce.Do(func() {
+		kubeClient, kubeErr = newKubeClient("")
+	})
+	return kubeClient, kubeErr
+}
+
 // getTemplateVars will combine the settings map with the scanner objects list.
 // The settings will be added as "settings" and the objects will be added as
 // 'objects' where it will add the scanner objects array.
@@ -90,3 +229,21 @@ func getTemplateVars(settings map[string]string, objs []*scanner.Object) map[str
 	vars["settings"] = settings
 	return vars
 }
+
+// getEventVars will return the template variables for the given trigger
+// config and event. In addition to the variables provided by getTemplateVars,
+// it will add the trigger id and type as "trigger", the description of the
+// schedules that caused the event as "schedule", the event time (as epoch)
+// as "event" and the cluster of the objects as "cluster".
+func getEventVars(cfg Config, evt Event) map[string]interface{} {
+	vars := getTemplateVars(cfg.Settings, evt.Objects)
+	at := evt.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	vars["trigger"] = map[string]string{"id": cfg.Id, "type": cfg.Type}
+	vars["schedule"] = evt.Schedule
+	vars["event"] = map[string]string{"time": fmt.Sprintf("%d", at.Unix())}
+	vars["cluster"], _ = eventCluster(cfg.Settings, evt.Objects)
+	return vars
+}
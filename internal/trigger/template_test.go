@@ -1,8 +1,16 @@
 package trigger
 
 import (
+	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/joyrex2001/nightshift/internal/scanner"
 )
@@ -137,6 +145,65 @@ func TestRenderTemplate(t *testing.T) {
 			setup:  func() {},
 			err:    false,
 		},
+		{
+			in:     `{{ upper "abc" }} {{ lower "ABC" }} {{ replace "-" "_" "a-b-c" }} {{ quote "a\"b" }}`,
+			out:    `ABC abc a_b_c "a\"b"`,
+			values: Config{},
+			setup:  func() {},
+			err:    false,
+		},
+		{
+			in:     `{{ .objects | namespaces | join "," }} {{ .objects | names | join " " }}`,
+			out:    `default,development app1 app2 app3`,
+			values: Config{},
+			objs: []*scanner.Object{
+				{Namespace: "development", Name: "app1"},
+				{Namespace: "default", Name: "app2"},
+				{Namespace: "development", Name: "app3"},
+			},
+			setup: func() {},
+			err:   false,
+		},
+		{
+			in:     `{{ .objects | names | toJson }} {{ (index .objects 0).Name | toJson }}`,
+			out:    `["app1","app2","app3"] "app1"`,
+			values: Config{},
+			objs: []*scanner.Object{
+				{Namespace: "development", Name: "app1"},
+				{Namespace: "default", Name: "app2"},
+				{Namespace: "development", Name: "app3"},
+			},
+			setup: func() {},
+			err:   false,
+		},
+		{
+			in:     `{{ .objects | names | toJson }}`,
+			out:    `[]`,
+			values: Config{},
+			setup:  func() {},
+			err:    false,
+		},
+		{
+			in:     `{{ addDuration "24h" "0" | time "rfc3339" }} {{ addDuration "-30m" "3600" }}`,
+			out:    `1970-01-02T00:00:00Z 1800`,
+			values: Config{},
+			setup:  func() {},
+			err:    false,
+		},
+		{
+			in:     `{{ addDuration "1x" "0" }}`,
+			out:    ``,
+			values: Config{},
+			setup:  func() {},
+			err:    true,
+		},
+		{
+			in:     `{{ addDuration "1h" "a" }}`,
+			out:    ``,
+			values: Config{},
+			setup:  func() {},
+			err:    true,
+		},
 	}
 
 	os.Setenv("TZ", "UTC")
@@ -155,3 +222,125 @@ func TestRenderTemplate(t *testing.T) {
 		}
 	}
 }
+
+// setKubeClient will replace the lazy loaded kubernetes client with given
+// client, and will return a function that restores the lazy loading.
+func setKubeClient(client kubernetes.Interface) func() {
+	kubeOnce.Do(func() {})
+	kubeClient = client
+	return func() {
+		kubeOnce = sync.Once{}
+		kubeClient, kubeErr = nil, nil
+	}
+}
+
+func TestTemplateLookups(t *testing.T) {
+	restore := setKubeClient(fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "development", Name: "creds"},
+			Data:       map[string][]byte{"token": []byte("s3cr3t")},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "development", Name: "settings"},
+			Data:       map[string]string{"url": "http://example.com"},
+		},
+	))
+	defer restore()
+
+	remote := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "development", Name: "creds"},
+			Data:       map[string][]byte{"token": []byte("remote")},
+		},
+	)
+	orig := newKubeClient
+	newKubeClient = func(cluster string) (kubernetes.Interface, error) {
+		if cluster != "remote" {
+			return nil, fmt.Errorf("unknown cluster %s", cluster)
+		}
+		return remote, nil
+	}
+	defer func() { newKubeClient = orig }()
+
+	tests := []struct {
+		in      string
+		cluster string
+		out     string
+		err     bool
+	}{
+		{
+			in:  `{{ secret "development" "creds" "token" }}`,
+			out: `s3cr3t`,
+			err: false,
+		},
+		{
+			in:  `{{ configmap "development" "settings" "url" }}`,
+			out: `http://example.com`,
+			err: false,
+		},
+		{
+			in:  `{{ secret "development" "creds" "password" }}`,
+			err: true,
+		},
+		{
+			in:  `{{ secret "default" "creds" "token" }}`,
+			err: true,
+		},
+		{
+			in:  `{{ configmap "development" "settings" "nope" }}`,
+			err: true,
+		},
+		{
+			in:      `{{ secret "development" "creds" "token" }}`,
+			cluster: "remote",
+			out:     `remote`,
+			err:     false,
+		},
+		{
+			in:      `{{ configmap "development" "settings" "url" }}`,
+			cluster: "remote",
+			err:     true,
+		},
+		{
+			in:      `{{ secret "development" "creds" "token" }}`,
+			cluster: "unknown",
+			err:     true,
+		},
+	}
+
+	for i, tst := range tests {
+		out, err := RenderTemplate(tst.in, map[string]interface{}{"cluster": tst.cluster})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err == nil && tst.out != out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, out)
+		}
+	}
+}
+
+func TestGetEventVars(t *testing.T) {
+	cfg := Config{Id: "refreshdb", Type: "webhook", Settings: map[string]string{"key": "value"}}
+	evt := Event{
+		Objects:  []*scanner.Object{{Namespace: "development", Name: "app1", Cluster: "remote"}},
+		Time:     time.Unix(3600, 0),
+		Schedule: "mon 18:00 replicas=0 trigger=refreshdb",
+	}
+	vars := getEventVars(cfg, evt)
+	out, err := RenderTemplate(`{{ .trigger.id }}/{{ .trigger.type }} {{ .event.time }} {{ .schedule }} {{ .settings.key }} {{ .objects | names | join "," }} {{ .cluster }}`, vars)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	exp := `refreshdb/webhook 3600 mon 18:00 replicas=0 trigger=refreshdb value app1 remote`
+	if out != exp {
+		t.Errorf("failed getEventVars - expected %s, but got %s", exp, out)
+	}
+
+	vars = getEventVars(cfg, Event{})
+	if vars["event"].(map[string]string)["time"] == "" {
+		t.Errorf("failed getEventVars - expected event time to default to now")
+	}
+}
@@ -1,9 +1,12 @@
 package trigger
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/joyrex2001/nightshift/internal/logging"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 )
 
@@ -11,7 +14,34 @@ import (
 type Trigger interface {
 	SetConfig(Config)
 	GetConfig() Config
-	Execute([]*scanner.Object) error
+	Execute(Event) (Result, error)
+}
+
+// Event contains the details of the scale event that caused the execution of
+// a trigger; the objects that were scaled, the time of the event and the
+// description of the schedule(s) that caused the event. Context contains the
+// trace of the execution, which is propagated to the called endpoints.
+type Event struct {
+	Objects  []*scanner.Object
+	Time     time.Time
+	Schedule string
+	Context  context.Context
+}
+
+// Result contains the details of a trigger execution as reported by the
+// trigger module, e.g. the exit status of a command.
+type Result map[string]string
+
+// Execution describes a single execution of a trigger, and is used to keep a
+// history of executed triggers.
+type Execution struct {
+	Id       string        `json:"id"`
+	Type     string        `json:"type"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Objects  []string      `json:"objects"`
+	Result   Result        `json:"result"`
+	Error    string        `json:"error"`
 }
 
 // Config is the configuration for this trigger, and contains a hashmap with
@@ -27,6 +57,8 @@ type Factory func() (Trigger, error)
 
 var modules map[string]Factory
 
+var logger = logging.For(logging.Trigger)
+
 // RegisterModule will add the provided module, with given factory method to
 // the list of available modules in order to support dependency injection, as
 // well as easing up modular development for triggers.
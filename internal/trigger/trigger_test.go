@@ -2,8 +2,6 @@ package trigger
 
 import (
 	"testing"
-
-	"github.com/joyrex2001/nightshift/internal/scanner"
 )
 
 type mock struct {
@@ -22,8 +20,8 @@ func (m *mock) GetConfig() Config {
 	return m.cfg
 }
 
-func (m *mock) Execute([]*scanner.Object) error {
-	return nil
+func (m *mock) Execute(Event) (Result, error) {
+	return nil, nil
 }
 
 func getFactory(typ string, m *mock) Factory {
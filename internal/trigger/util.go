@@ -0,0 +1,89 @@
+package trigger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// getLines will split given setting in separate lines, and will return all
+// lines that are not empty, with leading and trailing spaces removed.
+func getLines(setting string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.Replace(setting, "\r\n", "\n", -1), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// truncate will shorten given string to the given maximum number of bytes.
+func truncate(str string, max int) string {
+	if len(str) > max {
+		return str[:max]
+	}
+	return str
+}
+
+// keyValue is a single key value pair as specified in a setting.
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+// renderKeyValues will parse given setting, which contains one key=value per
+// line, and will return the list of key value pairs with each value rendered
+// as a template.
+func renderKeyValues(setting string, vars map[string]interface{}) ([]keyValue, error) {
+	kvs := []keyValue{}
+	for _, line := range getLines(setting) {
+		flds := strings.SplitN(line, "=", 2)
+		if len(flds) != 2 || strings.TrimSpace(flds[0]) == "" {
+			return nil, fmt.Errorf("invalid key value pair specified '%s'", line)
+		}
+		val, err := RenderTemplate(strings.TrimSpace(flds[1]), vars)
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, keyValue{strings.TrimSpace(flds[0]), val})
+	}
+	return kvs, nil
+}
+
+// renderNamespace will render the given namespace setting. If no namespace is
+// configured, it will return the namespace of the first object that caused
+// the trigger.
+func renderNamespace(setting string, vars map[string]interface{}, objs []*scanner.Object) (string, error) {
+	ns, err := RenderTemplate(strings.TrimSpace(setting), vars)
+	if err != nil {
+		return "", err
+	}
+	if ns == "" && len(objs) > 0 {
+		ns = objs[0].Namespace
+	}
+	if ns == "" {
+		return "", fmt.Errorf("no namespace specified")
+	}
+	return ns, nil
+}
+
+// eventCluster will return the cluster in which the kubernetes resources of a
+// trigger are created or read. This is the cluster setting of the trigger, or
+// the cluster of the objects that caused the trigger. It will return an error
+// if the objects are in different clusters, and no cluster is configured. An
+// empty cluster is the cluster in which nightshift is running.
+func eventCluster(settings map[string]string, objs []*scanner.Object) (string, error) {
+	if cluster := strings.TrimSpace(settings["cluster"]); cluster != "" {
+		return cluster, nil
+	}
+	cluster := ""
+	for i, obj := range objs {
+		if i > 0 && obj.Cluster != cluster {
+			return "", fmt.Errorf("objects of multiple clusters, no cluster specified")
+		}
+		cluster = obj.Cluster
+	}
+	return cluster, nil
+}
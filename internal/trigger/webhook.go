@@ -2,16 +2,18 @@ package trigger
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/golang/glog"
-
-	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/tracing"
 )
 
 // WebhookTrigger is the object that implements http based triggers.
@@ -39,39 +41,92 @@ func (s *WebhookTrigger) GetConfig() Config {
 }
 
 // Execute will trigger the webhook.
-func (s *WebhookTrigger) Execute(objs []*scanner.Object) error {
-	vars := getTemplateVars(s.config.Settings, objs)
+func (s *WebhookTrigger) Execute(evt Event) (Result, error) {
+	vars := getEventVars(s.config, evt)
 	cli, err := s.newClient()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req, err := s.newRequest(vars)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	tracing.Inject(evt.Context, req.Header)
 	resp, err := cli.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
+	res := Result{"status": strconv.Itoa(resp.StatusCode)}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("error webhook; status=%s(%d)", resp.Status, resp.StatusCode)
+		return res, fmt.Errorf("error webhook; status=%s(%d)", resp.Status, resp.StatusCode)
 	}
 	body, _ := ioutil.ReadAll(resp.Body)
-	glog.V(5).Infof("url: %s, status: %s, body: %s", s.config.Settings["url"], resp.Status, body)
-	return nil
+	logger.Log(context.Background(), logging.LevelTrace, "Webhook response", logging.TriggerId(s.config.Id), "url", s.config.Settings["url"], "status", resp.Status, "body", string(body))
+	return res, nil
 }
 
 // newClient will create a new http.Client object with the correct settings, as
-// reflected in the config.
+// reflected in the config. If a CA bundle (cacert), or a client certificate
+// (clientcert and clientkey) is configured, the client will use these for
+// tls connections.
 func (s *WebhookTrigger) newClient() (*http.Client, error) {
 	timeout, err := s.getTimeout()
 	if err != nil {
 		return nil, err
 	}
-	return &http.Client{
+	cli := &http.Client{
 		Timeout: timeout,
-	}, nil
+	}
+	tlscfg, err := s.getTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlscfg != nil {
+		cli.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlscfg,
+		}
+	}
+	return cli, nil
+}
+
+// getTLSConfig will return the tls configuration for the configured CA bundle
+// and client certificate. It will return nil if none of these are configured.
+func (s *WebhookTrigger) getTLSConfig() (*tls.Config, error) {
+	ca := s.config.Settings["cacert"]
+	cert := s.config.Settings["clientcert"]
+	key := s.config.Settings["clientkey"]
+	if ca == "" && cert == "" && key == "" {
+		return nil, nil
+	}
+	tlscfg := &tls.Config{}
+	if ca != "" {
+		pem, err := resolveCredential(ca)
+		if err != nil {
+			return nil, fmt.Errorf("error reading cacert: %s", err)
+		}
+		tlscfg.RootCAs = x509.NewCertPool()
+		if !tlscfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in cacert")
+		}
+	}
+	if cert != "" || key != "" {
+		certpem, err := resolveCredential(cert)
+		if err != nil {
+			return nil, fmt.Errorf("error reading clientcert: %s", err)
+		}
+		keypem, err := resolveCredential(key)
+		if err != nil {
+			return nil, fmt.Errorf("error reading clientkey: %s", err)
+		}
+		crt, err := tls.X509KeyPair(certpem, keypem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %s", err)
+		}
+		tlscfg.Certificates = []tls.Certificate{crt}
+	}
+	return tlscfg, nil
 }
 
 // newRequest will create a http.Request for the configured url, body and
@@ -97,9 +152,64 @@ func (s *WebhookTrigger) newRequest(vars map[string]interface{}) (*http.Request,
 	for headr, val := range headers {
 		req.Header.Set(headr, val)
 	}
+	if err := s.setAuth(req); err != nil {
+		return nil, err
+	}
+	if err := s.sign(req, body.Bytes()); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
 
+// sign will add the signature headers to given request, if a signingkey has
+// been configured. The key can refer to a secret or a file. The signature
+// format (signatureformat), the name of the signature header
+// (signatureheader) and the name of the timestamp header (timestampheader)
+// are configurable.
+func (s *WebhookTrigger) sign(req *http.Request, body []byte) error {
+	ref := s.config.Settings["signingkey"]
+	if ref == "" {
+		return nil
+	}
+	key, err := resolveCredentialString(ref)
+	if err != nil {
+		return fmt.Errorf("error reading signingkey: %s", err)
+	}
+	headers, err := signatureHeaders(
+		strings.TrimSpace(s.config.Settings["signatureformat"]),
+		strings.TrimSpace(s.config.Settings["signatureheader"]),
+		strings.TrimSpace(s.config.Settings["timestampheader"]),
+		[]byte(key), body, time.Now())
+	if err != nil {
+		return err
+	}
+	for headr, val := range headers {
+		req.Header.Set(headr, val)
+	}
+	return nil
+}
+
+// setAuth will add the configured basic auth (username and password) or
+// bearer token (bearertoken) credentials to the given request. The password
+// and bearer token can refer to a secret or a file.
+func (s *WebhookTrigger) setAuth(req *http.Request) error {
+	if user := strings.TrimSpace(s.config.Settings["username"]); user != "" {
+		pass, err := resolveCredentialString(s.config.Settings["password"])
+		if err != nil {
+			return fmt.Errorf("error reading password: %s", err)
+		}
+		req.SetBasicAuth(user, pass)
+	}
+	if ref := s.config.Settings["bearertoken"]; ref != "" {
+		token, err := resolveCredentialString(ref)
+		if err != nil {
+			return fmt.Errorf("error reading bearertoken: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
 func (s *WebhookTrigger) getUrl(vars map[string]interface{}) (string, error) {
 	url := strings.TrimSpace(s.config.Settings["url"])
 	url, err := RenderTemplate(url, vars)
@@ -112,9 +222,9 @@ func (s *WebhookTrigger) getUrl(vars map[string]interface{}) (string, error) {
 	return url, nil
 }
 
-// getBody will process the configured body, and return an io.ReadWriter for
+// getBody will process the configured body, and return a bytes.Buffer for
 // that body.
-func (s *WebhookTrigger) getBody(vars map[string]interface{}) (io.ReadWriter, error) {
+func (s *WebhookTrigger) getBody(vars map[string]interface{}) (*bytes.Buffer, error) {
 	buf := new(bytes.Buffer)
 	body, err := RenderTemplate(s.config.Settings["body"], vars)
 	if err != nil {
@@ -151,7 +261,8 @@ func (s *WebhookTrigger) getMethod() string {
 }
 
 // getHeaders will parse the headers configuration, and return a map containing
-// the headers and its' values.
+// the headers and its' values. A header value can refer to a secret or a file
+// in which case the value will be read from that secret or file.
 func (s *WebhookTrigger) getHeaders(vars map[string]interface{}) (map[string]string, error) {
 	headers := map[string]string{}
 	chdrs := strings.Split(strings.Replace(s.config.Settings["headers"], "\r\n", "\n", -1), "\n")
@@ -159,7 +270,7 @@ func (s *WebhookTrigger) getHeaders(vars map[string]interface{}) (map[string]str
 		if header == "" {
 			continue
 		}
-		flds := strings.Split(header, ":")
+		flds := strings.SplitN(header, ":", 2)
 		if len(flds) != 2 {
 			return nil, fmt.Errorf("invalid header specified '%s'", header)
 		}
@@ -172,6 +283,11 @@ func (s *WebhookTrigger) getHeaders(vars map[string]interface{}) (map[string]str
 		if err != nil {
 			return headers, err
 		}
+		if isCredentialRef(val) {
+			if val, err = resolveCredentialString(val); err != nil {
+				return headers, fmt.Errorf("error reading header %s: %s", flds[0], err)
+			}
+		}
 		headers[flds[0]] = val
 	}
 	return headers, nil
@@ -1,9 +1,25 @@
 package trigger
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestNewWebhookTrigger(t *testing.T) {
@@ -320,3 +336,194 @@ func TestRequest(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestAuth(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("s3cr3t\n"), 0600)
+
+	tests := []struct {
+		settings map[string]string
+		auth     string
+		header   string
+		err      bool
+	}{
+		{
+			settings: map[string]string{"bearertoken": "file:" + tokenFile},
+			auth:     "Bearer s3cr3t",
+		},
+		{
+			settings: map[string]string{"username": "nightshift", "password": "file:" + tokenFile},
+			auth:     "Basic bmlnaHRzaGlmdDpzM2NyM3Q=",
+		},
+		{
+			settings: map[string]string{"headers": "X-Token: file:" + tokenFile},
+			header:   "s3cr3t",
+		},
+		{
+			settings: map[string]string{"headers": "X-Token: http://localhost"},
+			header:   "http://localhost",
+		},
+		{
+			settings: map[string]string{"bearertoken": "file:" + filepath.Join(dir, "missing")},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"headers": "X-Token: file:" + filepath.Join(dir, "missing")},
+			err:      true,
+		},
+	}
+
+	for i, tst := range tests {
+		tst.settings["url"] = "http://localhost:8080"
+		wht := &WebhookTrigger{}
+		wht.SetConfig(Config{Settings: tst.settings})
+		req, err := wht.newRequest(getTemplateVars(tst.settings, nil))
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err != nil {
+			continue
+		}
+		if auth := req.Header.Get("Authorization"); auth != tst.auth {
+			t.Errorf("failed test %d - expected authorization %s, but got %s", i, tst.auth, auth)
+		}
+		if hdr := req.Header.Get("X-Token"); hdr != tst.header {
+			t.Errorf("failed test %d - expected header %s, but got %s", i, tst.header, hdr)
+		}
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	clientCerts := 0
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCerts = len(r.TLS.PeerCertificates)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600)
+	certFile, keyFile := writeClientCert(t, dir)
+
+	tests := []struct {
+		settings map[string]string
+		certs    int
+		err      bool
+		reqerr   bool
+	}{
+		{
+			settings: map[string]string{},
+			reqerr:   true,
+		},
+		{
+			settings: map[string]string{"cacert": "file:" + caFile},
+			certs:    0,
+		},
+		{
+			settings: map[string]string{"cacert": "file:" + caFile, "clientcert": "file:" + certFile, "clientkey": "file:" + keyFile},
+			certs:    1,
+		},
+		{
+			settings: map[string]string{"cacert": "invalid"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"cacert": "file:" + caFile, "clientcert": "file:" + certFile},
+			err:      true,
+		},
+	}
+
+	for i, tst := range tests {
+		clientCerts = 0
+		tst.settings["url"] = srv.URL
+		tst.settings["timeout"] = "5s"
+		wht := &WebhookTrigger{}
+		wht.SetConfig(Config{Settings: tst.settings})
+		_, err := wht.Execute(Event{})
+		if tst.err || tst.reqerr {
+			if err == nil {
+				t.Errorf("failed test %d - expected err, but got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if clientCerts != tst.certs {
+			t.Errorf("failed test %d - expected %d client certificates, but got %d", i, tst.certs, clientCerts)
+		}
+	}
+}
+
+// writeClientCert will create a self-signed client certificate, and will
+// write the certificate and key to given directory.
+func writeClientCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %s", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate: %s", err)
+	}
+	keyder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshalling key: %s", err)
+	}
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyder}), 0600)
+	return certFile, keyFile
+}
+
+func TestTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	tests := []struct {
+		ctx context.Context
+		out string
+	}{
+		{ctx: nil, out: ""},
+		{ctx: context.Background(), out: ""},
+		{
+			ctx: trace.ContextWithSpanContext(context.Background(), sc),
+			out: "00-01000000000000000000000000000000-0200000000000000-01",
+		},
+	}
+	for i, tst := range tests {
+		traceparent = ""
+		wht, _ := NewWebhookTrigger()
+		wht.SetConfig(Config{Settings: map[string]string{"url": srv.URL}})
+		if _, err := wht.Execute(Event{Context: tst.ctx}); err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if traceparent != tst.out {
+			t.Errorf("failed test %d - expected traceparent %s, got %s", i, tst.out, traceparent)
+		}
+	}
+}
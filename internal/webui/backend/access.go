@@ -0,0 +1,177 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// accessReviewTTL is the duration for which the result of a
+// SubjectAccessReview is cached.
+const accessReviewTTL = time.Minute
+
+// accessCache will cache the results of SubjectAccessReviews.
+type accessCache struct {
+	m       sync.Mutex
+	reviews map[string]*cachedReview
+}
+
+type cachedReview struct {
+	allowed bool
+	expires time.Time
+}
+
+// Authorize will check if the user of given request is allowed to perform
+// given verb on all given objects, using a SubjectAccessReview in the cluster
+// of the object. It will return an error for the first object that is not
+// allowed. If authorization is disabled, it will always return nil.
+func (f *handler) Authorize(r *http.Request, verb string, objects []*scanner.Object) error {
+	if !f.authorize {
+		return nil
+	}
+	usr := requestUser(r)
+	for _, obj := range objects {
+		allowed, err := f.allowed(usr, verb, obj.Cluster, obj.Type, obj.Namespace, obj.Name)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("user %s is not allowed to %s %s %s/%s", usr.Name, verb, obj.Type, obj.Namespace, obj.Name)
+		}
+	}
+	return nil
+}
+
+// FilterObjects will return the objects of which the user of given request is
+// allowed to list the type in the namespace of the object. If authorization
+// is disabled, all objects are returned.
+func (f *handler) FilterObjects(r *http.Request, objects []*scanner.Object) []*scanner.Object {
+	if !f.authorize {
+		return objects
+	}
+	usr := requestUser(r)
+	res := []*scanner.Object{}
+	for _, obj := range objects {
+		if f.visible(usr, obj.Cluster, obj.Type, obj.Namespace) {
+			res = append(res, obj)
+		}
+	}
+	return res
+}
+
+// FilterScanners will return the scanner configurations of which the user of
+// given request is allowed to list the scanned type in the namespace of the
+// scanner. If authorization is disabled, all scanners are returned.
+func (f *handler) FilterScanners(r *http.Request, scanners []scanner.Config) []scanner.Config {
+	if !f.authorize {
+		return scanners
+	}
+	usr := requestUser(r)
+	res := []scanner.Config{}
+	for _, cfg := range scanners {
+		if f.visible(usr, cfg.Cluster, cfg.Type, cfg.Namespace) {
+			res = append(res, cfg)
+		}
+	}
+	return res
+}
+
+// visible will check if given user is allowed to list the given type in the
+// given namespace. Errors are logged, and result in not being allowed. If
+// authorization is disabled, it will always return true.
+func (f *handler) visible(usr *user, cluster, typ, ns string) bool {
+	if !f.authorize {
+		return true
+	}
+	allowed, err := f.allowed(usr, "list", cluster, typ, ns, "")
+	if err != nil {
+		logger.Error("Error authorizing", "user", usr.Name, logging.KeyNamespace, ns, logging.Err(err))
+		return false
+	}
+	return allowed
+}
+
+// allowed will check if given user is allowed to perform given verb on the
+// object with given type, namespace and name in given cluster. If no name is
+// given, the verb is checked for all objects of the type in the namespace.
+// The results are cached for a short while.
+func (f *handler) allowed(usr *user, verb, cluster, typ, ns, name string) (bool, error) {
+	key := strings.Join([]string{usr.Name, usr.UID, strings.Join(usr.Groups, ","), verb, cluster, typ, ns, name}, "|")
+	now := time.Now()
+	f.reviews.m.Lock()
+	if c, ok := f.reviews.reviews[key]; ok && now.Before(c.expires) {
+		f.reviews.m.Unlock()
+		return c.allowed, nil
+	}
+	f.reviews.m.Unlock()
+	allowed, err := accessReview(usr, verb, cluster, typ, ns, name)
+	if err != nil {
+		return false, err
+	}
+	f.reviews.m.Lock()
+	defer f.reviews.m.Unlock()
+	for k, c := range f.reviews.reviews {
+		if now.After(c.expires) {
+			delete(f.reviews.reviews, k)
+		}
+	}
+	f.reviews.reviews[key] = &cachedReview{allowed: allowed, expires: now.Add(accessReviewTTL)}
+	return allowed, nil
+}
+
+// accessReview will check with a SubjectAccessReview if given user is allowed
+// to perform given verb on the object with given type, namespace and name in
+// given cluster.
+func accessReview(usr *user, verb, cluster, typ, ns, name string) (bool, error) {
+	group, resource, err := objectResource(typ)
+	if err != nil {
+		return false, err
+	}
+	client, err := kubeClient(cluster)
+	if err != nil {
+		return false, err
+	}
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   usr.Name,
+			UID:    usr.UID,
+			Groups: usr.Groups,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: ns,
+				Verb:      verb,
+				Group:     group,
+				Resource:  resource,
+				Name:      name,
+			},
+		},
+	}
+	res, err := client.AuthorizationV1().SubjectAccessReviews().Create(context.Background(), sar, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error reviewing access: %s", err)
+	}
+	logger.Log(context.Background(), logging.LevelTrace, "Access review", "verb", verb, "resource", resource, logging.KeyNamespace, ns, logging.KeyObject, name, "user", usr.Name, "allowed", res.Status.Allowed, "reason", res.Status.Reason)
+	return res.Status.Allowed, nil
+}
+
+// objectResource will return the api group and resource of given scanner
+// type.
+func objectResource(typ string) (string, string, error) {
+	switch strings.ToLower(typ) {
+	case "deployment":
+		return "apps", "deployments", nil
+	case "statefulset":
+		return "apps", "statefulsets", nil
+	case "openshift":
+		return "apps.openshift.io", "deploymentconfigs", nil
+	}
+	return "", "", fmt.Errorf("unsupported object type %s", typ)
+}
@@ -0,0 +1,203 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// fakeAccessReviews will replace the kubernetes client with a fake that
+// allows alice to list deployments in the dev namespace, and to scale the
+// web deployment. Requests to the broken cluster fail. It will return the
+// reviews that are done, and a function to restore the client.
+func fakeAccessReviews() (*[]authorizationv1.SubjectAccessReviewSpec, func()) {
+	reviews := &[]authorizationv1.SubjectAccessReviewSpec{}
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		sar := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		*reviews = append(*reviews, sar.Spec)
+		ra := sar.Spec.ResourceAttributes
+		if sar.Spec.User == "alice" && ra.Namespace == "dev" && ra.Resource == "deployments" {
+			sar.Status.Allowed = ra.Verb == "list" || (ra.Verb == "scale" && ra.Name == "web")
+		}
+		return true, sar, nil
+	})
+	orig := kubeClient
+	kubeClient = func(cluster string) (kubernetes.Interface, error) {
+		if cluster == "broken" {
+			return nil, fmt.Errorf("unknown cluster %s", cluster)
+		}
+		return client, nil
+	}
+	return reviews, func() { kubeClient = orig }
+}
+
+// userRequest will return a request authenticated as given user.
+func userRequest(usr *user) *http.Request {
+	r := httptest.NewRequest("GET", "/api/objects", nil)
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, usr))
+}
+
+func TestAuthorize(t *testing.T) {
+	reviews, restore := fakeAccessReviews()
+	defer restore()
+
+	alice := &user{Name: "alice", UID: "1001", Groups: []string{"dev"}}
+	bob := &user{Name: "bob"}
+	web := &scanner.Object{Type: "deployment", Namespace: "dev", Name: "web"}
+	db := &scanner.Object{Type: "deployment", Namespace: "dev", Name: "db"}
+	prod := &scanner.Object{Type: "deployment", Namespace: "prod", Name: "web"}
+	sts := &scanner.Object{Type: "statefulset", Namespace: "dev", Name: "web"}
+	other := &scanner.Object{Type: "unknown", Namespace: "dev", Name: "web"}
+	remote := &scanner.Object{Type: "deployment", Namespace: "dev", Name: "web", Cluster: "broken"}
+
+	tests := []struct {
+		authorize bool
+		usr       *user
+		objs      []*scanner.Object
+		err       bool
+		reviews   int
+	}{
+		{authorize: true, usr: alice, objs: []*scanner.Object{web}, err: false, reviews: 1},
+		{authorize: true, usr: alice, objs: []*scanner.Object{web, db}, err: true, reviews: 2},
+		{authorize: true, usr: alice, objs: []*scanner.Object{prod}, err: true, reviews: 1},
+		{authorize: true, usr: alice, objs: []*scanner.Object{sts}, err: true, reviews: 1},
+		{authorize: true, usr: bob, objs: []*scanner.Object{web}, err: true, reviews: 1},
+		{authorize: true, usr: alice, objs: []*scanner.Object{other}, err: true, reviews: 0},
+		{authorize: true, usr: alice, objs: []*scanner.Object{remote}, err: true, reviews: 0},
+		{authorize: true, usr: alice, objs: []*scanner.Object{}, err: false, reviews: 0},
+		{authorize: false, usr: bob, objs: []*scanner.Object{web, db, prod}, err: false, reviews: 0},
+	}
+	for i, tst := range tests {
+		f := NewHandler()
+		f.authorize = tst.authorize
+		*reviews = nil
+		err := f.Authorize(userRequest(tst.usr), "scale", tst.objs)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if len(*reviews) != tst.reviews {
+			t.Errorf("failed test %d - expected %d reviews, but got %d", i, tst.reviews, len(*reviews))
+		}
+	}
+
+	// the review should describe the user and the object
+	f := NewHandler()
+	f.authorize = true
+	*reviews = nil
+	f.Authorize(userRequest(alice), "scale", []*scanner.Object{web})
+	if len(*reviews) != 1 {
+		t.Fatalf("failed test - expected 1 review, but got %d", len(*reviews))
+	}
+	spec := (*reviews)[0]
+	ra := spec.ResourceAttributes
+	if spec.User != "alice" || spec.UID != "1001" || fmt.Sprint(spec.Groups) != "[dev]" ||
+		ra.Verb != "scale" || ra.Group != "apps" || ra.Resource != "deployments" || ra.Namespace != "dev" || ra.Name != "web" {
+		t.Errorf("failed test - unexpected review %+v %+v", spec, ra)
+	}
+}
+
+func TestFilterObjects(t *testing.T) {
+	reviews, restore := fakeAccessReviews()
+	defer restore()
+
+	alice := &user{Name: "alice"}
+	bob := &user{Name: "bob"}
+	objs := []*scanner.Object{
+		{Type: "deployment", Namespace: "dev", Name: "web"},
+		{Type: "deployment", Namespace: "dev", Name: "db"},
+		{Type: "deployment", Namespace: "prod", Name: "web"},
+		{Type: "statefulset", Namespace: "dev", Name: "web"},
+		{Type: "deployment", Namespace: "dev", Name: "web", Cluster: "broken"},
+	}
+
+	tests := []struct {
+		authorize bool
+		usr       *user
+		out       []string
+		reviews   int
+	}{
+		{authorize: true, usr: alice, out: []string{"dev/web", "dev/db"}, reviews: 3},
+		{authorize: true, usr: bob, out: []string{}, reviews: 3},
+		{authorize: false, usr: bob, out: []string{"dev/web", "dev/db", "prod/web", "dev/web", "dev/web"}, reviews: 0},
+	}
+	for i, tst := range tests {
+		f := NewHandler()
+		f.authorize = tst.authorize
+		*reviews = nil
+		out := []string{}
+		for _, obj := range f.FilterObjects(userRequest(tst.usr), objs) {
+			out = append(out, obj.Namespace+"/"+obj.Name)
+		}
+		if fmt.Sprint(out) != fmt.Sprint(tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, out)
+		}
+		if len(*reviews) != tst.reviews {
+			t.Errorf("failed test %d - expected %d reviews, but got %d", i, tst.reviews, len(*reviews))
+		}
+	}
+}
+
+func TestVisibleCache(t *testing.T) {
+	reviews, restore := fakeAccessReviews()
+	defer restore()
+
+	alice := &user{Name: "alice"}
+	admin := &user{Name: "alice", Groups: []string{"system:masters"}}
+	f := NewHandler()
+	f.authorize = true
+
+	tests := []struct {
+		usr     *user
+		ns      string
+		expire  bool
+		out     bool
+		reviews int
+	}{
+		{usr: alice, ns: "dev", out: true, reviews: 1},
+		{usr: alice, ns: "dev", out: true, reviews: 1},
+		{usr: alice, ns: "prod", out: false, reviews: 2},
+		{usr: alice, ns: "prod", out: false, reviews: 2},
+		{usr: admin, ns: "dev", out: true, reviews: 3},
+		{usr: alice, ns: "dev", expire: true, out: true, reviews: 4},
+		{usr: alice, ns: "prod", out: false, reviews: 5},
+	}
+	for i, tst := range tests {
+		if tst.expire {
+			f.reviews.m.Lock()
+			for _, c := range f.reviews.reviews {
+				c.expires = time.Now().Add(-time.Second)
+			}
+			f.reviews.m.Unlock()
+		}
+		out := f.visible(tst.usr, "", "deployment", tst.ns)
+		if out != tst.out {
+			t.Errorf("failed test %d - expected %t, but got %t", i, tst.out, out)
+		}
+		if len(*reviews) != tst.reviews {
+			t.Errorf("failed test %d - expected %d reviews, but got %d", i, tst.reviews, len(*reviews))
+		}
+	}
+
+	// expired reviews are removed from the cache
+	f.reviews.m.Lock()
+	n := len(f.reviews.reviews)
+	f.reviews.m.Unlock()
+	if n != 2 {
+		t.Errorf("failed test - expected 2 cached reviews, but got %d", n)
+	}
+}
@@ -1,25 +1,344 @@
 package backend
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/csv"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/julienschmidt/httprouter"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/scanner"
 )
 
+// tokenReviewTTL is the duration for which the result of a TokenReview is
+// cached.
+const tokenReviewTTL = 2 * time.Minute
+
+// kubeClient will return the kubernetes client for given cluster, which is
+// used for the TokenReviews and SubjectAccessReviews.
+var kubeClient = func(cluster string) (kubernetes.Interface, error) {
+	cfg, err := scanner.GetKubernetesForCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(cfg)
+}
+
+// AuthConfig describes the authentication and authorization of the web api.
+// If no authentication method is configured, all requests are allowed.
+type AuthConfig struct {
+	// TokenFile is a csv file with static api tokens, in the format
+	// token,user,uid,"group1,group2".
+	TokenFile string
+	// TokenReview enables validation of bearer tokens with the kubernetes
+	// TokenReview api.
+	TokenReview bool
+	// OIDCIssuerURL enables validation of bearer tokens as OIDC id tokens
+	// issued by given issuer.
+	OIDCIssuerURL string
+	// OIDCClientID is the client id (audience) of the OIDC id tokens.
+	OIDCClientID string
+	// OIDCUsernameClaim is the claim used as username (default sub).
+	OIDCUsernameClaim string
+	// OIDCGroupsClaim is the claim used as groups (default groups).
+	OIDCGroupsClaim string
+	// Authorize enables authorization of actions on objects with the
+	// kubernetes SubjectAccessReview api.
+	Authorize bool
+}
+
+// user describes an authenticated user.
+type user struct {
+	Name   string
+	UID    string
+	Groups []string
+}
+
+// anonymous is the user that is used when authentication is disabled.
+var anonymous = &user{Name: "system:anonymous", Groups: []string{"system:unauthenticated"}}
+
+// authenticator is the interface of an authentication method. It will return
+// nil if the given token is not valid for this method.
+type authenticator interface {
+	authenticate(token string) (*user, error)
+}
+
+type userKey struct{}
+
+// SetAuth will configure the authentication and authorization of the api.
+func (f *handler) SetAuth(cfg AuthConfig) error {
+	f.auth = []authenticator{}
+	f.authorize = cfg.Authorize
+	if cfg.TokenFile != "" {
+		auth, err := newStaticTokens(cfg.TokenFile)
+		if err != nil {
+			return err
+		}
+		f.auth = append(f.auth, auth)
+	}
+	if cfg.TokenReview {
+		f.auth = append(f.auth, &tokenReview{cache: map[[32]byte]*cachedUser{}})
+	}
+	if cfg.OIDCIssuerURL != "" {
+		f.auth = append(f.auth, &oidcTokens{
+			issuer:        cfg.OIDCIssuerURL,
+			clientID:      cfg.OIDCClientID,
+			usernameClaim: defaultString(cfg.OIDCUsernameClaim, "sub"),
+			groupsClaim:   defaultString(cfg.OIDCGroupsClaim, "groups"),
+		})
+	}
+	if len(f.auth) == 0 {
+		logger.Warn("No authentication configured for the web api")
+	}
+	return nil
+}
+
+// Authenticate will only call given handler if the request is done by an
+// authenticated user. The user is added to the context of the request.
 func (f *handler) Authenticate(okhandler httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		if !f.validUser(r) {
+		usr, err := f.getUser(r)
+		if err != nil {
+			logger.Error("Error authenticating", logging.Err(err))
+		}
+		if usr == nil {
 			f.unauthorized(w)
 			return
 		}
-		okhandler(w, r, ps)
+		okhandler(w, r.WithContext(context.WithValue(r.Context(), userKey{}, usr)), ps)
 	}
 }
 
 func (f *handler) unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="nightshift"`)
 	w.WriteHeader(http.StatusUnauthorized)
 }
 
-func (f *handler) validUser(r *http.Request) bool {
-	return true
+// getUser will return the user that is authenticated by the bearer token in
+// the request, by trying the configured authentication methods in order. It
+// will return nil if the user could not be authenticated.
+func (f *handler) getUser(r *http.Request) (*user, error) {
+	if len(f.auth) == 0 {
+		return anonymous, nil
+	}
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	errs := []string{}
+	for _, auth := range f.auth {
+		usr, err := auth.authenticate(token)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if usr != nil {
+			return usr, nil
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, ","))
+	}
+	return nil, nil
+}
+
+// requestUser will return the authenticated user of given request.
+func requestUser(r *http.Request) *user {
+	if usr, ok := r.Context().Value(userKey{}).(*user); ok {
+		return usr
+	}
+	return anonymous
+}
+
+// bearerToken will return the bearer token in the Authorization header of
+// given request.
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// staticTokens is the authenticator that validates tokens against a list of
+// static tokens.
+type staticTokens struct {
+	users map[string]*user
+}
+
+// newStaticTokens will read the static tokens from given csv file, with the
+// format token,user,uid,"group1,group2", in which uid and groups are
+// optional.
+func newStaticTokens(file string) (*staticTokens, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	rdr := csv.NewReader(fh)
+	rdr.FieldsPerRecord = -1
+	rdr.Comment = '#'
+	auth := &staticTokens{users: map[string]*user{}}
+	for {
+		rec, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid token file %s; %s", file, err)
+		}
+		if len(rec) < 2 || rec[0] == "" || rec[1] == "" {
+			return nil, fmt.Errorf("invalid token file %s; token and user required", file)
+		}
+		usr := &user{Name: rec[1]}
+		if len(rec) > 2 {
+			usr.UID = rec[2]
+		}
+		if len(rec) > 3 {
+			for _, grp := range strings.Split(rec[3], ",") {
+				if grp = strings.TrimSpace(grp); grp != "" {
+					usr.Groups = append(usr.Groups, grp)
+				}
+			}
+		}
+		auth.users[rec[0]] = usr
+	}
+	return auth, nil
+}
+
+func (a *staticTokens) authenticate(token string) (*user, error) {
+	var found *user
+	for tkn, usr := range a.users {
+		if subtle.ConstantTimeCompare([]byte(tkn), []byte(token)) == 1 {
+			found = usr
+		}
+	}
+	return found, nil
+}
+
+// tokenReview is the authenticator that validates tokens with the kubernetes
+// TokenReview api. Results are cached for a short while.
+type tokenReview struct {
+	m     sync.Mutex
+	cache map[[32]byte]*cachedUser
+}
+
+type cachedUser struct {
+	user    *user
+	expires time.Time
+}
+
+func (a *tokenReview) authenticate(token string) (*user, error) {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+	a.m.Lock()
+	if c, ok := a.cache[key]; ok && now.Before(c.expires) {
+		a.m.Unlock()
+		return c.user, nil
+	}
+	a.m.Unlock()
+	client, err := kubeClient("")
+	if err != nil {
+		return nil, err
+	}
+	tr := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	res, err := client.AuthenticationV1().TokenReviews().Create(context.Background(), tr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reviewing token: %s", err)
+	}
+	var usr *user
+	if res.Status.Authenticated {
+		usr = &user{Name: res.Status.User.Username, UID: res.Status.User.UID, Groups: res.Status.User.Groups}
+	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	for k, c := range a.cache {
+		if now.After(c.expires) {
+			delete(a.cache, k)
+		}
+	}
+	a.cache[key] = &cachedUser{user: usr, expires: now.Add(tokenReviewTTL)}
+	return usr, nil
+}
+
+// oidcTokens is the authenticator that validates tokens as OIDC id tokens.
+// The provider is discovered on first use.
+type oidcTokens struct {
+	issuer        string
+	clientID      string
+	usernameClaim string
+	groupsClaim   string
+	m             sync.Mutex
+	verifier      *oidc.IDTokenVerifier
+}
+
+func (a *oidcTokens) authenticate(token string) (*user, error) {
+	verifier, err := a.getVerifier()
+	if err != nil {
+		return nil, err
+	}
+	if strings.Count(token, ".") != 2 {
+		// not a jwt
+		return nil, nil
+	}
+	idt, err := verifier.Verify(context.Background(), token)
+	if err != nil {
+		logger.Debug("Invalid id token", logging.Err(err))
+		return nil, nil
+	}
+	claims := map[string]interface{}{}
+	if err := idt.Claims(&claims); err != nil {
+		return nil, err
+	}
+	name, _ := claims[a.usernameClaim].(string)
+	if name == "" {
+		return nil, fmt.Errorf("claim %s not found in id token", a.usernameClaim)
+	}
+	usr := &user{Name: name, UID: idt.Subject}
+	switch grps := claims[a.groupsClaim].(type) {
+	case string:
+		usr.Groups = []string{grps}
+	case []interface{}:
+		for _, grp := range grps {
+			if g, ok := grp.(string); ok {
+				usr.Groups = append(usr.Groups, g)
+			}
+		}
+	}
+	return usr, nil
+}
+
+// getVerifier will lazy load the id token verifier for the configured issuer.
+func (a *oidcTokens) getVerifier() (*oidc.IDTokenVerifier, error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	if a.verifier == nil {
+		provider, err := oidc.NewProvider(context.Background(), a.issuer)
+		if err != nil {
+			return nil, fmt.Errorf("error discovering oidc issuer %s: %s", a.issuer, err)
+		}
+		a.verifier = provider.Verifier(&oidc.Config{ClientID: a.clientID, SkipClientIDCheck: a.clientID == ""})
+	}
+	return a.verifier, nil
+}
+
+// defaultString will return given value, or the default if it's empty.
+func defaultString(val, def string) string {
+	if val == "" {
+		return def
+	}
+	return val
 }
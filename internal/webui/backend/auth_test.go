@@ -0,0 +1,440 @@
+package backend
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/joyrex2001/nightshift/internal/config"
+)
+
+const testTokens = `static,alice,1001,"dev,ops"
+# comment
+bob-token,bob
+`
+
+// newTestHandler will return a handler with the static test tokens
+// configured.
+func newTestHandler(t *testing.T) *handler {
+	file := filepath.Join(t.TempDir(), "tokens.csv")
+	ioutil.WriteFile(file, []byte(testTokens), 0600)
+	f := NewHandler()
+	if err := f.SetAuth(AuthConfig{TokenFile: file}); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	return f
+}
+
+// whoami is a handler that will write the name of the authenticated user.
+func whoami(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	fmt.Fprint(w, requestUser(r).Name)
+}
+
+func TestAuthenticateStatic(t *testing.T) {
+	f := newTestHandler(t)
+	tests := []struct {
+		auth string
+		code int
+		user string
+	}{
+		{auth: "Bearer static", code: 200, user: "alice"},
+		{auth: "bearer static", code: 200, user: "alice"},
+		{auth: "Bearer bob-token", code: 200, user: "bob"},
+		{auth: "Bearer invalid", code: 401},
+		{auth: "Bearer ", code: 401},
+		{auth: "Basic static", code: 401},
+		{auth: "", code: 401},
+	}
+	for i, tst := range tests {
+		r := httptest.NewRequest("GET", "/api/objects", nil)
+		if tst.auth != "" {
+			r.Header.Set("Authorization", tst.auth)
+		}
+		w := httptest.NewRecorder()
+		f.Authenticate(whoami)(w, r, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.code, w.Code)
+		}
+		if w.Code == 200 && w.Body.String() != tst.user {
+			t.Errorf("failed test %d - expected user %s, but got %s", i, tst.user, w.Body.String())
+		}
+		if w.Code == 401 && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("failed test %d - expected WWW-Authenticate header", i)
+		}
+	}
+}
+
+func TestStaticTokens(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		data   string
+		token  string
+		groups []string
+		err    bool
+	}{
+		{data: testTokens, token: "static", groups: []string{"dev", "ops"}, err: false},
+		{data: testTokens, token: "bob-token", groups: nil, err: false},
+		{data: "token\n", err: true},
+		{data: ",user\n", err: true},
+		{data: "token,\"user\n", err: true},
+	}
+	for i, tst := range tests {
+		file := filepath.Join(dir, fmt.Sprintf("tokens%d.csv", i))
+		ioutil.WriteFile(file, []byte(tst.data), 0600)
+		auth, err := newStaticTokens(file)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err != nil {
+			continue
+		}
+		usr, _ := auth.authenticate(tst.token)
+		if usr == nil {
+			t.Errorf("failed test %d - expected user for token %s", i, tst.token)
+			continue
+		}
+		if fmt.Sprint(usr.Groups) != fmt.Sprint(tst.groups) {
+			t.Errorf("failed test %d - expected groups %v, but got %v", i, tst.groups, usr.Groups)
+		}
+	}
+	if _, err := newStaticTokens(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Errorf("failed test - expected err for missing token file, but got none")
+	}
+}
+
+func TestAuthenticateAnonymous(t *testing.T) {
+	f := NewHandler()
+	f.SetAuth(AuthConfig{})
+	r := httptest.NewRequest("GET", "/api/objects", nil)
+	w := httptest.NewRecorder()
+	f.Authenticate(whoami)(w, r, nil)
+	if w.Code != 200 || w.Body.String() != anonymous.Name {
+		t.Errorf("failed test - expected anonymous access, but got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthenticateAccessToken(t *testing.T) {
+	f := newTestHandler(t)
+	tests := []struct {
+		path string
+		auth string
+		code int
+		user string
+	}{
+		{path: "/api/events?access_token=static", code: 200, user: "alice"},
+		{path: "/api/events?access_token=invalid", code: 401},
+		{path: "/api/events?access_token=", code: 401},
+		{path: "/api/events", auth: "Bearer bob-token", code: 200, user: "bob"},
+		{path: "/api/events?access_token=static", auth: "Bearer bob-token", code: 200, user: "bob"},
+		{path: "/api/events?access_token=static", auth: "Bearer invalid", code: 401},
+	}
+	for i, tst := range tests {
+		r := httptest.NewRequest("GET", tst.path, nil)
+		if tst.auth != "" {
+			r.Header.Set("Authorization", tst.auth)
+		}
+		w := httptest.NewRecorder()
+		tokenFromQuery(f.Authenticate(whoami))(w, r, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.code, w.Code)
+		}
+		if w.Code == 200 && w.Body.String() != tst.user {
+			t.Errorf("failed test %d - expected user %s, but got %s", i, tst.user, w.Body.String())
+		}
+	}
+}
+
+func TestAuthenticateTokenReview(t *testing.T) {
+	reviews := 0
+	fail := false
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		if fail {
+			return true, nil, fmt.Errorf("unavailable")
+		}
+		tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if tr.Spec.Token == "valid" {
+			tr.Status.Authenticated = true
+			tr.Status.User = authenticationv1.UserInfo{Username: "system:serviceaccount:dev:ci", UID: "42"}
+		}
+		return true, tr, nil
+	})
+	orig := kubeClient
+	kubeClient = func(cluster string) (kubernetes.Interface, error) { return client, nil }
+	defer func() { kubeClient = orig }()
+
+	f := NewHandler()
+	f.SetAuth(AuthConfig{TokenReview: true})
+	review := f.auth[0].(*tokenReview)
+
+	tests := []struct {
+		token   string
+		expire  bool
+		fail    bool
+		code    int
+		user    string
+		reviews int
+	}{
+		{token: "valid", code: 200, user: "system:serviceaccount:dev:ci", reviews: 1},
+		{token: "valid", code: 200, user: "system:serviceaccount:dev:ci", reviews: 1},
+		{token: "invalid", code: 401, reviews: 2},
+		{token: "invalid", code: 401, reviews: 2},
+		{token: "valid", expire: true, code: 200, user: "system:serviceaccount:dev:ci", reviews: 3},
+		{token: "valid", expire: true, fail: true, code: 401, reviews: 4},
+		{token: "valid", code: 401, fail: true, reviews: 5},
+		{token: "valid", code: 200, user: "system:serviceaccount:dev:ci", reviews: 6},
+	}
+	for i, tst := range tests {
+		if tst.expire {
+			review.m.Lock()
+			for _, c := range review.cache {
+				c.expires = time.Now().Add(-time.Second)
+			}
+			review.m.Unlock()
+		}
+		fail = tst.fail
+		r := httptest.NewRequest("GET", "/api/objects", nil)
+		r.Header.Set("Authorization", "Bearer "+tst.token)
+		w := httptest.NewRecorder()
+		f.Authenticate(whoami)(w, r, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.code, w.Code)
+		}
+		if w.Code == 200 && w.Body.String() != tst.user {
+			t.Errorf("failed test %d - expected user %s, but got %s", i, tst.user, w.Body.String())
+		}
+		if reviews != tst.reviews {
+			t.Errorf("failed test %d - expected %d reviews, but got %d", i, tst.reviews, reviews)
+		}
+	}
+}
+
+// oidcIssuer is a minimal OIDC issuer serving the discovery document and
+// the signing keys.
+type oidcIssuer struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+}
+
+func newOIDCIssuer(t *testing.T) *oidcIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	iss := &oidcIssuer{key: key}
+	iss.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"issuer":                                iss.srv.URL,
+				"authorization_endpoint":                iss.srv.URL + "/auth",
+				"jwks_uri":                              iss.srv.URL + "/keys",
+				"id_token_signing_alg_values_supported": []string{"RS256"},
+			})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{{
+					"kty": "RSA",
+					"alg": "RS256",
+					"use": "sig",
+					"kid": "test",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return iss
+}
+
+// token will return an id token with given claims, signed with given key.
+func (iss *oidcIssuer) token(key *rsa.PrivateKey, claims map[string]interface{}) string {
+	enc := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := enc(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"}) + "." + enc(claims)
+	sum := sha256.Sum256([]byte(input))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestAuthenticateOIDC(t *testing.T) {
+	iss := newOIDCIssuer(t)
+	defer iss.srv.Close()
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	f := NewHandler()
+	f.SetAuth(AuthConfig{
+		OIDCIssuerURL:     iss.srv.URL,
+		OIDCClientID:      "nightshift",
+		OIDCUsernameClaim: "email",
+	})
+
+	now := time.Now()
+	claims := func(mod map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":    iss.srv.URL,
+			"aud":    "nightshift",
+			"sub":    "1234",
+			"email":  "alice@example.com",
+			"groups": []string{"dev", "ops"},
+			"iat":    now.Unix(),
+			"exp":    now.Add(time.Hour).Unix(),
+		}
+		for k, v := range mod {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		token  string
+		code   int
+		user   string
+		groups []string
+	}{
+		{token: iss.token(iss.key, claims(nil)), code: 200, user: "alice@example.com", groups: []string{"dev", "ops"}},
+		{token: iss.token(iss.key, claims(map[string]interface{}{"groups": "dev"})), code: 200, user: "alice@example.com", groups: []string{"dev"}},
+		{token: iss.token(iss.key, claims(map[string]interface{}{"aud": "other"})), code: 401},
+		{token: iss.token(iss.key, claims(map[string]interface{}{"iss": "https://other.example.com"})), code: 401},
+		{token: iss.token(iss.key, claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})), code: 401},
+		{token: iss.token(iss.key, claims(map[string]interface{}{"email": nil})), code: 401},
+		{token: iss.token(other, claims(nil)), code: 401},
+		{token: "not-a-jwt", code: 401},
+	}
+	for i, tst := range tests {
+		var usr *user
+		r := httptest.NewRequest("GET", "/api/objects", nil)
+		r.Header.Set("Authorization", "Bearer "+tst.token)
+		w := httptest.NewRecorder()
+		f.Authenticate(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			usr = requestUser(r)
+		})(w, r, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.code, w.Code)
+		}
+		if w.Code != 200 {
+			continue
+		}
+		if usr.Name != tst.user || usr.UID != "1234" {
+			t.Errorf("failed test %d - expected user %s, but got %s (%s)", i, tst.user, usr.Name, usr.UID)
+		}
+		if fmt.Sprint(usr.Groups) != fmt.Sprint(tst.groups) {
+			t.Errorf("failed test %d - expected groups %v, but got %v", i, tst.groups, usr.Groups)
+		}
+	}
+
+	// an unreachable issuer should deny access
+	f.SetAuth(AuthConfig{OIDCIssuerURL: "http://127.0.0.1:1"})
+	r := httptest.NewRequest("GET", "/api/objects", nil)
+	r.Header.Set("Authorization", "Bearer "+tests[0].token)
+	w := httptest.NewRecorder()
+	f.Authenticate(whoami)(w, r, nil)
+	if w.Code != 401 {
+		t.Errorf("failed test - expected 401 for unreachable issuer, but got %d", w.Code)
+	}
+}
+
+func TestAuthenticateRoutes(t *testing.T) {
+	f := newTestHandler(t)
+	f.SetHooks([]*config.Hook{{Id: "deploy", Action: "save", Token: "hooktoken"}})
+	tests := []struct {
+		method string
+		path   string
+		header string
+		auth   string
+		code   int
+	}{
+		{method: "GET", path: "/api/version", code: 401},
+		{method: "GET", path: "/api/version", header: "Authorization", auth: "Bearer static", code: 200},
+		{method: "GET", path: "/api/version?access_token=static", code: 401},
+		{method: "POST", path: "/api/hooks/deploy", code: 401},
+		{method: "POST", path: "/api/hooks/deploy", header: "Authorization", auth: "Bearer hooktoken", code: 200},
+		{method: "POST", path: "/api/hooks/Deploy", header: "X-Nightshift-Token", auth: "hooktoken", code: 200},
+		{method: "POST", path: "/api/hooks/deploy", header: "Authorization", auth: "Bearer static", code: 401},
+		{method: "POST", path: "/api/hooks/missing", header: "Authorization", auth: "Bearer hooktoken", code: 404},
+		{method: "GET", path: "/api/openapi.yaml", code: 200},
+		{method: "GET", path: "/healthz", code: 200},
+		{method: "GET", path: "/", code: 307},
+	}
+	for i, tst := range tests {
+		r := httptest.NewRequest(tst.method, tst.path, nil)
+		if tst.header != "" {
+			r.Header.Set(tst.header, tst.auth)
+		}
+		w := httptest.NewRecorder()
+		f.ServeHTTP(w, r)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.code, w.Code)
+		}
+	}
+
+	// all other api routes require authentication
+	for i, route := range []string{
+		"GET /api/objects",
+		"GET /api/objects/1/schedule",
+		"PUT /api/objects/1/schedule",
+		"GET /api/objects/1/next-events",
+		"POST /api/objects/scale/1",
+		"POST /api/objects/restore",
+		"POST /api/objects/snooze/3h",
+		"GET /api/namespaces/dev/objects/web",
+		"PATCH /api/namespaces/dev/objects/web",
+		"POST /api/namespaces/dev/snooze/3h",
+		"POST /api/scanners/web/snooze/3h",
+		"GET /api/events",
+		"GET /api/timeline",
+		"GET /api/savings",
+		"GET /api/scanners",
+		"GET /api/triggers",
+		"GET /api/triggers/history",
+		"GET /api/version",
+	} {
+		method, path, _ := strings.Cut(route, " ")
+		r := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		f.ServeHTTP(w, r)
+		if w.Code != 401 {
+			t.Errorf("failed test %d - expected 401 for %s, but got %d", i, route, w.Code)
+		}
+	}
+
+	// the static files of the webui are public
+	r := httptest.NewRequest("GET", "/public/index.html", nil)
+	w := httptest.NewRecorder()
+	f.ServeHTTP(w, r)
+	if w.Code == 401 {
+		t.Errorf("failed test - expected public files without authentication, but got %d", w.Code)
+	}
+}
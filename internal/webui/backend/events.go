@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/joyrex2001/nightshift/internal/logging"
+)
+
+// eventsKeepAlive is the interval at which a comment is sent on the events
+// stream, to keep the connection open.
+var eventsKeepAlive = 30 * time.Second
+
+// GetEvents will stream the activities of the agent as server-sent events,
+// until the client disconnects. Activities on objects in namespaces the user
+// is not allowed to see are not sent.
+func (f *handler) GetEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		logger.Debug("Unable to disable write deadline for events", logging.Err(err))
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		logger.Error("Error streaming events", logging.Err(err))
+		return
+	}
+
+	acts, unsubscribe := f.subscribe()
+	defer unsubscribe()
+	usr := requestUser(r)
+	tmr := time.NewTicker(eventsKeepAlive)
+	defer tmr.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-tmr.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case act, ok := <-acts:
+			if !ok {
+				return
+			}
+			if act.Namespace != "" && !f.visible(usr, act.Cluster, act.ObjectType, act.Namespace) {
+				continue
+			}
+			data, err := json.Marshal(act)
+			if err != nil {
+				logger.Error("Error encoding event", logging.Err(err))
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", act.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// tokenFromQuery will use the access_token query parameter as bearer token,
+// if the request has no Authorization header. Browsers can't set headers on
+// EventSource requests.
+func tokenFromQuery(handler httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		handler(w, r, ps)
+	}
+}
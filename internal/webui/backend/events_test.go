@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/agent"
+)
+
+func TestGetEvents(t *testing.T) {
+	_, restore := fakeAccessReviews()
+	defer restore()
+	orig := eventsKeepAlive
+	eventsKeepAlive = 50 * time.Millisecond
+	defer func() { eventsKeepAlive = orig }()
+
+	acts := make(chan agent.Activity, 10)
+	unsubscribed := make(chan bool, 1)
+	f := newTestHandler(t)
+	f.authorize = true
+	f.subscribe = func() (<-chan agent.Activity, func()) {
+		return acts, func() { unsubscribed <- true }
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	// the stream requires authentication
+	resp, err := http.Get(srv.URL + "/api/events")
+	if err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 401 {
+		t.Errorf("failed test - expected status 401, but got %d", resp.StatusCode)
+	}
+
+	// the token can be passed as access_token query parameter
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/events?access_token=static", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("failed test - expected status 200, but got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("failed test - expected content type text/event-stream, but got %s", ct)
+	}
+
+	// alice is not allowed to see prod, so only the dev activity is sent
+	acts <- agent.Activity{Type: "scale", UID: "1", Namespace: "prod", Name: "web", ObjectType: "deployment"}
+	acts <- agent.Activity{Type: "scale", UID: "2", Namespace: "dev", Name: "web", ObjectType: "deployment"}
+
+	rd := bufio.NewReader(resp.Body)
+	event, data, keepalive := "", "", false
+	for data == "" || !keepalive {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed test - unexpected err: %s", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if data != "" {
+				t.Fatalf("failed test - unexpected second event %s", line)
+			}
+			data = strings.TrimPrefix(line, "data: ")
+		case line == ": keep-alive":
+			keepalive = true
+		}
+	}
+	if event != "scale" {
+		t.Errorf("failed test - expected event scale, but got %s", event)
+	}
+	act := agent.Activity{}
+	if err := json.Unmarshal([]byte(data), &act); err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	if act.UID != "2" || act.Namespace != "dev" || act.Name != "web" {
+		t.Errorf("failed test - expected the dev activity, but got %+v", act)
+	}
+
+	// disconnecting the client unsubscribes
+	cancel()
+	select {
+	case <-unsubscribed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("failed test - expected unsubscribe after disconnect")
+	}
+}
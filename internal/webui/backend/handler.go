@@ -1,6 +1,7 @@
 package backend
 
 import (
+	_ "embed"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,21 +9,50 @@ import (
 	"time"
 
 	"github.com/elazarl/go-bindata-assetfs"
-	"github.com/golang/glog"
 	"github.com/julienschmidt/httprouter"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/joyrex2001/nightshift/internal/agent"
+	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/trigger"
 	"github.com/joyrex2001/nightshift/internal/webui/backend/internalfs"
 )
 
+//go:embed openapi.yaml
+var openapi []byte
+
+var logger = logging.For(logging.WebUI)
+
 // NewHandler will instantiate a http handler for serving the webui backend.
 func NewHandler() *handler {
-	return &handler{}
+	return &handler{
+		hooks:        map[string]*config.Hook{},
+		reviews:      &accessCache{reviews: map[string]*cachedReview{}},
+		objects:      agent.New().GetObjects,
+		queueTrigger: agent.New().QueueTrigger,
+		subscribe:    agent.New().Subscribe,
+	}
 }
 
 type handler struct {
-	once sync.Once
-	mux  *httprouter.Router
+	once         sync.Once
+	mux          *httprouter.Router
+	hooks        map[string]*config.Hook
+	auth         []authenticator
+	authorize    bool
+	reviews      *accessCache
+	objects      func() map[string]*scanner.Object
+	queueTrigger func(string, trigger.Event) error
+	subscribe    func() (<-chan agent.Activity, func())
+}
+
+// SetHooks will set the inbound hooks that can be called via the api.
+func (f *handler) SetHooks(hooks []*config.Hook) {
+	for _, hook := range hooks {
+		f.hooks[hook.Id] = hook
+	}
 }
 
 func (f *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -36,13 +66,30 @@ func (f *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (f *handler) init() {
 	// web routing
 	f.mux = httprouter.New()
-	f.mux.GET("/public/*filepath", f.Authenticate(f.ServeFiles("")))
+	// the static files of the webui are served without authentication, as the
+	// browser can't send a bearer token when loading them; they contain no
+	// data, and the webui will ask for a token to access the api.
+	f.mux.GET("/public/*filepath", f.ServeFiles(""))
+	f.mux.GET("/api/openapi.yaml", f.OpenAPI)
 	f.mux.GET("/api/objects", f.Authenticate(f.GetObjects))
+	f.mux.GET("/api/objects/:uid/schedule", f.Authenticate(f.GetObjectSchedule))
+	f.mux.PUT("/api/objects/:uid/schedule", f.Authenticate(f.PutObjectSchedule))
+	f.mux.GET("/api/objects/:uid/next-events", f.Authenticate(f.GetObjectNextEvents))
 	f.mux.POST("/api/objects/scale/:replicas", f.Authenticate(f.PostObjectsScale))
 	f.mux.POST("/api/objects/restore", f.Authenticate(f.PostObjectsRestore))
+	f.mux.POST("/api/objects/snooze/:until", f.Authenticate(f.PostObjectsSnooze))
+	f.mux.GET("/api/namespaces/:namespace/objects/:name", f.Authenticate(f.GetObject))
+	f.mux.PATCH("/api/namespaces/:namespace/objects/:name", f.Authenticate(f.PatchObject))
+	f.mux.POST("/api/namespaces/:namespace/snooze/:until", f.Authenticate(f.PostNamespaceSnooze))
+	f.mux.POST("/api/scanners/:id/snooze/:until", f.Authenticate(f.PostScannerSnooze))
+	f.mux.GET("/api/events", tokenFromQuery(f.Authenticate(f.GetEvents)))
+	f.mux.GET("/api/timeline", f.Authenticate(f.GetTimeline))
+	f.mux.GET("/api/savings", f.Authenticate(f.GetSavings))
 	f.mux.GET("/api/scanners", f.Authenticate(f.GetScanners))
 	f.mux.GET("/api/triggers", f.Authenticate(f.GetTriggers))
+	f.mux.GET("/api/triggers/history", f.Authenticate(f.GetTriggerHistory))
 	f.mux.GET("/api/version", f.Authenticate(f.GetVersion))
+	f.mux.POST("/api/hooks/:id", f.PostHook)
 	f.mux.GET("/metrics", f.Metrics())
 	f.mux.GET("/healthz", f.Healthz)
 	f.mux.GET("/", f.Redirect(307, "/public"))
@@ -85,6 +132,13 @@ func (f *handler) Metrics() httprouter.Handle {
 	}
 }
 
+// OpenAPI will return the OpenAPI specification of the api.
+func (f *handler) OpenAPI(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openapi)
+	return
+}
+
 // Healthz will return a liveness response.
 func (f *handler) Healthz(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
@@ -100,6 +154,6 @@ func (f *handler) Error(w http.ResponseWriter, r *http.Request, code int, cerr e
 		Status int    `json:"status"`
 		Error  string `json:"error"`
 	}{code, cerr.Error()})
-	glog.Errorf("HTTP %d: %s", code, cerr)
+	logger.Error("HTTP error", "method", r.Method, "path", r.URL.Path, "status", code, logging.Err(cerr))
 	return
 }
@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/joyrex2001/nightshift/internal/agent"
+	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/logging"
+	"github.com/joyrex2001/nightshift/internal/metrics"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+// PostHook will execute the action of the inbound hook with given id. The
+// request is authenticated with the token of the hook, which should be
+// provided as bearer token, or in the X-Nightshift-Token header. For the
+// scale action, the configured number of replicas can be overridden with the
+// replicas query parameter.
+func (f *handler) PostHook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	hook, ok := f.hooks[strings.ToLower(ps.ByName("id"))]
+	if !ok {
+		f.Error(w, r, http.StatusNotFound, fmt.Errorf("non existing hook: %s", ps.ByName("id")))
+		return
+	}
+	if !validHookToken(hook, r) {
+		f.unauthorized(w)
+		return
+	}
+	replicas := hook.Replicas
+	if repl := r.URL.Query().Get("replicas"); repl != "" {
+		var err error
+		if replicas, err = strconv.Atoi(repl); err != nil || replicas < 0 {
+			f.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid number of replicas: %s", repl))
+			return
+		}
+	}
+	objs := hookObjects(hook, f.objects())
+	logger.Info("Executing hook", "hook", hook.Id, "action", hook.Action, "objects", len(objs))
+	metrics.Increase("hook")
+	if err := f.executeHook(hook, objs, replicas); err != nil {
+		metrics.Increase("hook_error")
+		code := http.StatusInternalServerError
+		if errors.Is(err, agent.ErrQueueFull) {
+			code = http.StatusServiceUnavailable
+		}
+		f.Error(w, r, code, err)
+		return
+	}
+	res := struct {
+		Id       string   `json:"id"`
+		Action   string   `json:"action"`
+		Objects  []string `json:"objects"`
+		Triggers []string `json:"triggers"`
+	}{
+		Id:       hook.Id,
+		Action:   hook.Action,
+		Objects:  []string{},
+		Triggers: hook.Trigger,
+	}
+	for _, obj := range objs {
+		res.Objects = append(res.Objects, obj.Namespace+"/"+obj.Name)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
+// executeHook will execute the action of given hook for given objects, and
+// will enqueue the triggers configured for the hook.
+func (f *handler) executeHook(hook *config.Hook, objs []*scanner.Object, replicas int) error {
+	var err error
+	switch hook.Action {
+	case "scale":
+		err = scaleObjects(objs, replicas)
+	case "save":
+		err = saveObjects(objs)
+	case "restore":
+		err = restoreObjects(objs)
+	}
+	if err != nil {
+		return err
+	}
+	evt := trigger.Event{Objects: objs, Time: time.Now(), Schedule: "hook " + hook.Id}
+	for _, id := range hook.Trigger {
+		if err := f.queueTrigger(id, evt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hookObjects will return the objects that match the scanner ids and the
+// namespaces configured for given hook, ordered by namespace and name.
+func hookObjects(hook *config.Hook, objects map[string]*scanner.Object) []*scanner.Object {
+	objs := []*scanner.Object{}
+	if len(hook.Scanner) == 0 && len(hook.Namespace) == 0 {
+		return objs
+	}
+	for _, obj := range objects {
+		if len(hook.Scanner) > 0 && !hasString(hook.Scanner, obj.ScannerId) {
+			continue
+		}
+		if len(hook.Namespace) > 0 && !hasString(hook.Namespace, obj.Namespace) {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		if objs[i].Namespace != objs[j].Namespace {
+			return objs[i].Namespace < objs[j].Namespace
+		}
+		return objs[i].Name < objs[j].Name
+	})
+	return objs
+}
+
+// validHookToken will check if the given request contains the token of given
+// hook, either as bearer token or in the X-Nightshift-Token header.
+func validHookToken(hook *config.Hook, r *http.Request) bool {
+	token := hook.Token
+	if hook.TokenFile != "" {
+		data, err := ioutil.ReadFile(hook.TokenFile)
+		if err != nil {
+			logger.Error("Error reading token", "hook", hook.Id, logging.Err(err))
+			return false
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return false
+	}
+	given := r.Header.Get("X-Nightshift-Token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		given = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(given)) == 1
+}
+
+// hasString will return true if given list contains given string.
+func hasString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
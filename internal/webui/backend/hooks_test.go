@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/joyrex2001/nightshift/internal/agent"
+	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+func TestPostHook(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("filetoken\n"), 0600)
+
+	mock := &mockScanner{}
+	scanner.RegisterModule("hookscanner", func() (scanner.Scanner, error) { return mock, nil })
+	objs := map[string]*scanner.Object{
+		"1": {UID: "1", Type: "hookscanner", ScannerId: "web", Namespace: "dev", Name: "web", Replicas: 2, State: &scanner.State{Replicas: 3}},
+		"2": {UID: "2", Type: "hookscanner", ScannerId: "db", Namespace: "prod", Name: "db", Replicas: 1},
+	}
+
+	hooks := []*config.Hook{
+		{Id: "scale", Action: "scale", Namespace: []string{"dev"}, Replicas: 0, Token: "s3cr3t", Trigger: []string{"notify"}},
+		{Id: "save", Action: "save", Scanner: []string{"web"}, TokenFile: tokenFile},
+		{Id: "restore", Action: "restore", Scanner: []string{"web"}, Token: "s3cr3t"},
+		{Id: "restoreall", Action: "restore", Namespace: []string{"dev", "prod"}, Token: "s3cr3t"},
+		{Id: "trigger", Action: "trigger", Token: "s3cr3t", Trigger: []string{"notify", "report"}},
+		{Id: "busy", Action: "trigger", Token: "s3cr3t", Trigger: []string{"busy"}},
+		{Id: "missingfile", Action: "trigger", TokenFile: filepath.Join(dir, "missing"), Trigger: []string{"notify"}},
+	}
+
+	tests := []struct {
+		path    string
+		header  string
+		token   string
+		code    int
+		scaled  map[string]int
+		queued  []string
+		objects []string
+	}{
+		// authentication
+		{path: "/api/hooks/scale", code: 401},
+		{path: "/api/hooks/scale", header: "Authorization", token: "Bearer wrong", code: 401},
+		{path: "/api/hooks/scale", header: "X-Nightshift-Token", token: "wrong", code: 401},
+		{path: "/api/hooks/save", header: "Authorization", token: "Bearer s3cr3t", code: 401},
+		{path: "/api/hooks/missingfile", header: "Authorization", token: "Bearer ", code: 401},
+		{path: "/api/hooks/unknown", header: "Authorization", token: "Bearer s3cr3t", code: 404},
+		// actions
+		{path: "/api/hooks/scale", header: "Authorization", token: "Bearer s3cr3t", code: 200, scaled: map[string]int{"web": 0}, queued: []string{"notify"}, objects: []string{"dev/web"}},
+		{path: "/api/hooks/Scale?replicas=1", header: "X-Nightshift-Token", token: "s3cr3t", code: 200, scaled: map[string]int{"web": 1}, queued: []string{"notify"}, objects: []string{"dev/web"}},
+		{path: "/api/hooks/scale?replicas=-1", header: "Authorization", token: "Bearer s3cr3t", code: 400},
+		{path: "/api/hooks/save", header: "Authorization", token: "Bearer filetoken", code: 200, scaled: map[string]int{"web": 2}, objects: []string{"dev/web"}},
+		{path: "/api/hooks/restore", header: "Authorization", token: "Bearer s3cr3t", code: 200, scaled: map[string]int{"web": 3}, objects: []string{"dev/web"}},
+		{path: "/api/hooks/restoreall", header: "Authorization", token: "Bearer s3cr3t", code: 500, scaled: map[string]int{"web": 3}},
+		{path: "/api/hooks/trigger", header: "Authorization", token: "Bearer s3cr3t", code: 200, queued: []string{"notify", "report"}, objects: []string{}},
+		{path: "/api/hooks/busy", header: "Authorization", token: "Bearer s3cr3t", code: 503},
+	}
+
+	for i, tst := range tests {
+		queued := []string{}
+		f := NewHandler()
+		f.SetHooks(hooks)
+		f.objects = func() map[string]*scanner.Object {
+			res := map[string]*scanner.Object{}
+			for uid, obj := range objs {
+				res[uid] = obj.Copy()
+			}
+			return res
+		}
+		f.queueTrigger = func(id string, evt trigger.Event) error {
+			if id == "busy" {
+				return agent.ErrQueueFull
+			}
+			queued = append(queued, id)
+			return nil
+		}
+		mock.scaled = map[string]int{}
+
+		r := httptest.NewRequest("POST", tst.path, nil)
+		if tst.header != "" {
+			r.Header.Set(tst.header, tst.token)
+		}
+		w := httptest.NewRecorder()
+		f.ServeHTTP(w, r)
+
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected status %d, but got %d", i, tst.code, w.Code)
+		}
+		if tst.scaled == nil {
+			tst.scaled = map[string]int{}
+		}
+		if fmt.Sprint(mock.scaled) != fmt.Sprint(tst.scaled) {
+			t.Errorf("failed test %d - expected scaled %v, but got %v", i, tst.scaled, mock.scaled)
+		}
+		if fmt.Sprint(queued) != fmt.Sprint(append([]string{}, tst.queued...)) {
+			t.Errorf("failed test %d - expected queued %v, but got %v", i, tst.queued, queued)
+		}
+		if w.Code != 200 {
+			continue
+		}
+		res := struct {
+			Objects []string `json:"objects"`
+		}{}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if fmt.Sprint(res.Objects) != fmt.Sprint(tst.objects) {
+			t.Errorf("failed test %d - expected objects %v, but got %v", i, tst.objects, res.Objects)
+		}
+	}
+}
@@ -5,8 +5,6 @@ package backend
 import (
 	"net/http"
 	"time"
-
-	"github.com/golang/glog"
 )
 
 type stResponseWriter struct {
@@ -27,6 +25,12 @@ func (w *stResponseWriter) Flush() {
 	}
 }
 
+// Unwrap will return the original ResponseWriter, so http.ResponseController
+// can access its optional interfaces.
+func (w *stResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func (w *stResponseWriter) Write(b []byte) (int, error) {
 	if w.HTTPStatus == 0 {
 		w.HTTPStatus = 200
@@ -49,15 +53,15 @@ func HTTPLogger(handler http.Handler, ignore []string) http.Handler {
 		if skip[r.URL.Path] {
 			return
 		}
-		glog.Infof("HTTP - %s - - - \"%s %s %s\" %d %d %s %dus\n",
-			r.RemoteAddr,
-			r.Method,
-			r.URL.Path,
-			r.Proto,
-			interceptWriter.HTTPStatus,
-			interceptWriter.ResponseSize,
-			r.UserAgent(),
-			time.Since(t),
+		logger.Info("HTTP",
+			"remote", r.RemoteAddr,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"proto", r.Proto,
+			"status", interceptWriter.HTTPStatus,
+			"size", interceptWriter.ResponseSize,
+			"user_agent", r.UserAgent(),
+			"duration", time.Since(t),
 		)
 	})
 }
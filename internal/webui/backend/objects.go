@@ -0,0 +1,255 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/joyrex2001/nightshift/internal/metrics"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+// maxNextEvents is the maximum number of events returned by the next-events
+// endpoint.
+const maxNextEvents = 100
+
+// objectPatch describes the changes that can be applied to a single object.
+// State is either save, which saves the current number of replicas before
+// scaling, or restore, which restores the saved state. SnoozeUntil is a
+// snooze deadline, as accepted by the snooze endpoints.
+type objectPatch struct {
+	Replicas    *int    `json:"replicas"`
+	State       string  `json:"state"`
+	SnoozeUntil *string `json:"snooze_until"`
+}
+
+// nextEvent describes an upcoming scale event of an object.
+type nextEvent struct {
+	Time     time.Time `json:"time"`
+	Schedule string    `json:"schedule"`
+	Snoozed  bool      `json:"snoozed"`
+}
+
+// GetObject will return the object with given name in given namespace. The
+// type and cluster query parameters can be used to select the object if the
+// name is ambiguous.
+func (f *handler) GetObject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	obj, err := f.namespacedObject(r, ps, "get")
+	if err != nil {
+		f.Error(w, r, errorStatus(err), err)
+		return
+	}
+	f.writeObject(w, r, obj)
+}
+
+// PatchObject will scale, save or restore the state, or snooze the object
+// with given name in given namespace, and will return the updated object.
+func (f *handler) PatchObject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	patch := objectPatch{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err := patch.validate(); err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	verb := "update"
+	if patch.Replicas == nil && patch.State == "" {
+		verb = "patch"
+	}
+	obj, err := f.namespacedObject(r, ps, verb)
+	if err != nil {
+		f.Error(w, r, errorStatus(err), err)
+		return
+	}
+	if err := patch.apply(obj, time.Now()); err != nil {
+		f.Error(w, r, errorStatus(err), err)
+		return
+	}
+	f.writeObject(w, r, obj)
+}
+
+// GetObjectSchedule will return the schedule of the object with given uid.
+func (f *handler) GetObjectSchedule(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	obj, err := f.uidObject(r, ps, "get")
+	if err != nil {
+		f.Error(w, r, errorStatus(err), err)
+		return
+	}
+	res := obj.Schedule
+	if res == nil {
+		res = []*schedule.Schedule{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
+// GetObjectNextEvents will return the upcoming scale events of the object
+// with given uid. The number of events can be specified with the count query
+// parameter (default 10).
+func (f *handler) GetObjectNextEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	count := 10
+	if cnt := r.URL.Query().Get("count"); cnt != "" {
+		var err error
+		if count, err = strconv.Atoi(cnt); err != nil || count < 1 || count > maxNextEvents {
+			f.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid count: %s", cnt))
+			return
+		}
+	}
+	obj, err := f.uidObject(r, ps, "get")
+	if err != nil {
+		f.Error(w, r, errorStatus(err), err)
+		return
+	}
+	res, err := nextEvents(obj, time.Now(), count)
+	if err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
+// writeObject will write given object as response.
+func (f *handler) writeObject(w http.ResponseWriter, r *http.Request, obj *scanner.Object) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// namespacedObject will return the object referred to by the namespace and
+// name parameters, and the type and cluster query parameters, if the user of
+// the request is allowed to perform given verb on it.
+func (f *handler) namespacedObject(r *http.Request, ps httprouter.Params, verb string) (*scanner.Object, error) {
+	ns, name := ps.ByName("namespace"), ps.ByName("name")
+	typ, cluster := r.URL.Query().Get("type"), r.URL.Query().Get("cluster")
+	objs := f.matchObjects(func(obj *scanner.Object) bool {
+		return obj.Namespace == ns && obj.Name == name &&
+			(typ == "" || strings.EqualFold(obj.Type, typ)) &&
+			(cluster == "" || strings.EqualFold(obj.Cluster, cluster))
+	})
+	if len(objs) == 0 {
+		return nil, &statusError{http.StatusNotFound, fmt.Errorf("non existing object: %s/%s", ns, name)}
+	}
+	if len(objs) > 1 {
+		return nil, &statusError{http.StatusConflict, fmt.Errorf("ambiguous object %s/%s, specify type and/or cluster", ns, name)}
+	}
+	if err := f.Authorize(r, verb, objs); err != nil {
+		return nil, &statusError{http.StatusForbidden, err}
+	}
+	return objs[0], nil
+}
+
+// uidObject will return the object referred to by the uid parameter, if the
+// user of the request is allowed to perform given verb on it.
+func (f *handler) uidObject(r *http.Request, ps httprouter.Params, verb string) (*scanner.Object, error) {
+	obj, ok := f.objects()[ps.ByName("uid")]
+	if !ok {
+		return nil, &statusError{http.StatusNotFound, fmt.Errorf("non existing object: %s", ps.ByName("uid"))}
+	}
+	if err := f.Authorize(r, verb, []*scanner.Object{obj}); err != nil {
+		return nil, &statusError{http.StatusForbidden, err}
+	}
+	return obj, nil
+}
+
+// validate will check if the patch is valid.
+func (p objectPatch) validate() error {
+	if p.Replicas == nil && p.State == "" && p.SnoozeUntil == nil {
+		return fmt.Errorf("no replicas, state or snooze_until specified")
+	}
+	if p.Replicas != nil && *p.Replicas < 0 {
+		return fmt.Errorf("invalid number of replicas: %d", *p.Replicas)
+	}
+	switch p.State {
+	case "", "save":
+	case "restore":
+		if p.Replicas != nil {
+			return fmt.Errorf("replicas can't be combined with state restore")
+		}
+	default:
+		return fmt.Errorf("invalid state: %s", p.State)
+	}
+	if p.SnoozeUntil != nil {
+		if _, err := parseSnoozeUntil(*p.SnoozeUntil, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply will apply the patch to given object.
+func (p objectPatch) apply(obj *scanner.Object, now time.Time) error {
+	var err error
+	switch {
+	case p.State == "restore":
+		metrics.Increase("manual_restore")
+		if err = restoreObject(obj); err != nil {
+			metrics.Increase("manual_restore_error")
+		}
+	case p.State == "save":
+		metrics.Increase("manual_save")
+		var state *int
+		if state, err = obj.GetState(); err == nil {
+			repl := *state
+			if p.Replicas != nil {
+				repl = *p.Replicas
+			}
+			err = obj.Scale(state, repl)
+		}
+		if err != nil {
+			metrics.Increase("manual_save_error")
+		}
+	case p.Replicas != nil:
+		metrics.Increase("manual_scale")
+		if err = scaleObject(obj, *p.Replicas); err != nil {
+			metrics.Increase("manual_scale_error")
+		}
+	}
+	if err != nil || p.SnoozeUntil == nil {
+		return err
+	}
+	until, _ := parseSnoozeUntil(*p.SnoozeUntil, now)
+	metrics.Increase("manual_snooze")
+	if err = obj.Snooze(until); err != nil {
+		metrics.Increase("manual_snooze_error")
+	}
+	return err
+}
+
+// nextEvents will return the first count scale events of given object after
+// given time, in chronological order.
+func nextEvents(obj *scanner.Object, from time.Time, count int) ([]nextEvent, error) {
+	res := []nextEvent{}
+	for _, s := range obj.Schedule {
+		next := from
+		for i := 0; i < count; i++ {
+			at, err := s.GetNextTrigger(next)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, nextEvent{Time: at, Schedule: s.Description, Snoozed: obj.IsSnoozed(at)})
+			next = at.Add(time.Minute)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Time.Before(res[j].Time) })
+	if len(res) > count {
+		res = res[:count]
+	}
+	return res, nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/joyrex2001/nightshift/internal/agent"
 	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/logging"
 	"github.com/joyrex2001/nightshift/internal/metrics"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/trigger"
@@ -37,11 +38,12 @@ func (f *handler) GetVersion(w http.ResponseWriter, r *http.Request, ps httprout
 // GetObjects will return the list of currently scanned objects.
 func (f *handler) GetObjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	res := []*scanner.Object{}
-	for _, obj := range agent.New().GetObjects() {
+	for _, obj := range f.objects() {
 		if len(obj.Schedule) > 0 {
 			res = append(res, obj)
 		}
 	}
+	res = f.FilterObjects(r, res)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(res); err != nil {
 		f.Error(w, r, http.StatusInternalServerError, err)
@@ -55,6 +57,7 @@ func (f *handler) GetScanners(w http.ResponseWriter, r *http.Request, ps httprou
 	for _, scnr := range agent.New().GetScanners() {
 		res = append(res, scnr.GetConfig())
 	}
+	res = f.FilterScanners(r, res)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(res); err != nil {
 		f.Error(w, r, http.StatusInternalServerError, err)
@@ -75,84 +78,190 @@ func (f *handler) GetTriggers(w http.ResponseWriter, r *http.Request, ps httprou
 	return
 }
 
+// GetTriggerHistory will return the list of recently executed triggers.
+func (f *handler) GetTriggerHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	res := agent.New().GetTriggerHistory()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
 // PostObjectsScale will scale the provided pods to the number of specified
-// replicas.
+// replicas, and will return the result for each object.
 func (f *handler) PostObjectsScale(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	replicas, err := strconv.Atoi(ps.ByName("replicas"))
 	if err != nil {
-		f.Error(w, r, http.StatusInternalServerError, err)
+		f.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid number of replicas: %s", ps.ByName("replicas")))
 		return
 	}
 	if replicas < 0 {
-		f.Error(w, r, http.StatusInternalServerError, fmt.Errorf("invalid number of replicas: %d", replicas))
+		f.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid number of replicas: %d", replicas))
 		return
 	}
 	in := []*scanner.Object{}
 	if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-		f.Error(w, r, http.StatusInternalServerError, err)
-		return
-	}
-	if err := scaleObjects(in, replicas); err != nil {
-		f.Error(w, r, http.StatusInternalServerError, err)
+		f.Error(w, r, http.StatusBadRequest, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNoContent)
-	return
+	f.batch(w, r, "update", "manual_scale", in, func(obj *scanner.Object) error {
+		return scaleObject(obj, replicas)
+	})
 }
 
 // PostObjectsRestore will restore the provided pods to the previous known
-// state of the given objects.
+// state of the given objects, and will return the result for each object.
 func (f *handler) PostObjectsRestore(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	in := []*scanner.Object{}
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		f.Error(w, r, http.StatusInternalServerError, err)
+		f.Error(w, r, http.StatusBadRequest, err)
 		return
 	}
-	if err := restoreObjects(in); err != nil {
-		f.Error(w, r, http.StatusInternalServerError, err)
-		return
+	f.batch(w, r, "update", "manual_restore", in, restoreObject)
+}
+
+// objectResult is the result of an operation on a single object of a batch
+// operation.
+type objectResult struct {
+	UID       string `json:"uid"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    int    `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// statusError is an error with the http status code that should be returned
+// for it.
+type statusError struct {
+	code int
+	err  error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+// errorStatus will return the http status code for given error.
+func errorStatus(err error) int {
+	if serr, ok := err.(*statusError); ok {
+		return serr.code
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNoContent)
-	return
+	return http.StatusInternalServerError
 }
 
-// scaleObjects will scale the array of objects to given amount of replicas.
-func scaleObjects(objects []*scanner.Object, replicas int) error {
+// batch will execute given operation for each of the given objects, which
+// are looked up by uid in the agent, if the user of the request is allowed to
+// perform given verb on the object. It will respond with the result for each
+// object. The status of the response is the status of the first failed
+// object, or 200 if all succeeded. The given metric is increased for the
+// batch, and its error metric if any of the objects failed.
+func (f *handler) batch(w http.ResponseWriter, r *http.Request, verb, metric string, in []*scanner.Object, op func(*scanner.Object) error) {
+	res := struct {
+		Status  int            `json:"status"`
+		Error   string         `json:"error,omitempty"`
+		Results []objectResult `json:"results"`
+	}{Status: http.StatusOK, Results: []objectResult{}}
 	errs := []string{}
-	metrics.Increase("manual_scale")
-	for _, obj := range objects {
-		if _err := obj.Scale(nil, replicas); _err != nil {
-			errs = append(errs, _err.Error())
+	objs := f.objects()
+	metrics.Increase(metric)
+	for _, o := range in {
+		or := objectResult{UID: o.UID, Namespace: o.Namespace, Name: o.Name, Status: http.StatusOK}
+		err := f.objectOperation(r, verb, objs[o.UID], op)
+		if err != nil {
+			or.Status = errorStatus(err)
+			or.Error = err.Error()
+			errs = append(errs, err.Error())
+			if res.Status == http.StatusOK {
+				res.Status = or.Status
+			}
 		}
+		res.Results = append(res.Results, or)
 	}
 	if len(errs) > 0 {
-		metrics.Increase("manual_scale_error")
-		return fmt.Errorf("%s", strings.Join(errs, ","))
+		metrics.Increase(metric + "_error")
+		res.Error = strings.Join(errs, ",")
+		logger.Error("HTTP error", "method", r.Method, "path", r.URL.Path, "status", res.Status, logging.KeyError, res.Error)
 	}
-	return nil
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(res.Status)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.Error("Error writing response", "path", r.URL.Path, logging.Err(err))
+	}
+}
+
+// objectOperation will execute given operation on given object, if the user
+// of the request is allowed to perform given verb on the object.
+func (f *handler) objectOperation(r *http.Request, verb string, obj *scanner.Object, op func(*scanner.Object) error) error {
+	if obj == nil {
+		return &statusError{http.StatusNotFound, fmt.Errorf("non existing object")}
+	}
+	if err := f.Authorize(r, verb, []*scanner.Object{obj}); err != nil {
+		return &statusError{http.StatusForbidden, err}
+	}
+	return op(obj)
+}
+
+// scaleObjects will scale the array of objects to given amount of replicas.
+func scaleObjects(objects []*scanner.Object, replicas int) error {
+	return eachObject(objects, "manual_scale", func(obj *scanner.Object) error {
+		return scaleObject(obj, replicas)
+	})
+}
+
+// saveObjects will save the current number of replicas as the state of the
+// array of objects.
+func saveObjects(objects []*scanner.Object) error {
+	return eachObject(objects, "manual_save", saveObject)
 }
 
 // restoreObjects will scale the array of objects to the previous known state.
 func restoreObjects(objects []*scanner.Object) error {
+	return eachObject(objects, "manual_restore", restoreObject)
+}
+
+// eachObject will execute given operation for each of the given objects, and
+// will return the errors combined. The given metric is increased, and its
+// error metric if any of the objects failed.
+func eachObject(objects []*scanner.Object, metric string, op func(*scanner.Object) error) error {
 	errs := []string{}
-	metrics.Increase("manual_restore")
+	metrics.Increase(metric)
 	for _, obj := range objects {
-		if obj.State == nil {
-			_err := fmt.Sprintf("no state available on %s/%s", obj.Namespace, obj.Name)
-			errs = append(errs, _err)
-			continue
-		}
-		if obj.State != nil {
-			if _err := obj.Scale(nil, obj.State.Replicas); _err != nil {
-				errs = append(errs, _err.Error())
-			}
+		if _err := op(obj); _err != nil {
+			errs = append(errs, _err.Error())
 		}
 	}
 	if len(errs) > 0 {
-		metrics.Increase("manual_restore_error")
+		metrics.Increase(metric + "_error")
 		return fmt.Errorf("%s", strings.Join(errs, ","))
 	}
 	return nil
 }
+
+// scaleObject will scale the object to given amount of replicas.
+func scaleObject(obj *scanner.Object, replicas int) error {
+	return obj.Scale(nil, replicas)
+}
+
+// saveObject will save the current number of replicas as the state of the
+// object.
+func saveObject(obj *scanner.Object) error {
+	state, err := obj.GetState()
+	if err != nil {
+		return err
+	}
+	return obj.Scale(state, *state)
+}
+
+// restoreObject will scale the object to the previous known state, and will
+// restore the saved container resources, if any.
+func restoreObject(obj *scanner.Object) error {
+	if obj.State == nil {
+		return &statusError{http.StatusConflict, fmt.Errorf("no state available on %s/%s", obj.Namespace, obj.Name)}
+	}
+	var profile *scanner.Profile
+	if len(obj.State.Resources) > 0 {
+		profile = scanner.RestoreProfile
+	}
+	return obj.ScaleWithProfile(nil, obj.State.Replicas, profile)
+}
@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// mockScanner is a scanner that records the scaled and annotated objects.
+// Scaling or annotating an object named broken fails.
+type mockScanner struct {
+	scaled    map[string]int
+	annotated map[string]map[string]string
+	profile   *scanner.Profile
+}
+
+func (m *mockScanner) SetConfig(c scanner.Config) {
+}
+
+func (m *mockScanner) GetConfig() scanner.Config {
+	return scanner.Config{}
+}
+
+func (m *mockScanner) GetObjects() ([]*scanner.Object, error) {
+	return nil, nil
+}
+
+func (m *mockScanner) GetState(obj *scanner.Object) (int, error) {
+	return obj.Replicas, nil
+}
+
+func (m *mockScanner) Scale(obj *scanner.Object, state *int, r int, p *scanner.Profile) error {
+	if obj.Name == "broken" {
+		return fmt.Errorf("scale failed")
+	}
+	m.scaled[obj.Name] = r
+	m.profile = p
+	return nil
+}
+
+func (m *mockScanner) Annotate(obj *scanner.Object, annotations map[string]string) error {
+	if obj.Name == "broken" {
+		return fmt.Errorf("annotate failed")
+	}
+	if m.annotated != nil {
+		m.annotated[obj.Name] = annotations
+	}
+	return nil
+}
+
+func (m *mockScanner) Watch(_stop chan bool) (chan scanner.Event, error) {
+	return nil, nil
+}
+
+type batchResult struct {
+	Status  int            `json:"status"`
+	Error   string         `json:"error"`
+	Results []objectResult `json:"results"`
+}
+
+func TestBatch(t *testing.T) {
+	_, restore := fakeAccessReviews()
+	defer restore()
+
+	mock := &mockScanner{}
+	scanner.RegisterModule("batchscanner", func() (scanner.Scanner, error) { return mock, nil })
+
+	resources := map[string]corev1.ResourceRequirements{"app": {}}
+	objs := map[string]*scanner.Object{
+		"1": {UID: "1", Type: "batchscanner", Namespace: "dev", Name: "web", Replicas: 0, State: &scanner.State{Replicas: 2}},
+		"2": {UID: "2", Type: "batchscanner", Namespace: "dev", Name: "db", Replicas: 1},
+		"3": {UID: "3", Type: "batchscanner", Namespace: "dev", Name: "broken", Replicas: 0, State: &scanner.State{Replicas: 1}},
+		"4": {UID: "4", Type: "batchscanner", Namespace: "dev", Name: "cache", Replicas: 0, State: &scanner.State{Replicas: 3, Resources: resources}},
+		"5": {UID: "5", Type: "deployment", Namespace: "prod", Name: "web", Replicas: 1},
+	}
+
+	tests := []struct {
+		restore   bool
+		replicas  string
+		authorize bool
+		uids      []string
+		code      int
+		codes     []int
+		scaled    map[string]int
+		profile   *scanner.Profile
+	}{
+		{restore: true, uids: []string{"1"}, code: 200, codes: []int{200}, scaled: map[string]int{"web": 2}},
+		{restore: true, uids: []string{"4"}, code: 200, codes: []int{200}, scaled: map[string]int{"cache": 3}, profile: scanner.RestoreProfile},
+		{restore: true, uids: []string{"9"}, code: 404, codes: []int{404}, scaled: map[string]int{}},
+		{restore: true, uids: []string{"2"}, code: 409, codes: []int{409}, scaled: map[string]int{}},
+		{restore: true, uids: []string{"3"}, code: 500, codes: []int{500}, scaled: map[string]int{}},
+		{restore: true, uids: []string{"1", "2", "9"}, code: 409, codes: []int{200, 409, 404}, scaled: map[string]int{"web": 2}},
+		{restore: true, authorize: true, uids: []string{"5"}, code: 403, codes: []int{403}, scaled: map[string]int{}},
+		{restore: true, authorize: true, uids: []string{"9", "5"}, code: 404, codes: []int{404, 403}, scaled: map[string]int{}},
+		{replicas: "0", uids: []string{"1", "2"}, code: 200, codes: []int{200, 200}, scaled: map[string]int{"web": 0, "db": 0}},
+		{replicas: "1", uids: []string{"2", "9", "3"}, code: 404, codes: []int{200, 404, 500}, scaled: map[string]int{"db": 1}},
+		{replicas: "1", authorize: true, uids: []string{"5"}, code: 403, codes: []int{403}, scaled: map[string]int{}},
+		{replicas: "-1", uids: []string{"1"}, code: 400, scaled: map[string]int{}},
+		{replicas: "x", uids: []string{"1"}, code: 400, scaled: map[string]int{}},
+	}
+
+	for i, tst := range tests {
+		f := NewHandler()
+		f.authorize = tst.authorize
+		f.objects = func() map[string]*scanner.Object {
+			res := map[string]*scanner.Object{}
+			for uid, obj := range objs {
+				res[uid] = obj.Copy()
+			}
+			return res
+		}
+		mock.scaled = map[string]int{}
+		mock.profile = nil
+
+		in := []*scanner.Object{}
+		for _, uid := range tst.uids {
+			in = append(in, &scanner.Object{UID: uid})
+		}
+		body, _ := json.Marshal(in)
+		r := httptest.NewRequest("POST", "/api/objects", bytes.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), userKey{}, &user{Name: "bob"}))
+		w := httptest.NewRecorder()
+		if tst.restore {
+			f.PostObjectsRestore(w, r, nil)
+		} else {
+			f.PostObjectsScale(w, r, httprouter.Params{{Key: "replicas", Value: tst.replicas}})
+		}
+
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected status %d, but got %d", i, tst.code, w.Code)
+		}
+		if fmt.Sprint(mock.scaled) != fmt.Sprint(tst.scaled) {
+			t.Errorf("failed test %d - expected scaled %v, but got %v", i, tst.scaled, mock.scaled)
+		}
+		if mock.profile != tst.profile {
+			t.Errorf("failed test %d - expected profile %v, but got %v", i, tst.profile, mock.profile)
+		}
+		if tst.codes == nil {
+			continue
+		}
+		res := batchResult{}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+			continue
+		}
+		codes := []int{}
+		for _, or := range res.Results {
+			codes = append(codes, or.Status)
+			if (or.Status == 200) != (or.Error == "") {
+				t.Errorf("failed test %d - unexpected error %q for status %d", i, or.Error, or.Status)
+			}
+		}
+		if res.Status != tst.code || fmt.Sprint(codes) != fmt.Sprint(tst.codes) {
+			t.Errorf("failed test %d - expected %d %v, but got %d %v", i, tst.code, tst.codes, res.Status, codes)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/joyrex2001/nightshift/internal/savings"
+)
+
+// GetSavings will return the resources that are saved by scaling down
+// objects, per namespace and scanner, and the cost thereof.
+func (f *handler) GetSavings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	usr := requestUser(r)
+	rep := savings.GetReport(func(s savings.Savings) bool {
+		return f.visible(usr, s.Cluster, s.Type, s.Namespace)
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rep); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+// scheduleUpdate describes a change of the schedule of an object. Schedule
+// is the list of schedule descriptions that will be stored in the schedule
+// annotation; if empty, the annotation is removed and the schedule of the
+// scanner is applied again. If Ignore is set, the object will be ignored
+// instead.
+type scheduleUpdate struct {
+	Schedule []string `json:"schedule"`
+	Ignore   bool     `json:"ignore"`
+}
+
+// PutObjectSchedule will update the schedule annotation, or the ignore
+// annotation, of the object with given uid, and will return the updated
+// object.
+func (f *handler) PutObjectSchedule(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	upd := scheduleUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	sched, err := upd.validate()
+	if err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	obj, err := f.uidObject(r, ps, "patch")
+	if err != nil {
+		f.Error(w, r, errorStatus(err), err)
+		return
+	}
+	err = eachObject([]*scanner.Object{obj}, "manual_schedule", func(obj *scanner.Object) error {
+		if upd.Ignore {
+			return obj.Ignore(true)
+		}
+		return obj.SetSchedule(sched)
+	})
+	if err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	f.writeObject(w, r, obj)
+}
+
+// validate will check if each of the schedules in the update is valid, and
+// will return the value of the schedule annotation.
+func (u scheduleUpdate) validate() (string, error) {
+	if u.Ignore && len(u.Schedule) > 0 {
+		return "", fmt.Errorf("schedule can't be combined with ignore")
+	}
+	sched := []string{}
+	for _, text := range u.Schedule {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if strings.Contains(text, ";") {
+			return "", fmt.Errorf("invalid schedule %s; specify one schedule per entry", text)
+		}
+		if _, err := schedule.New(text); err != nil {
+			return "", err
+		}
+		sched = append(sched, text)
+	}
+	return strings.Join(sched, ";"), nil
+}
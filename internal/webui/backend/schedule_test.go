@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+func TestScheduleUpdateValidate(t *testing.T) {
+	tests := []struct {
+		upd   scheduleUpdate
+		sched string
+		err   bool
+	}{
+		{upd: scheduleUpdate{Schedule: []string{"Mon-Fri 8:00 replicas=1", " Mon-Fri 18:00 replicas=0 "}}, sched: "Mon-Fri 8:00 replicas=1;Mon-Fri 18:00 replicas=0"},
+		{upd: scheduleUpdate{Schedule: []string{"", "Sat 10:00 state=restore", "  "}}, sched: "Sat 10:00 state=restore"},
+		{upd: scheduleUpdate{Schedule: []string{}}, sched: ""},
+		{upd: scheduleUpdate{Ignore: true}, sched: ""},
+		{upd: scheduleUpdate{Schedule: []string{"Mon-Fri 8:00 replicas=1;Mon-Fri 18:00 replicas=0"}}, err: true},
+		{upd: scheduleUpdate{Schedule: []string{"Mon-Fri 8:00 replicas=1"}, Ignore: true}, err: true},
+		{upd: scheduleUpdate{Schedule: []string{"Mon-Fri 25:00 replicas=1"}}, err: true},
+		{upd: scheduleUpdate{Schedule: []string{"Mon-Fri 8:00 replicas=1", "Someday 10:00 replicas=1"}}, err: true},
+	}
+	for i, tst := range tests {
+		sched, err := tst.upd.validate()
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if sched != tst.sched {
+			t.Errorf("failed test %d - expected %q, but got %q", i, tst.sched, sched)
+		}
+	}
+}
+
+func TestPutObjectSchedule(t *testing.T) {
+	_, restore := fakeAccessReviews()
+	defer restore()
+
+	mock := &mockScanner{}
+	scanner.RegisterModule("schedulescanner", func() (scanner.Scanner, error) { return mock, nil })
+	sched, _ := schedule.New("Mon-Fri 18:00 replicas=0")
+	objs := map[string]*scanner.Object{
+		"1": {UID: "1", Type: "schedulescanner", Namespace: "dev", Name: "web", Schedule: []*schedule.Schedule{sched}},
+		"2": {UID: "2", Type: "schedulescanner", Namespace: "dev", Name: "broken"},
+		"3": {UID: "3", Type: "schedulescanner", Namespace: "prod", Name: "web"},
+	}
+
+	tests := []struct {
+		uid       string
+		body      string
+		authorize bool
+		code      int
+		annotated map[string]string
+		schedules int
+	}{
+		{uid: "1", body: `{"schedule":["Mon-Fri 8:00 replicas=1","Mon-Fri 18:00 replicas=0"]}`, code: 200,
+			annotated: map[string]string{scanner.ScheduleAnnotation: "Mon-Fri 8:00 replicas=1;Mon-Fri 18:00 replicas=0", scanner.IgnoreAnnotation: ""}, schedules: 2},
+		{uid: "1", body: `{"schedule":[]}`, code: 200,
+			annotated: map[string]string{scanner.ScheduleAnnotation: "", scanner.IgnoreAnnotation: ""}, schedules: 0},
+		{uid: "1", body: `{"ignore":true}`, code: 200,
+			annotated: map[string]string{scanner.IgnoreAnnotation: "true"}, schedules: 0},
+		{uid: "1", body: `{"schedule":["Mon-Fri 8:00 replicas=1"],"ignore":true}`, code: 400},
+		{uid: "1", body: `{"schedule":["Mon-Fri 8:00 replicas=1;Mon-Fri 18:00 replicas=0"]}`, code: 400},
+		{uid: "1", body: `{"schedule":["Mon-Fri 25:00 replicas=1"]}`, code: 400},
+		{uid: "1", body: `{"schedule":`, code: 400},
+		{uid: "9", body: `{"schedule":[]}`, code: 404},
+		{uid: "2", body: `{"schedule":[]}`, code: 500},
+		{uid: "3", body: `{"schedule":[]}`, authorize: true, code: 403},
+	}
+
+	for i, tst := range tests {
+		f := NewHandler()
+		f.authorize = tst.authorize
+		f.objects = func() map[string]*scanner.Object {
+			res := map[string]*scanner.Object{}
+			for uid, obj := range objs {
+				res[uid] = obj.Copy()
+			}
+			return res
+		}
+		annotated := map[string]map[string]string{}
+		mock.annotated = annotated
+
+		r := httptest.NewRequest("PUT", "/api/objects/"+tst.uid+"/schedule", bytes.NewReader([]byte(tst.body)))
+		r = r.WithContext(context.WithValue(r.Context(), userKey{}, &user{Name: "bob"}))
+		w := httptest.NewRecorder()
+		f.PutObjectSchedule(w, r, httprouter.Params{{Key: "uid", Value: tst.uid}})
+		mock.annotated = nil
+
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected status %d, but got %d", i, tst.code, w.Code)
+		}
+		if tst.annotated == nil {
+			tst.annotated = map[string]string{}
+		}
+		name := ""
+		if obj, ok := objs[tst.uid]; ok {
+			name = obj.Name
+		}
+		if fmt.Sprint(annotated[name]) != fmt.Sprint(tst.annotated) {
+			t.Errorf("failed test %d - expected annotations %v, but got %v", i, tst.annotated, annotated)
+		}
+		if w.Code != 200 {
+			continue
+		}
+		res := struct {
+			UID      string        `json:"uid"`
+			Schedule []interface{} `json:"schedule"`
+		}{}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+			continue
+		}
+		if res.UID != tst.uid || len(res.Schedule) != tst.schedules {
+			t.Errorf("failed test %d - expected %s with %d schedules, but got %s with %d", i, tst.uid, tst.schedules, res.UID, len(res.Schedule))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// PostObjectsSnooze will suspend the schedule of the provided objects until
+// the given time.
+func (f *handler) PostObjectsSnooze(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	until, err := parseSnoozeUntil(ps.ByName("until"), time.Now())
+	if err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	in := []*scanner.Object{}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	f.snooze(w, r, in, until)
+}
+
+// PostNamespaceSnooze will suspend the schedule of all objects in the given
+// namespace until the given time.
+func (f *handler) PostNamespaceSnooze(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	until, err := parseSnoozeUntil(ps.ByName("until"), time.Now())
+	if err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	ns := ps.ByName("namespace")
+	objs := f.matchObjects(func(obj *scanner.Object) bool { return obj.Namespace == ns })
+	f.snooze(w, r, objs, until)
+}
+
+// PostScannerSnooze will suspend the schedule of all objects of the scanner
+// with given id until the given time.
+func (f *handler) PostScannerSnooze(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	until, err := parseSnoozeUntil(ps.ByName("until"), time.Now())
+	if err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	id := ps.ByName("id")
+	objs := f.matchObjects(func(obj *scanner.Object) bool { return obj.ScannerId == id })
+	f.snooze(w, r, objs, until)
+}
+
+// snooze will suspend the schedule of given objects until the given time, and
+// will respond with the result for each object.
+func (f *handler) snooze(w http.ResponseWriter, r *http.Request, objs []*scanner.Object, until *time.Time) {
+	f.batch(w, r, "patch", "manual_snooze", objs, func(obj *scanner.Object) error {
+		return obj.Snooze(until)
+	})
+}
+
+// matchObjects will return the objects known by the agent for which the
+// given function returns true, ordered by namespace and name.
+func (f *handler) matchObjects(match func(*scanner.Object) bool) []*scanner.Object {
+	objs := []*scanner.Object{}
+	for _, obj := range f.objects() {
+		if match(obj) {
+			objs = append(objs, obj)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		if objs[i].Namespace != objs[j].Namespace {
+			return objs[i].Namespace < objs[j].Namespace
+		}
+		if objs[i].Name != objs[j].Name {
+			return objs[i].Name < objs[j].Name
+		}
+		return objs[i].UID < objs[j].UID
+	})
+	return objs
+}
+
+// parseSnoozeUntil will parse the given snooze deadline, which is either a
+// time in RFC3339 format, or a duration relative to now (e.g. 3h). If "off"
+// is given, it will return nil, in order to resume the schedule.
+func parseSnoozeUntil(val string, now time.Time) (*time.Time, error) {
+	if strings.ToLower(val) == "off" {
+		return nil, nil
+	}
+	if dur, err := time.ParseDuration(val); err == nil {
+		if dur <= 0 {
+			return nil, fmt.Errorf("invalid snooze duration: %s", val)
+		}
+		until := now.Add(dur).Truncate(time.Second)
+		return &until, nil
+	}
+	until, err := scanner.ParseTime(val)
+	if err != nil {
+		return nil, err
+	}
+	return &until, nil
+}
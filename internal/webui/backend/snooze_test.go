@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+func TestParseSnoozeUntil(t *testing.T) {
+	now := time.Date(2026, 10, 18, 20, 0, 0, 500, time.UTC)
+	until := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	tests := []struct {
+		val   string
+		until *time.Time
+		err   bool
+	}{
+		{val: "3h", until: &until},
+		{val: "180m", until: &until},
+		{val: "2026-10-18T23:00:00Z", until: &until},
+		{val: "2026-10-18T23:00Z", until: &until},
+		{val: "2026-10-19T01:00+02:00", until: &until},
+		{val: "off", until: nil},
+		{val: "OFF", until: nil},
+		{val: "0s", err: true},
+		{val: "-1h", err: true},
+		{val: "2026-10-18 23:00", err: true},
+		{val: "tomorrow", err: true},
+		{val: "", err: true},
+	}
+	for i, tst := range tests {
+		res, err := parseSnoozeUntil(tst.val, now)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if tst.err {
+			continue
+		}
+		if (res == nil) != (tst.until == nil) || (res != nil && !res.Equal(*tst.until)) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.until, res)
+		}
+	}
+}
+
+func TestPostSnooze(t *testing.T) {
+	_, restore := fakeAccessReviews()
+	defer restore()
+
+	mock := &mockScanner{}
+	scanner.RegisterModule("snoozescanner", func() (scanner.Scanner, error) { return mock, nil })
+	objs := map[string]*scanner.Object{
+		"1": {UID: "1", Type: "snoozescanner", ScannerId: "web", Namespace: "dev", Name: "web"},
+		"2": {UID: "2", Type: "snoozescanner", ScannerId: "db", Namespace: "dev", Name: "db"},
+		"3": {UID: "3", Type: "snoozescanner", ScannerId: "web", Namespace: "prod", Name: "web"},
+		"4": {UID: "4", Type: "snoozescanner", ScannerId: "db", Namespace: "prod", Name: "broken"},
+	}
+
+	tests := []struct {
+		handler   string
+		param     string
+		until     string
+		uids      []string
+		code      int
+		annotated map[string]string
+	}{
+		{handler: "objects", until: "2026-10-18T23:00Z", uids: []string{"1"}, code: 200, annotated: map[string]string{"web": "2026-10-18T23:00:00Z"}},
+		{handler: "objects", until: "off", uids: []string{"1", "2"}, code: 200, annotated: map[string]string{"web": "", "db": ""}},
+		{handler: "objects", until: "off", uids: []string{"1", "9"}, code: 404, annotated: map[string]string{"web": ""}},
+		{handler: "objects", until: "tomorrow", uids: []string{"1"}, code: 400, annotated: map[string]string{}},
+		{handler: "namespace", param: "dev", until: "2026-10-18T23:00:00Z", code: 200, annotated: map[string]string{"web": "2026-10-18T23:00:00Z", "db": "2026-10-18T23:00:00Z"}},
+		{handler: "namespace", param: "prod", until: "off", code: 500, annotated: map[string]string{"web": ""}},
+		{handler: "namespace", param: "test", until: "off", code: 200, annotated: map[string]string{}},
+		{handler: "namespace", param: "dev", until: "-1h", code: 400, annotated: map[string]string{}},
+		{handler: "scanner", param: "web", until: "off", code: 200, annotated: map[string]string{"web": ""}},
+		{handler: "scanner", param: "db", until: "2026-10-18T23:00Z", code: 500, annotated: map[string]string{"db": "2026-10-18T23:00:00Z"}},
+	}
+
+	for i, tst := range tests {
+		f := NewHandler()
+		f.objects = func() map[string]*scanner.Object {
+			res := map[string]*scanner.Object{}
+			for uid, obj := range objs {
+				res[uid] = obj.Copy()
+			}
+			return res
+		}
+		annotated := map[string]map[string]string{}
+		mock.annotated = annotated
+
+		in := []*scanner.Object{}
+		for _, uid := range tst.uids {
+			in = append(in, &scanner.Object{UID: uid})
+		}
+		body, _ := json.Marshal(in)
+		r := httptest.NewRequest("POST", "/api/snooze", bytes.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), userKey{}, &user{Name: "bob"}))
+		w := httptest.NewRecorder()
+		switch tst.handler {
+		case "objects":
+			f.PostObjectsSnooze(w, r, httprouter.Params{{Key: "until", Value: tst.until}})
+		case "namespace":
+			f.PostNamespaceSnooze(w, r, httprouter.Params{{Key: "namespace", Value: tst.param}, {Key: "until", Value: tst.until}})
+		case "scanner":
+			f.PostScannerSnooze(w, r, httprouter.Params{{Key: "id", Value: tst.param}, {Key: "until", Value: tst.until}})
+		}
+		mock.annotated = nil
+
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected status %d, but got %d", i, tst.code, w.Code)
+		}
+		res := map[string]string{}
+		for name, anns := range annotated {
+			res[name] = anns[scanner.SnoozeAnnotation]
+		}
+		if fmt.Sprint(res) != fmt.Sprint(tst.annotated) {
+			t.Errorf("failed test %d - expected annotated %v, but got %v", i, tst.annotated, res)
+		}
+	}
+}
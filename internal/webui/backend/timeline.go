@@ -0,0 +1,67 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/joyrex2001/nightshift/internal/agent"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+// maxTimeline is the maximum period of a timeline.
+const maxTimeline = 31 * 24 * time.Hour
+
+// GetTimeline will return the expected scale events and replicas of all
+// objects and scanners within the period given by the from and to query
+// parameters. By default, the current week is returned.
+func (f *handler) GetTimeline(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	from, to, err := parsePeriod(r.URL.Query().Get("from"), r.URL.Query().Get("to"), time.Now())
+	if err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	usr := requestUser(r)
+	res := []agent.Timeline{}
+	for _, tl := range agent.New().GetTimeline(from, to) {
+		if f.visible(usr, tl.Cluster, tl.Type, tl.Namespace) {
+			res = append(res, tl)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
+// parsePeriod will parse the given from and to times. If from is empty, the
+// start of the current week (monday) is used. If to is empty, the period will
+// be a week.
+func parsePeriod(fromval, toval string, now time.Time) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if fromval == "" {
+		now = now.In(schedule.GetTimeZone())
+		from = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		from = from.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+	} else if from, err = scanner.ParseTime(fromval); err != nil {
+		return from, to, err
+	}
+	if toval == "" {
+		to = from.AddDate(0, 0, 7)
+	} else if to, err = scanner.ParseTime(toval); err != nil {
+		return from, to, err
+	}
+	if !to.After(from) {
+		return from, to, fmt.Errorf("invalid period, to should be after from")
+	}
+	if to.Sub(from) > maxTimeline {
+		return from, to, fmt.Errorf("invalid period, maximum is %s", maxTimeline)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+func TestParsePeriod(t *testing.T) {
+	tz := schedule.GetTimeZone()
+	now := time.Date(2026, 10, 21, 15, 30, 0, 0, tz)
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, tz)
+	sunday := time.Date(2026, 10, 25, 0, 0, 0, 0, tz)
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		from string
+		to   string
+		now  time.Time
+		out  []time.Time
+		err  bool
+	}{
+		{now: now, out: []time.Time{monday, monday.AddDate(0, 0, 7)}},
+		{now: monday, out: []time.Time{monday, monday.AddDate(0, 0, 7)}},
+		{now: sunday, out: []time.Time{monday, monday.AddDate(0, 0, 7)}},
+		{from: "2026-10-01T00:00:00Z", now: now, out: []time.Time{from, from.AddDate(0, 0, 7)}},
+		{from: "2026-10-01T00:00Z", to: "2026-10-02T00:00Z", now: now, out: []time.Time{from, from.AddDate(0, 0, 1)}},
+		{from: "2026-10-01T00:00Z", to: "2026-11-01T00:00Z", now: now, out: []time.Time{from, from.AddDate(0, 0, 31)}},
+		{from: "2026-10-01T00:00Z", to: "2026-11-01T00:01Z", now: now, err: true},
+		{from: "2026-10-01T00:00Z", to: "2026-10-01T00:00Z", now: now, err: true},
+		{from: "2026-10-02T00:00Z", to: "2026-10-01T00:00Z", now: now, err: true},
+		{from: "yesterday", now: now, err: true},
+		{from: "2026-10-01T00:00Z", to: "2026-10-02", now: now, err: true},
+	}
+	for i, tst := range tests {
+		from, to, err := parsePeriod(tst.from, tst.to, tst.now)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if tst.err {
+			continue
+		}
+		if !from.Equal(tst.out[0]) || !to.Equal(tst.out[1]) {
+			t.Errorf("failed test %d - expected %v - %v, but got %v - %v", i, tst.out[0], tst.out[1], from, to)
+		}
+	}
+}
@@ -1,3 +1,4 @@
+import axios from 'axios';
 import Vue from 'vue';
 import App from './App.vue';
 import router from './router';
@@ -9,6 +10,26 @@ import 'bootstrap-vue/dist/bootstrap-vue.css';
 Vue.config.productionTip = false;
 Vue.use(BootstrapVue);
 
+// add the api token to all requests, and ask for a token if the api requires
+// authentication.
+axios.interceptors.request.use((config) => {
+  const token = localStorage.getItem('nightshift-token');
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  return config;
+});
+axios.interceptors.response.use((response) => response, (error) => {
+  if (error.response && error.response.status === 401) {
+    const token = window.prompt('Authentication required, please enter your api token');
+    if (token) {
+      localStorage.setItem('nightshift-token', token);
+      window.location.reload();
+    }
+  }
+  return Promise.reject(error);
+});
+
 new Vue({
   router,
   render: (h) => h(App),
@@ -22,6 +22,11 @@ export default new Router({
       name: 'objects',
       component: () => import('./views/ObjectsOverview.vue'),
     },
+    {
+      path: '/timeline',
+      name: 'timeline',
+      component: () => import('./views/TimelineOverview.vue'),
+    },
     {
       path: '/triggers',
       name: 'triggers',
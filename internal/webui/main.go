@@ -3,19 +3,22 @@ package webui
 import (
 	"context"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
-	"github.com/golang/glog"
-
+	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/logging"
 	"github.com/joyrex2001/nightshift/internal/webui/backend"
 )
 
 type webui struct {
-	Addr string
-	TLS  bool
-	Cert string
-	Key  string
+	Addr  string
+	TLS   bool
+	Cert  string
+	Key   string
+	Hooks []*config.Hook
+	Auth  backend.AuthConfig
 
 	m    sync.Mutex
 	srv  *http.Server
@@ -25,6 +28,8 @@ type webui struct {
 var instance *webui
 var once sync.Once
 
+var logger = logging.For(logging.WebUI)
+
 // New will instantiate a new webui object.
 func New() *webui {
 	once.Do(func() {
@@ -39,6 +44,11 @@ func New() *webui {
 func (a *webui) Start() {
 	go func() {
 		hndlr := backend.NewHandler()
+		hndlr.SetHooks(a.Hooks)
+		if err := hndlr.SetAuth(a.Auth); err != nil {
+			logger.Error("Invalid authentication configuration", logging.Err(err))
+			os.Exit(1)
+		}
 		a.srv = &http.Server{
 			Addr:         a.Addr,
 			Handler:      backend.HTTPLogger(hndlr, []string{"/healthz", "/metrics"}),
@@ -46,12 +56,15 @@ func (a *webui) Start() {
 			WriteTimeout: 10 * time.Second,
 			IdleTimeout:  30 * time.Second,
 		}
-		glog.Infof("Starting webui on %s...", a.Addr)
+		logger.Info("Starting webui", "addr", a.Addr, "tls", a.TLS)
+		var err error
 		if a.TLS {
-			glog.Fatal(a.srv.ListenAndServeTLS(a.Cert, a.Key))
+			err = a.srv.ListenAndServeTLS(a.Cert, a.Key)
 		} else {
-			glog.Fatal(a.srv.ListenAndServe())
+			err = a.srv.ListenAndServe()
 		}
+		logger.Error("Error serving webui", logging.Err(err))
+		os.Exit(1)
 	}()
 }
 